@@ -0,0 +1,376 @@
+/*
+* This package implements an HTTP server with JSON endpoints that
+* let operators look inside a running Panthera (cached blocks,
+* metadata caches, statistics, configuration, sessions) and act
+* on its caches without speaking cache_protocol. The actions go
+* through cache_info_server.CacheControl, so they do exactly what
+* the corresponding cache_info_server requests do.
+*
+* GET  /blocks, /metadata, /stats, /config, /datanodes, /sessions
+* POST /evict?block=id,...&path=...   /clear?cache=name
+*      /enable?cache=name   /disable?cache=name
+*      /pin?block=id,...    /unpin?block=id,...
+*
+* where name is one of cache_protocol.CACHE_NAMES.
+*/
+
+package admin_server
+
+import (
+	//go packages
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	//local packages
+	"cache_info_server"
+	"cache_protocol"
+	"caches"
+	"configuration"
+	"util"
+)
+
+type AdminServer struct {
+	//host:port the server listens on
+	Address string
+
+	//the caches that are looked at and acted on
+	Control *cache_info_server.CacheControl
+
+	//what /config (without the secrets) and /datanodes
+	//answer with (nil => not known)
+	Config *configuration.Configuration
+	DataNodeMap configuration.DataNodeMap
+
+	//served on /sessions (nil => no sessions)
+	Sessions *SessionList
+
+	//requests have to carry "Authorization: Bearer <Secret>"
+	//(empty => GET requests are open to anyone and POST
+	//requests are turned off)
+	Secret []byte
+}
+
+func NewAdminServer(address string,
+	control *cache_info_server.CacheControl) *AdminServer {
+	a := AdminServer{Address: address, Control: control}
+	return &a
+}
+
+//blocking call
+func (a *AdminServer) Start() error {
+	return http.ListenAndServe(a.Address, a.Handler())
+}
+
+func (a *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks", a.get(a.handleBlocks))
+	mux.HandleFunc("/metadata", a.get(a.handleMetadata))
+	mux.HandleFunc("/stats", a.get(a.handleStats))
+	mux.HandleFunc("/config", a.get(a.handleConfig))
+	mux.HandleFunc("/datanodes", a.get(a.handleDataNodes))
+	mux.HandleFunc("/sessions", a.get(a.handleSessions))
+
+	mux.HandleFunc("/evict", a.post(a.handleEvict))
+	mux.HandleFunc("/clear", a.post(a.handleClear))
+	mux.HandleFunc("/enable", a.post(a.handleEnable))
+	mux.HandleFunc("/disable", a.post(a.handleEnable))
+	mux.HandleFunc("/pin", a.post(a.handlePin))
+	mux.HandleFunc("/unpin", a.post(a.handlePin))
+	return mux
+}
+
+//answers the request with value encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		util.DebugLogger.Println("Could not write admin response: ", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"Error": message})
+}
+
+func (a *AdminServer) authorized(r *http.Request) bool {
+	if len(a.Secret) == 0 {
+		return true
+	}
+
+	expected := append([]byte("Bearer "), a.Secret...)
+	given := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(given, expected) == 1
+}
+
+func (a *AdminServer) get(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, "Use GET.")
+			return
+		}
+
+		if !a.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "Wrong or missing secret.")
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func (a *AdminServer) post(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, "Use POST.")
+			return
+		}
+
+		if len(a.Secret) == 0 {
+			writeError(w, http.StatusForbidden,
+				"Admin actions are turned off (no secret is set).")
+			return
+		}
+
+		if !a.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "Wrong or missing secret.")
+			return
+		}
+
+		err := r.ParseForm()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func (a *AdminServer) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	c := a.Control
+	writeJSON(w, http.StatusOK,
+		cache_protocol.CreateCachedBlocks(c.DataCache, c.BlockIndex))
+}
+
+//what /metadata says about each metadata cache
+type metadataCache struct {
+	Enabled bool
+	Entries []caches.RequestCacheEntry
+}
+
+func (a *AdminServer) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	res := make(map[string]metadataCache)
+	for name, target := range cache_protocol.CACHE_NAMES {
+		requestCache := a.Control.RequestCache(target)
+		if requestCache == nil {
+			continue
+		}
+
+		res[name] = metadataCache{Enabled: requestCache.IsEnabled(),
+			Entries: requestCache.Entries()}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+//what /stats says about the data cache
+type dataCacheStats struct {
+	Enabled bool
+	Description *cache_protocol.CacheDescription
+	Stats *cache_protocol.CacheStats
+}
+
+func (a *AdminServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	dataCache := a.Control.DataCache
+	writeJSON(w, http.StatusOK, dataCacheStats{
+		Enabled: dataCache.IsEnabled(),
+		Description: cache_protocol.CreateCacheDescription(dataCache),
+		Stats: cache_protocol.CreateCacheStats(dataCache)})
+}
+
+func (a *AdminServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if a.Config == nil {
+		writeError(w, http.StatusNotFound, "No configuration.")
+		return
+	}
+
+	//never hand out the secrets
+	config := *a.Config
+	config.AdminSecret = ""
+	config.CacheInfoKey = ""
+	writeJSON(w, http.StatusOK, config)
+}
+
+func (a *AdminServer) handleDataNodes(w http.ResponseWriter,
+	r *http.Request) {
+	dataNodeMap := a.DataNodeMap
+	if dataNodeMap == nil {
+		dataNodeMap = make(configuration.DataNodeMap)
+	}
+
+	writeJSON(w, http.StatusOK, dataNodeMap)
+}
+
+func (a *AdminServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := []Session{}
+	if a.Sessions != nil {
+		sessions = a.Sessions.List()
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+//the block ids in r's block parameters (which may each be a
+//comma separated list)
+func blockIds(r *http.Request) ([]uint64, error) {
+	res := make([]uint64, 0)
+	for _, value := range r.Form["block"] {
+		for _, field := range strings.Split(value, ",") {
+			blockId, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Bad block id %q.", field)
+			}
+			res = append(res, blockId)
+		}
+	}
+
+	return res, nil
+}
+
+//the CACHE_* that r's cache parameter names
+func cacheTarget(r *http.Request) (uint16, error) {
+	name := r.Form.Get("cache")
+	target, present := cache_protocol.CACHE_NAMES[name]
+	if !present {
+		return 0, fmt.Errorf("Unknown cache %q.", name)
+	}
+
+	return target, nil
+}
+
+//runs commands and answers with the number of entries they dropped.
+//Nothing is run unless every command can be; if one still fails,
+//the answer says how many of them ran before it.
+func (a *AdminServer) runCommands(w http.ResponseWriter,
+	commands []*cache_protocol.AdminCommand) {
+	for i := 0; i < len(commands); i++ {
+		err := a.Control.CheckAdminCommand(commands[i])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	count := 0
+	for i := 0; i < len(commands); i++ {
+		dropped, err := a.Control.RunAdminCommand(commands[i])
+		if err != nil {
+			util.DebugLogger.Println("Admin command over HTTP failed after ",
+				i, " of ", len(commands), " ran: ", err)
+			writeJSON(w, http.StatusInternalServerError,
+				map[string]interface{}{"Error": err.Error(), "Ran": i,
+					"Count": count})
+			return
+		}
+		count += dropped
+	}
+
+	util.DebugLogger.Println("Admin commands over HTTP dropped ", count,
+		" entries.")
+	writeJSON(w, http.StatusOK, map[string]int{"Count": count})
+}
+
+func (a *AdminServer) handleEvict(w http.ResponseWriter, r *http.Request) {
+	ids, err := blockIds(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	paths := r.Form["path"]
+	if len(ids) == 0 && len(paths) == 0 {
+		writeError(w, http.StatusBadRequest, "Give a block or a path.")
+		return
+	}
+
+	commands := make([]*cache_protocol.AdminCommand, 0)
+	for i := 0; i < len(ids); i++ {
+		command := cache_protocol.NewAdminCommand(
+			cache_protocol.ADMIN_EVICT_BLOCK)
+		command.BlockId = ids[i]
+		commands = append(commands, command)
+	}
+
+	for i := 0; i < len(paths); i++ {
+		command := cache_protocol.NewAdminCommand(
+			cache_protocol.ADMIN_EVICT_PATH)
+		command.Path = paths[i]
+		commands = append(commands, command)
+	}
+
+	a.runCommands(w, commands)
+}
+
+func (a *AdminServer) handleClear(w http.ResponseWriter, r *http.Request) {
+	target, err := cacheTarget(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	command := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_CLEAR)
+	command.Target = target
+	a.runCommands(w, []*cache_protocol.AdminCommand{command})
+}
+
+//answers /enable and /disable
+func (a *AdminServer) handleEnable(w http.ResponseWriter, r *http.Request) {
+	target, err := cacheTarget(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	op := cache_protocol.ADMIN_ENABLE
+	if r.URL.Path == "/disable" {
+		op = cache_protocol.ADMIN_DISABLE
+	}
+
+	command := cache_protocol.NewAdminCommand(op)
+	command.Target = target
+	a.runCommands(w, []*cache_protocol.AdminCommand{command})
+}
+
+//what happened to one of the blocks of /pin or /unpin
+type blockStatus struct {
+	BlockId uint64
+	Status string
+}
+
+//answers /pin and /unpin
+func (a *AdminServer) handlePin(w http.ResponseWriter, r *http.Request) {
+	ids, err := blockIds(r)
+	if err != nil || len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, "Give one or more blocks.")
+		return
+	}
+
+	handleBlock := a.Control.PinBlock
+	if r.URL.Path == "/unpin" {
+		handleBlock = a.Control.UnpinBlock
+	}
+
+	res := make([]blockStatus, len(ids))
+	for i := 0; i < len(ids); i++ {
+		res[i] = blockStatus{BlockId: ids[i],
+			Status: cache_protocol.BLOCK_STATUS_NAMES[handleBlock(ids[i])]}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,257 @@
+package admin_server
+
+import (
+	//go packages
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	//local packages
+	"cache_info_server"
+	"caches"
+	"cache_protocol"
+	"configuration"
+	"util"
+	"writables"
+)
+
+//an admin server (with secret "s") in front of a data cache
+//that holds blocks 1 to 3, of which 2 and 3 make up /file
+func startAdminServer() (*AdminServer, *httptest.Server) {
+	util.Init()
+
+	dataCache := caches.NewWritableDataCache(15)
+	for i := 1; i <= 3; i++ {
+		request := writables.NewReadBlockHeader()
+		request.BlockId = uint64(i)
+		pair, _ := dataCache.Join("", request)
+		pair.Complete()
+	}
+
+	blockIndex := caches.NewBlockLocationIndex()
+	locatedBlocks := writables.NewLocatedBlocks()
+	for i := 2; i <= 3; i++ {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = uint64(i)
+		locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr,
+			block)
+	}
+	blockIndex.Add("/file", locatedBlocks)
+
+	cacheSet := caches.NewCacheSet()
+	cacheSet.GetListingCache = caches.NewGetListingCache(5)
+
+	control := cache_info_server.NewCacheControl(dataCache)
+	control.BlockIndex = blockIndex
+	control.Caches = cacheSet
+
+	a := NewAdminServer("", control)
+	a.Secret = []byte("s")
+	return a, httptest.NewServer(a.Handler())
+}
+
+//sends a request to the admin server and decodes its answer into res
+func send(t *testing.T, method string, url string, secret string,
+	res interface{}) int {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer " + secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if res != nil {
+		err = json.NewDecoder(resp.Body).Decode(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestAdminServerAuth(t *testing.T) {
+	a, server := startAdminServer()
+	defer server.Close()
+
+	if send(t, "GET", server.URL + "/blocks", "", nil) != 
+		http.StatusUnauthorized {
+		t.Fail()
+	}
+
+	if send(t, "GET", server.URL + "/blocks", "wrong", nil) != 
+		http.StatusUnauthorized {
+		t.Fail()
+	}
+
+	if send(t, "GET", server.URL + "/evict?block=1", "s", nil) != 
+		http.StatusMethodNotAllowed {
+		t.Fail()
+	}
+
+	//without a secret anyone may look, but nobody may act
+	a.Secret = nil
+	if send(t, "GET", server.URL + "/blocks", "", nil) != http.StatusOK {
+		t.Fail()
+	}
+
+	if send(t, "POST", server.URL + "/evict?block=1", "", nil) != 
+		http.StatusForbidden {
+		t.Fail()
+	}
+}
+
+func TestAdminServerGet(t *testing.T) {
+	a, server := startAdminServer()
+	defer server.Close()
+
+	blocks := make(map[string]interface{})
+	status := send(t, "GET", server.URL + "/blocks", "s", &blocks)
+	if status != http.StatusOK || blocks["NumBlocks"] != float64(3) {
+		t.Fail()
+	}
+
+	metadata := make(map[string]metadataCache)
+	status = send(t, "GET", server.URL + "/metadata", "s", &metadata)
+	_, present := metadata["getlisting"]
+	if status != http.StatusOK || !present {
+		t.Fail()
+	}
+
+	stats := dataCacheStats{}
+	status = send(t, "GET", server.URL + "/stats", "s", &stats)
+	if status != http.StatusOK || !stats.Enabled || 
+		stats.Description.CurrSize != 3 {
+		t.Fail()
+	}
+
+	//no configuration given
+	if send(t, "GET", server.URL + "/config", "s", nil) != 
+		http.StatusNotFound {
+		t.Fail()
+	}
+
+	a.Config = configuration.NewConfiguration()
+	a.Config.CacheInfoPort = "1337"
+	a.Config.AdminSecret = "s"
+	a.Config.CacheInfoKey = "k"
+	config := configuration.NewConfiguration()
+	status = send(t, "GET", server.URL + "/config", "s", config)
+	if status != http.StatusOK || config.CacheInfoPort != "1337" ||
+		config.AdminSecret != "" || config.CacheInfoKey != "" {
+		t.Fail()
+	}
+
+	a.DataNodeMap = configuration.MakeDataNodeMap(
+		[]*configuration.DataNodeLocation{
+			configuration.NewDataNodeLocation("10.0.0.1", "50010")}, 2010)
+	dataNodes := make(configuration.DataNodeMap)
+	status = send(t, "GET", server.URL + "/datanodes", "s", &dataNodes)
+	if status != http.StatusOK || len(dataNodes) != 1 {
+		t.Fail()
+	}
+
+	a.Sessions = NewSessionList()
+	a.Sessions.Add(SESSION_DATANODE, "client", "datanode")
+	sessions := []Session{}
+	status = send(t, "GET", server.URL + "/sessions", "s", &sessions)
+	if status != http.StatusOK || len(sessions) != 1 || 
+		sessions[0].Client != "client" {
+		t.Fail()
+	}
+}
+
+func TestAdminServerActions(t *testing.T) {
+	a, server := startAdminServer()
+	defer server.Close()
+	dataCache := a.Control.DataCache
+
+	count := make(map[string]int)
+	status := send(t, "POST", server.URL + "/evict?block=1", "s", &count)
+	if status != http.StatusOK || count["Count"] != 1 || 
+		dataCache.CurrSize() != 2 {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/evict?path=/file", "s", &count)
+	if status != http.StatusOK || count["Count"] != 2 || 
+		dataCache.CurrSize() != 0 {
+		t.Fail()
+	}
+
+	if send(t, "POST", server.URL + "/evict?block=x", "s", nil) != 
+		http.StatusBadRequest {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/disable?cache=getlisting", "s", nil)
+	listingCache := a.Control.Caches.GetListingCache
+	if status != http.StatusOK || listingCache.IsEnabled() {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/enable?cache=getlisting", "s", nil)
+	if status != http.StatusOK || !listingCache.IsEnabled() {
+		t.Fail()
+	}
+
+	if send(t, "POST", server.URL + "/clear?cache=nope", "s", nil) != 
+		http.StatusBadRequest {
+		t.Fail()
+	}
+
+	//no Fetcher, so the pin cannot be carried out and is undone
+	dataCache.PinnedBytesLimit = 1 << 20
+	statuses := []blockStatus{}
+	status = send(t, "POST", server.URL + "/pin?block=2,7", "s", &statuses)
+	if status != http.StatusOK || len(statuses) != 2 ||
+		statuses[0].Status != "no_replica" || statuses[1].Status != "unknown" {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/unpin?block=2", "s", &statuses)
+	if status != http.StatusOK || statuses[0].Status != "not_pinned" {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/clear?cache=data", "s", &count)
+	if status != http.StatusOK || count["Count"] != 0 {
+		t.Fail()
+	}
+
+	status = send(t, "POST", server.URL + "/disable?cache=data", "s", nil)
+	if status != http.StatusOK || dataCache.IsEnabled() {
+		t.Fail()
+	}
+
+}
+
+func TestAdminServerBatchCheckedFirst(t *testing.T) {
+	a, server := startAdminServer()
+	defer server.Close()
+
+	evict := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_BLOCK)
+	evict.BlockId = 1
+	clear := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_CLEAR)
+	clear.Target = 42
+
+	w := httptest.NewRecorder()
+	a.runCommands(w, []*cache_protocol.AdminCommand{evict, clear})
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("Bad batch answered with ", w.Code)
+	}
+
+	//the good command that came first was not run either
+	if a.Control.DataCache.CurrSize() != 3 {
+		t.Fatal("Part of a bad batch ran.")
+	}
+}
@@ -0,0 +1,88 @@
+/*
+* Keeps track of the client connections the proxy is serving
+* (to the NameNode and to the DataNodes) so that they can be
+* listed through the admin server.
+*/
+
+package admin_server
+
+import (
+	//go packages
+	"sort"
+	"sync"
+	"time"
+
+	//local packages
+)
+
+/* Session.Kind */
+const (
+	SESSION_NAMENODE = "namenode"
+	SESSION_DATANODE = "datanode"
+)
+
+type Session struct {
+	//starts at 1 and goes up by one per session
+	Id uint64
+
+	//one of SESSION_*
+	Kind string
+
+	//address of the client and of the NameNode/DataNode
+	//it is being relayed to
+	Client string
+	Upstream string
+
+	Started time.Time
+}
+
+type SessionList struct {
+	sync.Mutex
+
+	lastId uint64
+	sessions map[uint64]*Session
+}
+
+func NewSessionList() *SessionList {
+	s := SessionList{}
+	s.sessions = make(map[uint64]*Session)
+	return &s
+}
+
+//records a session that starts now; it has to be
+//handed to Remove() once it is over
+func (s *SessionList) Add(kind string, client string, 
+	upstream string) *Session {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastId++
+	session := Session{Id: s.lastId,
+		Kind: kind,
+		Client: client,
+		Upstream: upstream,
+		Started: time.Now()}
+	s.sessions[session.Id] = &session
+	return &session
+}
+
+func (s *SessionList) Remove(session *Session) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.sessions, session.Id)
+}
+
+//the sessions that are going on, oldest first
+func (s *SessionList) List() []Session {
+	s.Lock()
+	defer s.Unlock()
+
+	res := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		res = append(res, *session)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
@@ -0,0 +1,29 @@
+package admin_server
+
+import (
+	//go packages
+	"testing"
+
+	//local packages
+)
+
+func TestSessionList(t *testing.T) {
+	s := NewSessionList()
+	first := s.Add(SESSION_NAMENODE, "1.2.3.4:5", "namenode:8020")
+	second := s.Add(SESSION_DATANODE, "1.2.3.4:6", "datanode:50010")
+
+	list := s.List()
+	if len(list) != 2 || list[0].Id != first.Id || list[1].Id != second.Id {
+		t.FailNow()
+	}
+
+	if list[1].Kind != SESSION_DATANODE || list[1].Upstream != "datanode:50010" {
+		t.Fail()
+	}
+
+	s.Remove(first)
+	list = s.List()
+	if len(list) != 1 || list[0].Id != second.Id {
+		t.Fail()
+	}
+}
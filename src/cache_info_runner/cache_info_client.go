@@ -1,12 +1,15 @@
 /*
 * Implements a simple
 * testing client for the CacheInfoServer.
-* Used for debugging and unit tests */
+* Used for debugging and unit tests (panthera-ctl is the
+* client to use against running caches) */
 
 package main
 
 import (
 	//go imports
+	"errors"
+	"flag"
 	"net"
 	"fmt"
 	
@@ -15,31 +18,83 @@ import (
 	"writable_processor"
 )
 
-func sendDescriptionRequest(server net.Conn) error {
+//has to match the key the server was started with (if any)
+var key = flag.String("key", "", "shared key to authenticate with")
+
+//every connection starts with a hello exchange
+func sayHello(serverObj *writable_processor.Connection) error {
+	err := cache_protocol.NewHello(0).Write(serverObj)
+	if err == nil {
+		err = serverObj.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	hello := new(cache_protocol.Hello)
+	err = cache_protocol.ReadResponse(serverObj, hello)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Server hello: ", *hello)
+	if !hello.HasCapability(cache_protocol.CAP_AUTH) {
+		return nil
+	}
+
+	return authenticate(serverObj)
+}
+
+//answers the challenge the server sends after its hello
+func authenticate(serverObj *writable_processor.Connection) error {
+	if *key == "" {
+		return errors.New("Server requires authentication, use -key.")
+	}
+
+	challenge := new(cache_protocol.AuthChallenge)
+	err := challenge.Read(serverObj)
+	if err != nil {
+		return err
+	}
+
+	res := cache_protocol.NewAuthResponse([]byte(*key), challenge)
+	err = res.Write(serverObj)
+	if err == nil {
+		err = serverObj.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cache_protocol.ReadResponse(serverObj, nil)
+}
+
+func sendDescriptionRequest(serverObj *writable_processor.Connection) error {
 	r := cache_protocol.NewRequest(cache_protocol.REQ_CACHE_DESCRIPTION)
-	serverObj := writable_processor.NewConnection(server)
 
 	err := r.Write(serverObj)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return serverObj.Flush()
 }
 
-func sendRequest(server net.Conn) {
-	err := sendDescriptionRequest(server)
+func sendRequest(serverObj *writable_processor.Connection) {
+	err := sendDescriptionRequest(serverObj)
 	if err != nil {
 		fmt.Println("Could not send request: ", err)
 	}
 }
 
 func readDescriptionResponse(
-	server net.Conn) (*cache_protocol.CacheDescription, error) {
+	serverObj *writable_processor.Connection) (
+	*cache_protocol.CacheDescription, error) {
 
-	serverObj := writable_processor.NewConnection(server)
 	descr := cache_protocol.NewCacheDescription()
-	err := descr.Read(serverObj)
+	err := cache_protocol.ReadResponse(serverObj, descr)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +102,8 @@ func readDescriptionResponse(
 	return descr, nil
 }
 
-func readResponse(server net.Conn) {
-	descr, err := readDescriptionResponse(server)
+func readResponse(serverObj *writable_processor.Connection) {
+	descr, err := readDescriptionResponse(serverObj)
 	if err != nil {
 		fmt.Println("Could not read response: ", err)
 	}
@@ -57,6 +112,7 @@ func readResponse(server net.Conn) {
 }
 
 func main() {
+	flag.Parse()
 	hostname := "localhost"
 	port := "1337"
 
@@ -65,6 +121,13 @@ func main() {
 		fmt.Println("Could not connection to cache_info_server: ", err)
 	}
 
-	sendRequest(server)
-	readResponse(server)
+	serverObj := writable_processor.NewConnection(server)
+	err = sayHello(serverObj)
+	if err != nil {
+		fmt.Println("Hello failed: ", err)
+		return
+	}
+
+	sendRequest(serverObj)
+	readResponse(serverObj)
 }
\ No newline at end of file
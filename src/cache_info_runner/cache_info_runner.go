@@ -6,6 +6,7 @@ package main
 
 import (
 	//go packages
+	"flag"
 	"fmt"
 
 	//local packages
@@ -13,13 +14,17 @@ import (
 	"caches"
 )
 
+//clients have to prove they know this key (empty => they don't)
+var authKey = flag.String("key", "", "shared key clients authenticate with")
 
 func main() {
+	flag.Parse()
 	fmt.Println("Starting cache_info_runner...")
 	port := "1337"
 	cacheSize := 15
 
 	dataCache := caches.NewWritableDataCache(cacheSize)
 	server := cache_info_server.NewCacheInfoServer(port, dataCache)
+	server.AuthKey = []byte(*authKey)
 	server.Start()
 }
\ No newline at end of file
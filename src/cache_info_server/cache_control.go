@@ -0,0 +1,225 @@
+/*
+* What the cache info server can do to the caches (prefetch, pin,
+* evict, clear...), kept apart from the protocol so that other
+* front ends (see admin_server) do exactly the same thing.
+*/
+
+package cache_info_server
+
+import (
+	//go packages
+	"errors"
+	"fmt"
+
+	//local packages
+	"caches"
+	"cache_protocol"
+)
+
+type CacheControl struct {
+	//instance of data cache (used to answer requests)
+	DataCache *caches.WritableDataCache
+
+	//see CacheInfoServer
+	BlockIndex *caches.BlockLocationIndex
+	Fetcher BlockFetcher
+	Caches *caches.CacheSet
+}
+
+func NewCacheControl(dataCache *caches.WritableDataCache) *CacheControl {
+	c := CacheControl{DataCache: dataCache}
+	return &c
+}
+
+//starts reading blockId into the cache
+func (c *CacheControl) PrefetchBlock(blockId uint64) uint16 {
+	if !c.DataCache.IsEnabled() {
+		return cache_protocol.BLOCK_STATUS_DISABLED
+	}
+
+	if c.BlockIndex == nil {
+		return cache_protocol.BLOCK_STATUS_UNKNOWN
+	}
+
+	block := c.BlockIndex.LookupBlock(blockId)
+	if block == nil {
+		return cache_protocol.BLOCK_STATUS_UNKNOWN
+	}
+
+	if c.Fetcher == nil || !c.Fetcher.Fetch(block) {
+		return cache_protocol.BLOCK_STATUS_NO_REPLICA
+	}
+
+	return cache_protocol.BLOCK_STATUS_OK
+}
+
+//pins blockId and reads it into the cache
+func (c *CacheControl) PinBlock(blockId uint64) uint16 {
+	if !c.DataCache.IsEnabled() {
+		return cache_protocol.BLOCK_STATUS_DISABLED
+	}
+
+	if c.BlockIndex == nil {
+		return cache_protocol.BLOCK_STATUS_UNKNOWN
+	}
+
+	block := c.BlockIndex.LookupBlock(blockId)
+	if block == nil {
+		return cache_protocol.BLOCK_STATUS_UNKNOWN
+	}
+
+	pinned, added := c.DataCache.Pin(blockId, int64(block.B.NumBytes))
+	if !pinned {
+		return cache_protocol.BLOCK_STATUS_PIN_LIMIT
+	}
+
+	//only undo the pin if it is ours; whoever pinned the
+	//block before keeps it pinned
+	status := c.PrefetchBlock(blockId)
+	if status != cache_protocol.BLOCK_STATUS_OK && added {
+		c.DataCache.Unpin(blockId)
+	}
+
+	return status
+}
+
+func (c *CacheControl) UnpinBlock(blockId uint64) uint16 {
+	if !c.DataCache.Unpin(blockId) {
+		return cache_protocol.BLOCK_STATUS_NOT_PINNED
+	}
+
+	return cache_protocol.BLOCK_STATUS_OK
+}
+
+func (c *CacheControl) checksumCaches() []*caches.BlockChecksumCache {
+	if c.Caches == nil {
+		return nil
+	}
+
+	return c.Caches.ChecksumCaches()
+}
+
+//drops blockId from the data cache and the OP_BLOCK_CHECKSUM
+//answers kept for it (which would otherwise vouch for a block
+//that was evicted because it was bad)
+func (c *CacheControl) evictBlock(blockId uint64) int {
+	count := c.DataCache.EvictBlock(blockId)
+	for _, checksumCache := range c.checksumCaches() {
+		count += checksumCache.EvictBlock(blockId)
+	}
+
+	return count
+}
+
+//returns the error RunAdminCommand() would fail command with,
+//without carrying it out
+func (c *CacheControl) CheckAdminCommand(
+	command *cache_protocol.AdminCommand) error {
+	switch command.Op {
+	case cache_protocol.ADMIN_EVICT_BLOCK, cache_protocol.ADMIN_EVICT_PATH:
+		return nil
+
+	case cache_protocol.ADMIN_CLEAR, cache_protocol.ADMIN_ENABLE,
+		cache_protocol.ADMIN_DISABLE:
+		if command.Target != cache_protocol.CACHE_DATA &&
+			c.RequestCache(command.Target) == nil {
+			return errors.New("No such cache.")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("Unknown admin command %d.", command.Op)
+}
+
+//carries out command; returns the number of cache entries dropped
+func (c *CacheControl) RunAdminCommand(
+	command *cache_protocol.AdminCommand) (int, error) {
+	switch command.Op {
+	case cache_protocol.ADMIN_EVICT_BLOCK:
+		return c.evictBlock(command.BlockId), nil
+
+	case cache_protocol.ADMIN_EVICT_PATH:
+		count := 0
+		if c.BlockIndex != nil {
+			blockIds := c.BlockIndex.BlockIds(command.Path)
+			for i := 0; i < len(blockIds); i++ {
+				count += c.evictBlock(blockIds[i])
+			}
+		}
+
+		if c.Caches != nil {
+			for _, target := range []uint16{cache_protocol.CACHE_GET_FILE_INFO,
+				cache_protocol.CACHE_GET_LISTING} {
+				requestCache := c.RequestCache(target)
+				if requestCache != nil {
+					count += requestCache.RemovePath(command.Path)
+				}
+			}
+		}
+		return count, nil
+
+	case cache_protocol.ADMIN_CLEAR:
+		if command.Target == cache_protocol.CACHE_DATA {
+			count := c.DataCache.Clear()
+			for _, checksumCache := range c.checksumCaches() {
+				count += checksumCache.Clear()
+			}
+			return count, nil
+		}
+
+		requestCache := c.RequestCache(command.Target)
+		if requestCache == nil {
+			return 0, errors.New("No such cache.")
+		}
+
+		count := requestCache.Len()
+		requestCache.Clear()
+		return count, nil
+
+	case cache_protocol.ADMIN_ENABLE, cache_protocol.ADMIN_DISABLE:
+		enable := command.Op == cache_protocol.ADMIN_ENABLE
+		if command.Target == cache_protocol.CACHE_DATA {
+			//the OP_BLOCK_CHECKSUM answers go with the data
+			c.DataCache.SetEnabled(enable)
+			for _, checksumCache := range c.checksumCaches() {
+				checksumCache.SetEnabled(enable)
+			}
+			return 0, nil
+		}
+
+		requestCache := c.RequestCache(command.Target)
+		if requestCache == nil {
+			return 0, errors.New("No such cache.")
+		}
+
+		if enable {
+			requestCache.Enable()
+		} else {
+			requestCache.Disable()
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("Unknown admin command %d.", command.Op)
+}
+
+//the metadata cache that target (one of cache_protocol.CACHE_*) 
+//names, or nil if there is no such cache
+func (c *CacheControl) RequestCache(target uint16) *caches.RequestCache {
+	if c.Caches == nil {
+		return nil
+	}
+
+	switch target {
+	case cache_protocol.CACHE_GET_FILE_INFO:
+		if c.Caches.GfiCache != nil {
+			return c.Caches.GfiCache.Cache
+		}
+	case cache_protocol.CACHE_GET_LISTING:
+		if c.Caches.GetListingCache != nil {
+			return c.Caches.GetListingCache.Cache
+		}
+	}
+
+	return nil
+}
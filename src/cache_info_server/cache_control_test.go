@@ -0,0 +1,214 @@
+package cache_info_server
+
+import (
+	//go imports
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	//local imports
+	"caches"
+	"cache_protocol"
+	"util"
+	"writable_processor"
+	"writables"
+)
+
+func TestCacheControlEnableSurvivesRelay(t *testing.T) {
+	util.Init()
+
+	d := caches.NewWritableDataCache(15)
+	d.SetEnabled(false)
+
+	c := NewCacheControl(d)
+	_, err := c.RunAdminCommand(adminCommand(cache_protocol.ADMIN_ENABLE,
+		cache_protocol.CACHE_DATA))
+	if err != nil || !d.IsEnabled() {
+		t.Fatal("Cache not enabled: ", err)
+	}
+
+	//a DataNode client connects (and goes away again)
+	client, server := net.Pipe()
+	w := writable_processor.New(d)
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(server, func() (net.Conn, error) {
+			return nil, errors.New("No DataNode.")
+		})
+		done <- true
+	}()
+	client.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Relay did not finish.")
+	}
+
+	if !d.IsEnabled() {
+		t.Fatal("Relay turned the data cache off.")
+	}
+}
+
+func adminCommand(op uint16, target uint16) *cache_protocol.AdminCommand {
+	command := cache_protocol.NewAdminCommand(op)
+	command.Target = target
+	return command
+}
+
+func addChecksum(b *caches.BlockChecksumCache, blockId uint64) {
+	request := writables.NewBlockChecksumHeader()
+	request.BlockId = blockId
+	request.GenerationStamp = 1001
+	response := writables.NewBlockChecksumResponse()
+	response.Status = uint16(writables.OP_STATUS_SUCCESS)
+	b.Add(request, response)
+}
+
+func TestCacheControlEvictChecksums(t *testing.T) {
+	checksumCache := caches.NewBlockChecksumCache(10)
+	addChecksum(checksumCache, 1)
+	addChecksum(checksumCache, 2)
+	addChecksum(checksumCache, 3)
+
+	cacheSet := caches.NewCacheSet()
+	cacheSet.AddChecksumCache(checksumCache)
+
+	c := NewCacheControl(caches.NewWritableDataCache(15))
+	c.Caches = cacheSet
+
+	evict := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_BLOCK)
+	evict.BlockId = 1
+	count, err := c.RunAdminCommand(evict)
+	if err != nil || count != 1 || checksumCache.CurrSize() != 2 {
+		t.Fatal("Checksum of evicted block kept: ", count, err)
+	}
+
+	request := writables.NewBlockChecksumHeader()
+	request.BlockId = 1
+	request.GenerationStamp = 1001
+	if checksumCache.Query(request) != nil {
+		t.Fail()
+	}
+
+	count, err = c.RunAdminCommand(adminCommand(cache_protocol.ADMIN_CLEAR,
+		cache_protocol.CACHE_DATA))
+	if err != nil || count != 2 || checksumCache.CurrSize() != 0 {
+		t.Fatal("Checksums not cleared: ", count, err)
+	}
+}
+
+//a block index that knows block 1, with its replica on the
+//DataNode called name
+func indexBlock(name string) *caches.BlockLocationIndex {
+	block := writables.NewLocatedBlock()
+	block.B.BlockId = 1
+	block.B.NumBytes = 5
+	info := writables.NewDataNodeInfo()
+	info.Id.Name = name
+	block.InfoLength = 1
+	block.InfoArr = []*writables.DataNodeInfo{info}
+
+	locatedBlocks := writables.NewLocatedBlocks()
+	locatedBlocks.NumberOfBlocks = 1
+	locatedBlocks.LocatedBlockArr = []*writables.LocatedBlock{block}
+
+	blockIndex := caches.NewBlockLocationIndex()
+	blockIndex.Add("/file", locatedBlocks)
+	return blockIndex
+}
+
+func TestCacheControlPinKeepsEarlierPin(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	d.PinnedBytesLimit = 100
+	d.Pin(1, 5)
+
+	//no Fetcher, so this pin cannot be carried out
+	c := NewCacheControl(d)
+	c.BlockIndex = indexBlock("127.0.0.1:1389")
+	if c.PinBlock(1) != cache_protocol.BLOCK_STATUS_NO_REPLICA {
+		t.Fail()
+	}
+
+	if !d.IsPinned(1) {
+		t.Fatal("Failed pin dropped an earlier one.")
+	}
+
+	d.Unpin(1)
+	c.PinBlock(1)
+	if d.IsPinned(1) {
+		t.Fatal("Failed pin kept.")
+	}
+}
+
+//the NameNode knows DataNodes by the relay ports they registered
+//through; the Prefetchers have to see past that
+func TestCacheControlPinRelayName(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	resolve := func(name string) string {
+		if name == "127.0.0.1:1389" {
+			return ln.Addr().String()
+		}
+		return name
+	}
+
+	d := caches.NewWritableDataCache(15)
+	d.PinnedBytesLimit = 100
+	blockIndex := indexBlock("127.0.0.1:1389")
+
+	prefetchers := writable_processor.NewPrefetcherSet()
+	prefetchers.Add(writable_processor.NewPrefetcher(d, blockIndex,
+		"127.0.0.1:1", 1, 0, resolve))
+	prefetchers.Add(writable_processor.NewPrefetcher(d, blockIndex,
+		ln.Addr().String(), 1, 0, resolve))
+
+	c := NewCacheControl(d)
+	c.BlockIndex = blockIndex
+	c.Fetcher = prefetchers
+	if c.PinBlock(1) != cache_protocol.BLOCK_STATUS_OK || !d.IsPinned(1) {
+		t.Fatal("Block with a replica behind a relay port not pinned.")
+	}
+
+	//the block is read from the DataNode behind the relay port
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestCacheControlDisableChecksums(t *testing.T) {
+	checksumCache := caches.NewBlockChecksumCache(10)
+	addChecksum(checksumCache, 1)
+
+	cacheSet := caches.NewCacheSet()
+	cacheSet.AddChecksumCache(checksumCache)
+
+	c := NewCacheControl(caches.NewWritableDataCache(15))
+	c.Caches = cacheSet
+
+	request := writables.NewBlockChecksumHeader()
+	request.BlockId = 1
+	request.GenerationStamp = 1001
+
+	_, err := c.RunAdminCommand(adminCommand(cache_protocol.ADMIN_DISABLE,
+		cache_protocol.CACHE_DATA))
+	if err != nil || checksumCache.IsEnabled() ||
+		checksumCache.Query(request) != nil {
+		t.Fatal("Checksum answered while the data cache is off: ", err)
+	}
+
+	_, err = c.RunAdminCommand(adminCommand(cache_protocol.ADMIN_ENABLE,
+		cache_protocol.CACHE_DATA))
+	if err != nil || checksumCache.Query(request) == nil {
+		t.Fatal("Checksum cache not turned back on: ", err)
+	}
+}
@@ -2,6 +2,7 @@ package cache_info_server
 
 import (
 	//go packages
+	"errors"
 	"math/rand"
 	"fmt"
 	"net"
@@ -10,6 +11,8 @@ import (
 	"caches"
 	"cache_protocol"
 	"writable_processor" //used for writable_processor.Connection
+	"writables"
+	"util"
 )
 
 type Processor struct {
@@ -19,8 +22,16 @@ type Processor struct {
 	//different ids
 	Id uint64
 
-	//instance of data cache (used to answer requests)
-	DataCache *caches.WritableDataCache
+	//the caches the requests are answered from (and
+	//what they may do to them)
+	CacheControl
+
+	//see CacheInfoServer
+	AdminSecret []byte
+	AuthKey []byte
+
+	//set once the client has logged in with AdminSecret
+	admin bool
 
 	//socket to to the client
 	Client *writable_processor.Connection
@@ -29,22 +40,322 @@ type Processor struct {
 func NewProcessor(dataCache *caches.WritableDataCache, 
 	client net.Conn) *Processor {
 
-	p := Processor{Id: uint64(rand.Int63n(999999999)),
-		DataCache: dataCache}
+	p := Processor{Id: uint64(rand.Int63n(999999999))}
+	p.DataCache = dataCache
 	c := writable_processor.NewConnection(client)
 	p.Client = c
 
 	return &p
 }
 
+//sends body to the client as the answer to its request
+func (p *Processor) respond(body writables.Writable) error {
+	err := cache_protocol.WriteResponse(p.Client, body)
+	if err != nil {
+		return err
+	}
+
+	return p.Client.Flush()
+}
+
+//tells the client why its request could not be answered
+func (p *Processor) respondError(code uint16, message string) error {
+	err := cache_protocol.WriteError(p.Client, code, message)
+	if err != nil {
+		return err
+	}
+
+	return p.Client.Flush()
+}
+
+//what this processor can do for its client (see cache_protocol.Hello)
+func (p *Processor) capabilities() uint64 {
+	capabilities := cache_protocol.CAP_EVENTS | cache_protocol.CAP_STATS
+	if p.BlockIndex != nil && p.Fetcher != nil {
+		capabilities |= cache_protocol.CAP_BLOCK_REQUESTS
+	}
+
+	if p.BlockIndex != nil {
+		capabilities |= cache_protocol.CAP_RESOLVE_PATHS
+	}
+
+	if len(p.AdminSecret) > 0 {
+		capabilities |= cache_protocol.CAP_ADMIN
+	}
+
+	if len(p.AuthKey) > 0 {
+		capabilities |= cache_protocol.CAP_AUTH
+	}
+
+	return capabilities
+}
+
+//reads the Hello the client starts with and answers it. If the
+//client speaks another version of the protocol or (with an
+//AuthKey) cannot prove that it knows the key, it is told so
+//and an error is returned.
+func (p *Processor) hello() error {
+	hello := new(cache_protocol.Hello)
+	err := hello.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	if hello.Version != cache_protocol.PROTOCOL_VERSION {
+		message := fmt.Sprintf("Cache speaks protocol version %d, " +
+			"client speaks version %d.", cache_protocol.PROTOCOL_VERSION,
+			hello.Version)
+		p.respondError(cache_protocol.ERR_VERSION_MISMATCH, message)
+		return errors.New(message)
+	}
+
+	err = p.respond(cache_protocol.NewHello(p.capabilities()))
+	if err != nil || len(p.AuthKey) == 0 {
+		return err
+	}
+
+	return p.authenticate()
+}
+
+//challenges the client to HMAC a random nonce with AuthKey
+func (p *Processor) authenticate() error {
+	challenge, err := cache_protocol.NewAuthChallenge()
+	if err != nil {
+		return err
+	}
+
+	err = challenge.Write(p.Client)
+	if err == nil {
+		err = p.Client.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	res := new(cache_protocol.AuthResponse)
+	err = res.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	if !cache_protocol.CheckAuthMac(p.AuthKey, challenge.Nonce, res.Mac) {
+		p.respondError(cache_protocol.ERR_UNAUTHORIZED, 
+			"Authentication failed.")
+		return errors.New("Client failed authentication.")
+	}
+
+	return p.respond(nil)
+}
+
 func (p *Processor) HandleCachedBlocks(r *cache_protocol.Request) error {
-	blocks := cache_protocol.CreateCachedBlocks(p.DataCache)
-	return blocks.Write(p.Client)
+	return p.respond(cache_protocol.CreateCachedBlocks(p.DataCache, 
+		p.BlockIndex))
 }
 
 func (p *Processor) HandleCacheDescription(r *cache_protocol.Request) error {
-	descr := cache_protocol.CreateCacheDescription(p.DataCache)
-	return descr.Write(p.Client)
+	return p.respond(cache_protocol.CreateCacheDescription(p.DataCache))
+}
+
+//reads the BlockIdList that follows r, works out a status for
+//each block with handleBlock and sends the statuses back
+func (p *Processor) handleBlockList(r *cache_protocol.Request,
+	handleBlock func(blockId uint64) uint16) error {
+	blockIds := new(cache_protocol.BlockIdList)
+	err := blockIds.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	statuses := cache_protocol.NewBlockStatuses()
+	for i := 0; i < len(blockIds.BlockIds); i++ {
+		blockId := blockIds.BlockIds[i]
+		statuses.Add(blockId, handleBlock(blockId))
+	}
+
+	return p.respond(statuses)
+}
+
+//answers r (whose BlockIdList has not been read yet) with
+//ERR_NOT_SUPPORTED and returns false unless this processor
+//can fetch blocks
+func (p *Processor) checkBlockRequests(r *cache_protocol.Request) (bool,
+	error) {
+	if p.capabilities() & cache_protocol.CAP_BLOCK_REQUESTS != 0 {
+		return true, nil
+	}
+
+	blockIds := new(cache_protocol.BlockIdList)
+	err := blockIds.Read(p.Client)
+	if err != nil {
+		return false, err
+	}
+
+	return false, p.respondError(cache_protocol.ERR_NOT_SUPPORTED,
+		"This cache cannot look up or fetch blocks.")
+}
+
+func (p *Processor) HandlePrefetchBlocks(r *cache_protocol.Request) error {
+	ok, err := p.checkBlockRequests(r)
+	if !ok {
+		return err
+	}
+
+	return p.handleBlockList(r, p.PrefetchBlock)
+}
+
+func (p *Processor) HandlePinBlocks(r *cache_protocol.Request) error {
+	ok, err := p.checkBlockRequests(r)
+	if !ok {
+		return err
+	}
+
+	return p.handleBlockList(r, p.PinBlock)
+}
+
+func (p *Processor) HandleUnpinBlocks(r *cache_protocol.Request) error {
+	return p.handleBlockList(r, p.UnpinBlock)
+}
+
+//answers a REQ_CACHE_STATS
+func (p *Processor) HandleCacheStats(r *cache_protocol.Request) error {
+	return p.respond(cache_protocol.CreateCacheStats(p.DataCache))
+}
+
+//answers a REQ_RESOLVE_PATHS with the blocks of the 
+//files that match the paths the client sends
+func (p *Processor) HandleResolvePaths(r *cache_protocol.Request) error {
+	paths := new(cache_protocol.PathList)
+	err := paths.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	if p.BlockIndex == nil {
+		return p.respondError(cache_protocol.ERR_NOT_SUPPORTED,
+			"This cache does not keep track of file blocks.")
+	}
+
+	resolved, err := cache_protocol.CreateResolvedPaths(p.BlockIndex, 
+		paths.Paths)
+	if err != nil {
+		return p.respondError(cache_protocol.ERR_BAD_REQUEST, err.Error())
+	}
+
+	return p.respond(resolved)
+}
+
+//answers a REQ_ADMIN_LOGIN with a fresh challenge; the client
+//may send admin requests from then on if it answers it with
+//the HMAC of the nonce under AdminSecret
+func (p *Processor) HandleAdminLogin(r *cache_protocol.Request) error {
+	if len(p.AdminSecret) == 0 {
+		return p.respondError(cache_protocol.ERR_NOT_SUPPORTED,
+			"Admin requests are turned off on this cache.")
+	}
+
+	challenge, err := cache_protocol.NewAuthChallenge()
+	if err != nil {
+		return err
+	}
+
+	err = p.respond(challenge)
+	if err != nil {
+		return err
+	}
+
+	res := new(cache_protocol.AuthResponse)
+	err = res.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	if !cache_protocol.CheckAuthMac(p.AdminSecret, challenge.Nonce, res.Mac) {
+		return p.respondError(cache_protocol.ERR_UNAUTHORIZED, 
+			"Wrong admin secret.")
+	}
+
+	p.admin = true
+	return p.respond(nil)
+}
+
+//answers a REQ_ADMIN
+func (p *Processor) HandleAdmin(r *cache_protocol.Request) error {
+	command := new(cache_protocol.AdminCommand)
+	err := command.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	if !p.admin {
+		return p.respondError(cache_protocol.ERR_UNAUTHORIZED,
+			"Admin requests need an admin login.")
+	}
+
+	count, err := p.RunAdminCommand(command)
+	if err != nil {
+		return p.respondError(cache_protocol.ERR_BAD_REQUEST, err.Error())
+	}
+
+	util.DebugLogger.Println("Admin command ", *command, " dropped ", count,
+		" entries.")
+	return p.respond(&cache_protocol.AdminResult{Count: uint32(count)})
+}
+
+//answers a REQ_CACHED_BLOCKS_SINCE with the events that
+//came after the sequence number the client sends
+func (p *Processor) HandleCachedBlocksSince(r *cache_protocol.Request) error {
+	since := new(cache_protocol.SinceRequest)
+	err := since.Read(p.Client)
+	if err != nil {
+		return err
+	}
+
+	return p.respond(cache_protocol.CreateCacheEvents(p.DataCache, since.Seq))
+}
+
+//sends the client every event of the cache until either the
+//client hangs up or it falls too far behind (in which case we
+//hang up on it, and it has to catch up with 
+//REQ_CACHED_BLOCKS_SINCE). The connection is closed once
+//this returns.
+func (p *Processor) HandleSubscribe(r *cache_protocol.Request) error {
+	defer p.Client.Close()
+
+	subscription := p.DataCache.Events.Subscribe()
+	defer subscription.Close()
+
+	start := cache_protocol.Subscription{LastSeq: subscription.StartSeq}
+	err := p.respond(&start)
+	if err != nil {
+		return err
+	}
+
+	//the client has nothing more to say, so a read only
+	//returns once it has gone away
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			_, err := p.Client.Read(buf)
+			if err != nil {
+				subscription.Close()
+				return
+			}
+		}
+	}()
+
+	for event := range subscription.Events {
+		err = cache_protocol.CreateCacheEvent(event).Write(p.Client)
+		if err == nil {
+			err = p.Client.Flush()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 //Looks at the request that is read and responds to it
@@ -54,26 +365,61 @@ func (p *Processor) HandleRequest(r *cache_protocol.Request) error {
 		return p.HandleCacheDescription(r)
 	case cache_protocol.REQ_CACHED_BLOCKS:
 		return p.HandleCachedBlocks(r)
+	case cache_protocol.REQ_PREFETCH_BLOCKS:
+		return p.HandlePrefetchBlocks(r)
+	case cache_protocol.REQ_PIN_BLOCKS:
+		return p.HandlePinBlocks(r)
+	case cache_protocol.REQ_UNPIN_BLOCKS:
+		return p.HandleUnpinBlocks(r)
+	case cache_protocol.REQ_SUBSCRIBE:
+		return p.HandleSubscribe(r)
+	case cache_protocol.REQ_CACHED_BLOCKS_SINCE:
+		return p.HandleCachedBlocksSince(r)
+	case cache_protocol.REQ_CACHE_STATS:
+		return p.HandleCacheStats(r)
+	case cache_protocol.REQ_RESOLVE_PATHS:
+		return p.HandleResolvePaths(r)
+	case cache_protocol.REQ_ADMIN_LOGIN:
+		return p.HandleAdminLogin(r)
+	case cache_protocol.REQ_ADMIN:
+		return p.HandleAdmin(r)
 	}
-	return nil
+
+	//the client would wait forever for an answer otherwise
+	return p.respondError(cache_protocol.ERR_UNKNOWN_REQUEST, 
+		fmt.Sprintf("Unknown request type %d.", r.RequestType))
 }
 
-//handles the client. Reads cache_protocol.Request instances
-//and responds accordingly
+//handles the client. After the hello exchange, reads
+//cache_protocol.Request instances and responds accordingly.
+//Requests the processor cannot make sense of are answered with
+//an error; if one cannot even be read (or the answer cannot be
+//written), the stream is out of step with the client and the
+//connection is closed.
 func (p *Processor) HandleClient() error {
+	defer p.Client.Close()
+
+	err := p.hello()
+	if err != nil {
+		util.DebugLogger.Println("Handshake with client failed: ", err)
+		return err
+	}
+
 	for {
 		r := new(cache_protocol.Request)
 
 		//read in the request from the client
-		err := r.Read(p.Client)
+		err = r.Read(p.Client)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("Request received: ", *r)
+		util.TempLogger.Println("Request received: ", *r)
 		err = p.HandleRequest(r)
 		if err != nil {
-			fmt.Println("Could not process request: ", err)
+			util.DebugLogger.Println("Could not process request ", *r,
+				", closing the connection: ", err)
+			return err
 		}
 	}
 }
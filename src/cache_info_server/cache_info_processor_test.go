@@ -3,13 +3,21 @@ package cache_info_server
 import (
 	//go imports
 	"testing"
+	"net"
+	"sync"
 
+	//local imports
+	"caches"
+	"cache_protocol"
+	"writable_processor"
+	"writables"
+	"util"
 )
 
 func TestProcessorNew(t *testing.T) {
 	setup()
 
-	p := NewProcessor(dataCache)
+	p := NewProcessor(dataCache, nil)
 	if p == nil {
 		t.Fail()
 	}
@@ -18,3 +26,583 @@ func TestProcessorNew(t *testing.T) {
 		t.Fail()
 	}
 }
+
+//remembers the blocks it was asked to fetch; can only fetch
+//the ones in canFetch
+type fakeFetcher struct {
+	canFetch map[uint64]bool
+	fetched []uint64
+}
+
+func (f *fakeFetcher) Fetch(block *writables.LocatedBlock) bool {
+	if !f.canFetch[block.B.BlockId] {
+		return false
+	}
+
+	f.fetched = append(f.fetched, block.B.BlockId)
+	return true
+}
+
+//a processor answering requests on one end of a loopback
+//connection; the other end is returned (once the hello
+//exchange is over)
+func startProcessor(t *testing.T, p *Processor) *writable_processor.Connection {
+	conn := connectProcessor(t, p)
+
+	cache_protocol.NewHello(0).Write(conn)
+	conn.Flush()
+
+	err := cache_protocol.ReadResponse(conn, new(cache_protocol.Hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+//the loggers are set up once: processors (and the prefetches
+//they start) from earlier tests may still be logging
+var initLoggers sync.Once
+
+func connectProcessor(t *testing.T, p *Processor) *writable_processor.Connection {
+	initLoggers.Do(func() { util.Init() })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		p.Client = writable_processor.NewConnection(conn)
+		p.HandleClient()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writable_processor.NewConnection(conn)
+}
+
+func sendBlockList(t *testing.T, conn *writable_processor.Connection,
+	requestType uint16, blockIds ...uint64) []uint16 {
+	cache_protocol.NewRequest(requestType).Write(conn)
+	cache_protocol.NewBlockIdList(blockIds).Write(conn)
+	conn.Flush()
+
+	res := cache_protocol.NewBlockStatuses()
+	err := cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := make([]uint16, 0)
+	for i := 0; i < len(res.Statuses); i++ {
+		if res.Statuses[i].BlockId != blockIds[i] {
+			t.Fail()
+		}
+		statuses = append(statuses, res.Statuses[i].Status)
+	}
+
+	return statuses
+}
+
+func TestProcessorPrefetchAndPin(t *testing.T) {
+	blockIndex := caches.NewBlockLocationIndex()
+	locatedBlocks := writables.NewLocatedBlocks()
+	for i := 7; i <= 9; i++ {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = uint64(i)
+		block.B.NumBytes = uint64(50 * (i - 6))
+		locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr,
+			block)
+	}
+	blockIndex.Add("/file", locatedBlocks)
+
+	d := caches.NewWritableDataCache(15)
+	d.PinnedBytesLimit = 120
+
+	fetcher := &fakeFetcher{canFetch: map[uint64]bool{7: true, 8: true}}
+	p := NewProcessor(d, nil)
+	p.BlockIndex = blockIndex
+	p.Fetcher = fetcher
+
+	conn := startProcessor(t, p)
+	defer conn.Close()
+
+	statuses := sendBlockList(t, conn, cache_protocol.REQ_PREFETCH_BLOCKS, 
+		7, 9, 10)
+	if statuses[0] != cache_protocol.BLOCK_STATUS_OK ||
+		statuses[1] != cache_protocol.BLOCK_STATUS_NO_REPLICA ||
+		statuses[2] != cache_protocol.BLOCK_STATUS_UNKNOWN {
+		t.Fail()
+	}
+
+	//7 and 8 are 150 bytes together
+	statuses = sendBlockList(t, conn, cache_protocol.REQ_PIN_BLOCKS, 7, 8)
+	if statuses[0] != cache_protocol.BLOCK_STATUS_OK ||
+		statuses[1] != cache_protocol.BLOCK_STATUS_PIN_LIMIT {
+		t.Fail()
+	}
+
+	if !d.IsPinned(7) || d.IsPinned(8) || len(fetcher.fetched) != 2 {
+		t.Fail()
+	}
+
+	//a block that can't be fetched isn't left pinned
+	statuses = sendBlockList(t, conn, cache_protocol.REQ_PIN_BLOCKS, 9)
+	if statuses[0] != cache_protocol.BLOCK_STATUS_PIN_LIMIT {
+		t.Fail()
+	}
+
+	statuses = sendBlockList(t, conn, cache_protocol.REQ_UNPIN_BLOCKS, 7, 7)
+	if statuses[0] != cache_protocol.BLOCK_STATUS_OK ||
+		statuses[1] != cache_protocol.BLOCK_STATUS_NOT_PINNED {
+		t.Fail()
+	}
+
+	d.Enabled = false
+	statuses = sendBlockList(t, conn, cache_protocol.REQ_PREFETCH_BLOCKS, 7)
+	if statuses[0] != cache_protocol.BLOCK_STATUS_DISABLED {
+		t.Fail()
+	}
+}
+
+func TestProcessorSubscribe(t *testing.T) {
+	d := caches.NewWritableDataCache(1)
+	d.PinnedBytesLimit = 100
+	d.Pin(1, 10)
+
+	conn := startProcessor(t, NewProcessor(d, nil))
+	defer conn.Close()
+
+	cache_protocol.NewRequest(cache_protocol.REQ_SUBSCRIBE).Write(conn)
+	conn.Flush()
+
+	start := cache_protocol.Subscription{}
+	err := cache_protocol.ReadResponse(conn, &start)
+	if err != nil || start.LastSeq != 1 {
+		t.Fatal(err)
+	}
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 2
+	pair, _ := d.Join("dn:1", request)
+	pair.Complete()
+	d.Unpin(1)
+
+	expected := []uint16{cache_protocol.EVENT_INSERT, 
+		cache_protocol.EVENT_UNPIN}
+	for i := 0; i < len(expected); i++ {
+		event := cache_protocol.NewCacheEvent()
+		err = event.Read(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if event.Seq != uint64(i + 2) || event.Type != expected[i] {
+			t.Fail()
+		}
+	}
+}
+
+func TestProcessorCachedBlocksSince(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	d.PinnedBytesLimit = 100
+	for i := 1; i <= 3; i++ {
+		d.Pin(uint64(i), 10)
+	}
+
+	conn := startProcessor(t, NewProcessor(d, nil))
+	defer conn.Close()
+
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHED_BLOCKS_SINCE).Write(conn)
+	since := cache_protocol.SinceRequest{Seq: 1}
+	since.Write(conn)
+	conn.Flush()
+
+	res := cache_protocol.NewCacheEvents()
+	err := cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Complete || res.LastSeq != 3 || res.NumEvents != 2 ||
+		res.Events[0].BlockId != 2 {
+		t.Fail()
+	}
+}
+
+func TestProcessorCacheStats(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	d.Stats.RecordMiss(4, 100, 10)
+
+	conn := startProcessor(t, NewProcessor(d, nil))
+	defer conn.Close()
+
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHE_STATS).Write(conn)
+	conn.Flush()
+
+	res := cache_protocol.NewCacheStats()
+	err := cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Misses != 1 || res.BytesFromDataNode != 100 || res.NumBlocks != 1 {
+		t.Fail()
+	}
+}
+
+func TestProcessorHelloVersionMismatch(t *testing.T) {
+	conn := connectProcessor(t, NewProcessor(caches.NewWritableDataCache(15), nil))
+	defer conn.Close()
+
+	hello := cache_protocol.NewHello(0)
+	hello.Version = cache_protocol.PROTOCOL_VERSION + 1
+	hello.Write(conn)
+	conn.Flush()
+
+	err := cache_protocol.ReadResponse(conn, new(cache_protocol.Hello))
+	res, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || res.Code != cache_protocol.ERR_VERSION_MISMATCH {
+		t.FailNow()
+	}
+
+	//and the cache hangs up
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+//does the hello exchange with a processor that has an AuthKey
+//and answers its challenge with key
+func helloWithKey(t *testing.T, conn *writable_processor.Connection,
+	key string) error {
+	cache_protocol.NewHello(0).Write(conn)
+	conn.Flush()
+
+	hello := new(cache_protocol.Hello)
+	err := cache_protocol.ReadResponse(conn, hello)
+	if err != nil || !hello.HasCapability(cache_protocol.CAP_AUTH) {
+		t.Fatal("No challenge: ", err)
+	}
+
+	challenge := new(cache_protocol.AuthChallenge)
+	err = challenge.Read(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache_protocol.NewAuthResponse([]byte(key), challenge).Write(conn)
+	conn.Flush()
+
+	return cache_protocol.ReadResponse(conn, nil)
+}
+
+func TestProcessorAuth(t *testing.T) {
+	p := NewProcessor(caches.NewWritableDataCache(15), nil)
+	p.AuthKey = []byte("key")
+	conn := connectProcessor(t, p)
+	defer conn.Close()
+
+	err := helloWithKey(t, conn, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//requests are answered once we are in
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHE_DESCRIPTION).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, 
+		cache_protocol.NewCacheDescription())
+	if err != nil {
+		t.Fail()
+	}
+}
+
+func TestProcessorAuthWrongKey(t *testing.T) {
+	p := NewProcessor(caches.NewWritableDataCache(15), nil)
+	p.AuthKey = []byte("key")
+	conn := connectProcessor(t, p)
+	defer conn.Close()
+
+	err := helloWithKey(t, conn, "not the key")
+	res, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || res.Code != cache_protocol.ERR_UNAUTHORIZED {
+		t.FailNow()
+	}
+
+	//the request is never answered; the cache hangs up
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHED_BLOCKS).Write(conn)
+	conn.Flush()
+
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestProcessorErrors(t *testing.T) {
+	p := NewProcessor(caches.NewWritableDataCache(15), nil)
+	conn := connectProcessor(t, p)
+	defer conn.Close()
+
+	cache_protocol.NewHello(0).Write(conn)
+	conn.Flush()
+
+	//no BlockIndex => no block requests
+	server := new(cache_protocol.Hello)
+	err := cache_protocol.ReadResponse(conn, server)
+	if err != nil || server.HasCapability(cache_protocol.CAP_BLOCK_REQUESTS) ||
+		!server.HasCapability(cache_protocol.CAP_STATS) {
+		t.FailNow()
+	}
+
+	cache_protocol.NewRequest(999).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, nil)
+	res, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || res.Code != cache_protocol.ERR_UNKNOWN_REQUEST {
+		t.Fail()
+	}
+
+	cache_protocol.NewRequest(cache_protocol.REQ_PIN_BLOCKS).Write(conn)
+	cache_protocol.NewBlockIdList([]uint64{1}).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, cache_protocol.NewBlockStatuses())
+	res, ok = err.(*cache_protocol.ErrorResponse)
+	if !ok || res.Code != cache_protocol.ERR_NOT_SUPPORTED {
+		t.Fail()
+	}
+
+	//the connection is still good
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHE_DESCRIPTION).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, cache_protocol.NewCacheDescription())
+	if err != nil {
+		t.Fail()
+	}
+}
+
+func TestProcessorTruncatedRequest(t *testing.T) {
+	p := NewProcessor(caches.NewWritableDataCache(15), nil)
+	p.BlockIndex = caches.NewBlockLocationIndex()
+	p.Fetcher = new(fakeFetcher)
+	conn := startProcessor(t, p)
+	defer conn.Close()
+
+	//the list says two blocks but only one follows before the
+	//client stops writing
+	cache_protocol.NewRequest(cache_protocol.REQ_PREFETCH_BLOCKS).Write(conn)
+	writables.WriteInt(2, conn)
+	writables.WriteLongInt(1, conn)
+	conn.Flush()
+	conn.Conn.(*net.TCPConn).CloseWrite()
+
+	//no error frame: the processor hangs up
+	err := cache_protocol.ReadResponse(conn, cache_protocol.NewBlockStatuses())
+	if err == nil {
+		t.FailNow()
+	}
+
+	if _, ok := err.(*cache_protocol.ErrorResponse); ok {
+		t.Fail()
+	}
+}
+
+func TestProcessorResolvePaths(t *testing.T) {
+	blockIndex := caches.NewBlockLocationIndex()
+	locatedBlocks := writables.NewLocatedBlocks()
+	block := writables.NewLocatedBlock()
+	block.B.BlockId = 12
+	locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr, block)
+	blockIndex.Add("/data/in", locatedBlocks)
+
+	p := NewProcessor(caches.NewWritableDataCache(15), nil)
+	p.BlockIndex = blockIndex
+	conn := startProcessor(t, p)
+	defer conn.Close()
+
+	cache_protocol.NewRequest(cache_protocol.REQ_RESOLVE_PATHS).Write(conn)
+	cache_protocol.NewPathList([]string{"/data/*"}).Write(conn)
+	conn.Flush()
+
+	res := cache_protocol.NewResolvedPaths()
+	err := cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.NumFiles != 1 || res.Files[0].Path != "/data/in" ||
+		res.Files[0].BlockIds[0] != 12 {
+		t.Fail()
+	}
+
+	//a bad pattern is an error, but not the end of the connection
+	cache_protocol.NewRequest(cache_protocol.REQ_RESOLVE_PATHS).Write(conn)
+	cache_protocol.NewPathList([]string{"/data/["}).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, res)
+	errorResponse, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || errorResponse.Code != cache_protocol.ERR_BAD_REQUEST {
+		t.Fail()
+	}
+
+	cache_protocol.NewRequest(cache_protocol.REQ_CACHE_DESCRIPTION).Write(conn)
+	conn.Flush()
+
+	err = cache_protocol.ReadResponse(conn, cache_protocol.NewCacheDescription())
+	if err != nil {
+		t.Fail()
+	}
+}
+
+//sends command and returns the number of entries it dropped,
+//or the error code the processor answered with
+func sendAdminCommand(t *testing.T, conn *writable_processor.Connection,
+	command *cache_protocol.AdminCommand) (uint32, uint16) {
+	cache_protocol.NewRequest(cache_protocol.REQ_ADMIN).Write(conn)
+	command.Write(conn)
+	conn.Flush()
+
+	res := new(cache_protocol.AdminResult)
+	err := cache_protocol.ReadResponse(conn, res)
+	if err == nil {
+		return res.Count, 0
+	}
+
+	errorResponse, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok {
+		t.Fatal(err)
+	}
+
+	return 0, errorResponse.Code
+}
+
+func sendAdminLogin(conn *writable_processor.Connection, secret string) error {
+	cache_protocol.NewRequest(cache_protocol.REQ_ADMIN_LOGIN).Write(conn)
+	conn.Flush()
+
+	challenge := new(cache_protocol.AuthChallenge)
+	err := cache_protocol.ReadResponse(conn, challenge)
+	if err != nil {
+		return err
+	}
+
+	cache_protocol.NewAuthResponse([]byte(secret), challenge).Write(conn)
+	conn.Flush()
+
+	return cache_protocol.ReadResponse(conn, nil)
+}
+
+func TestProcessorAdmin(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	for i := 1; i <= 3; i++ {
+		request := writables.NewReadBlockHeader()
+		request.BlockId = uint64(i)
+		d.Join("", request)
+	}
+
+	blockIndex := caches.NewBlockLocationIndex()
+	locatedBlocks := writables.NewLocatedBlocks()
+	for i := 2; i <= 3; i++ {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = uint64(i)
+		locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr,
+			block)
+	}
+	blockIndex.Add("/file", locatedBlocks)
+
+	cacheSet := caches.NewCacheSet()
+	cacheSet.GetListingCache = caches.NewGetListingCache(5)
+
+	p := NewProcessor(d, nil)
+	p.BlockIndex = blockIndex
+	p.Caches = cacheSet
+	p.AdminSecret = []byte("secret")
+	conn := startProcessor(t, p)
+	defer conn.Close()
+
+	evict := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_BLOCK)
+	evict.BlockId = 1
+
+	//not logged in yet
+	_, code := sendAdminCommand(t, conn, evict)
+	if code != cache_protocol.ERR_UNAUTHORIZED || d.CurrSize() != 3 {
+		t.Fail()
+	}
+
+	err := sendAdminLogin(conn, "wrong")
+	errorResponse, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || errorResponse.Code != cache_protocol.ERR_UNAUTHORIZED {
+		t.Fail()
+	}
+
+	err = sendAdminLogin(conn, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, code := sendAdminCommand(t, conn, evict)
+	if code != 0 || count != 1 || d.CurrSize() != 2 {
+		t.Fail()
+	}
+
+	evictPath := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_PATH)
+	evictPath.Path = "/file"
+	count, code = sendAdminCommand(t, conn, evictPath)
+	if code != 0 || count != 2 || d.CurrSize() != 0 {
+		t.Fail()
+	}
+
+	enable := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_DISABLE)
+	enable.Target = cache_protocol.CACHE_GET_LISTING
+	_, code = sendAdminCommand(t, conn, enable)
+	if code != 0 || cacheSet.GetListingCache.IsEnabled() {
+		t.Fail()
+	}
+
+	enable.Op = cache_protocol.ADMIN_ENABLE
+	_, code = sendAdminCommand(t, conn, enable)
+	if code != 0 || !cacheSet.GetListingCache.IsEnabled() {
+		t.Fail()
+	}
+
+	disable := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_DISABLE)
+	disable.Target = cache_protocol.CACHE_DATA
+	_, code = sendAdminCommand(t, conn, disable)
+	if code != 0 || d.IsEnabled() {
+		t.Fail()
+	}
+
+	clear := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_CLEAR)
+	clear.Target = 42
+	_, code = sendAdminCommand(t, conn, clear)
+	if code != cache_protocol.ERR_BAD_REQUEST {
+		t.Fail()
+	}
+}
+
+func TestProcessorAdminTurnedOff(t *testing.T) {
+	conn := startProcessor(t, NewProcessor(caches.NewWritableDataCache(15), nil))
+	defer conn.Close()
+
+	err := sendAdminLogin(conn, "")
+	errorResponse, ok := err.(*cache_protocol.ErrorResponse)
+	if !ok || errorResponse.Code != cache_protocol.ERR_NOT_SUPPORTED {
+		t.Fail()
+	}
+}
@@ -13,8 +13,16 @@ import (
 
 	//local packages
 	"caches"
+	"writables"
 )
 
+//reads blocks into the data cache from one of their DataNodes
+//(see writable_processor.PrefetcherSet)
+type BlockFetcher interface {
+	//returns false if none of block's DataNodes can be used
+	Fetch(block *writables.LocatedBlock) bool
+}
+
 type CacheInfoServer struct {
 	//unique id number for this instance of the server.
 	//used primarily for debugging/logging purposes.
@@ -26,6 +34,27 @@ type CacheInfoServer struct {
 	//the caches.WritableDataCache associated with this server (each server
 	//deals with one cache instance)
 	DataCache *caches.WritableDataCache
+
+	//where the blocks named in prefetch and pin requests are
+	//looked up (nil => all of them are unknown)
+	BlockIndex *caches.BlockLocationIndex
+
+	//used to answer prefetch and pin requests (nil => 
+	//prefetching is not possible)
+	Fetcher BlockFetcher
+
+	//the metadata caches that admin requests may clear or
+	//turn on and off (nil => only the data cache)
+	Caches *caches.CacheSet
+
+	//what a client has to log in with before it may send admin
+	//requests (empty => admin requests are turned off)
+	AdminSecret []byte
+
+	//key that clients have to prove they know (by HMACing a
+	//challenge with it) before any of their requests are
+	//answered (empty => clients are not authenticated)
+	AuthKey []byte
 }
 
 func NewCacheInfoServer(port string, 
@@ -55,6 +84,11 @@ func (c *CacheInfoServer) Start() error {
 
 		//set up and run the processor
 		proc := NewProcessor(c.DataCache, client)
+		proc.BlockIndex = c.BlockIndex
+		proc.Fetcher = c.Fetcher
+		proc.Caches = c.Caches
+		proc.AdminSecret = c.AdminSecret
+		proc.AuthKey = c.AuthKey
 		go proc.HandleClient()
 	}
 }
@@ -0,0 +1,130 @@
+/*
+* Requests that change what is in a cache (or whether it caches
+* at all) rather than just asking about it. They are only
+* answered once the client has logged in as an admin.
+*/
+
+package cache_protocol
+
+import (
+	//go imports
+
+	//local imports
+	"writables"
+)
+
+/* AdminCommand.Op */
+const (
+	//drop every cached read of AdminCommand.BlockId
+	ADMIN_EVICT_BLOCK = uint16(iota)
+
+	//drop the cached blocks of AdminCommand.Path and the
+	//metadata cached about it
+	ADMIN_EVICT_PATH
+
+	//drop everything in AdminCommand.Target
+	ADMIN_CLEAR
+
+	//turn AdminCommand.Target on or off
+	ADMIN_ENABLE
+	ADMIN_DISABLE
+)
+
+/* AdminCommand.Target */
+const (
+	CACHE_DATA = uint16(iota)
+	CACHE_GET_FILE_INFO
+	CACHE_GET_LISTING
+)
+
+//what users call the AdminCommand.Target caches
+var CACHE_NAMES = map[string]uint16{
+	"data": CACHE_DATA,
+	"getfileinfo": CACHE_GET_FILE_INFO,
+	"getlisting": CACHE_GET_LISTING,
+}
+
+/**
+* AdminCommand
+* Follows the Request for REQ_ADMIN
+*/
+
+type AdminCommand struct {
+	Op uint16
+
+	//which cache ADMIN_CLEAR, ADMIN_ENABLE and
+	//ADMIN_DISABLE apply to
+	Target uint16
+
+	//for ADMIN_EVICT_BLOCK
+	BlockId uint64
+
+	//for ADMIN_EVICT_PATH
+	Path string
+}
+
+func NewAdminCommand(op uint16) *AdminCommand {
+	a := AdminCommand{Op: op}
+	return &a
+}
+
+func (a *AdminCommand) Read(reader writables.Reader) error {
+	var err error
+	a.Op, err = writables.ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	a.Target, err = writables.ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	a.BlockId, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	a.Path, err = readString(reader)
+	return err
+}
+
+func (a *AdminCommand) Write(writer writables.Writer) error {
+	err := writables.WriteShortInt(a.Op, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteShortInt(a.Target, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteLongInt(a.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	return writeString(a.Path, writer)
+}
+
+/**
+* AdminResult
+* Answers REQ_ADMIN
+*/
+
+type AdminResult struct {
+	//number of cache entries dropped (0 for
+	//ADMIN_ENABLE and ADMIN_DISABLE)
+	Count uint32
+}
+
+func (a *AdminResult) Read(reader writables.Reader) error {
+	var err error
+	a.Count, err = writables.ReadInt(reader)
+	return err
+}
+
+func (a *AdminResult) Write(writer writables.Writer) error {
+	return writables.WriteInt(a.Count, writer)
+}
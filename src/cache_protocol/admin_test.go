@@ -0,0 +1,29 @@
+package cache_protocol
+
+import (
+	//go imports
+	"bytes"
+	"reflect"
+	"testing"
+
+	//local imports
+)
+
+func TestAdminCommandReadWrite(t *testing.T) {
+	a := NewAdminCommand(ADMIN_EVICT_PATH)
+	a.Target = CACHE_GET_LISTING
+	a.BlockId = 1 << 33
+	a.Path = "/user/a"
+
+	buf := new(bytes.Buffer)
+	err := a.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(AdminCommand)
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(a, res) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,92 @@
+/*
+* Challenge-response authentication of clients. A cache that
+* has a shared key says so with CAP_AUTH in its Hello and sends
+* an AuthChallenge right after it. The client has to answer with
+* the HMAC of the challenge under the key before the cache reads
+* any of its requests.
+*/
+
+package cache_protocol
+
+import (
+	//go imports
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+
+	//local imports
+	"writables"
+)
+
+//number of random bytes in an AuthChallenge
+var AUTH_NONCE_SIZE = 32
+
+//number of bytes in an AuthResponse (HMAC-SHA256)
+var AUTH_MAC_SIZE = sha256.Size
+
+//HMAC-SHA256 of nonce under key
+func ComputeAuthMac(key []byte, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+//whether mac proves that whoever sent it knows key
+func CheckAuthMac(key []byte, nonce []byte, mac []byte) bool {
+	return hmac.Equal(mac, ComputeAuthMac(key, nonce))
+}
+
+/**
+* AuthChallenge
+* Follows the cache's Hello if it has CAP_AUTH
+*/
+
+type AuthChallenge struct {
+	Nonce []byte
+}
+
+//a challenge with a fresh random nonce
+func NewAuthChallenge() (*AuthChallenge, error) {
+	a := AuthChallenge{Nonce: make([]byte, AUTH_NONCE_SIZE)}
+	_, err := rand.Read(a.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (a *AuthChallenge) Read(reader writables.Reader) error {
+	var err error
+	a.Nonce, err = writables.ReadBytesIO(int64(AUTH_NONCE_SIZE), reader)
+	return err
+}
+
+func (a *AuthChallenge) Write(writer writables.Writer) error {
+	return writables.WriteBytes(a.Nonce, int64(len(a.Nonce)), writer)
+}
+
+/**
+* AuthResponse
+* Sent by the client in answer to an AuthChallenge; the cache
+* answers it with an empty response or ERR_UNAUTHORIZED
+*/
+
+type AuthResponse struct {
+	Mac []byte
+}
+
+func NewAuthResponse(key []byte, challenge *AuthChallenge) *AuthResponse {
+	a := AuthResponse{Mac: ComputeAuthMac(key, challenge.Nonce)}
+	return &a
+}
+
+func (a *AuthResponse) Read(reader writables.Reader) error {
+	var err error
+	a.Mac, err = writables.ReadBytesIO(int64(AUTH_MAC_SIZE), reader)
+	return err
+}
+
+func (a *AuthResponse) Write(writer writables.Writer) error {
+	return writables.WriteBytes(a.Mac, int64(len(a.Mac)), writer)
+}
@@ -8,12 +8,31 @@ package cache_protocol
 
 import (
 	//go imports
+	"sort"
 
 	//local imports
 	"caches"
 	"writables"
 )
 
+//strings go over the wire as a writables.Text
+func readString(reader writables.Reader) (string, error) {
+	text := writables.NewText()
+	err := text.Read(reader)
+	if err != nil {
+		return "", err
+	}
+
+	return string(text.Bytes), nil
+}
+
+func writeString(value string, writer writables.Writer) error {
+	text := writables.NewText()
+	text.Bytes = []byte(value)
+	text.Length = int64(len(text.Bytes))
+	return text.Write(writer)
+}
+
 /* CacheDescription.ReplaceAlgorithm */
 const (
 	//least recently used cache algorithm
@@ -101,6 +120,21 @@ func (c *CacheDescription) Write(writer writables.Writer) error {
 type BlockDescription struct {
 	//(see writables.Block)
 	BlockId uint64
+	GenerationStamp uint64
+
+	//length of the whole block (0 if the cache has not
+	//seen a getBlockLocations for its file)
+	Length uint64
+
+	//number of bytes of the block in the cache (less than 
+	//Length if only part of the block was read)
+	BytesCached uint64
+
+	//address of the DataNode the block was read from
+	DataNode string
+
+	//file the block belongs to ("" if not known)
+	Path string
 }
 
 func NewBlockDescription() *BlockDescription {
@@ -108,16 +142,85 @@ func NewBlockDescription() *BlockDescription {
 	return &b
 }
 
+//describes the block held by pair; blockIndex (which may
+//be nil) is used to fill in the length and path
+func CreateBlockDescription(pair *writables.ReadPair,
+	blockIndex *caches.BlockLocationIndex) *BlockDescription {
+	b := BlockDescription{BlockId: pair.Request.BlockId,
+		GenerationStamp: pair.Request.Timestamp,
+		BytesCached: uint64(pair.DataSize()),
+		DataNode: pair.DataNode}
+
+	if blockIndex != nil {
+		block := blockIndex.LookupBlock(b.BlockId)
+		if block != nil {
+			b.Length = block.B.NumBytes
+		}
+		b.Path = blockIndex.LookupPath(b.BlockId)
+	}
+
+	return &b
+}
+
 func (b *BlockDescription) Read(reader writables.Reader) error {
 	var err error
 	b.BlockId, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.GenerationStamp, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.Length, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.BytesCached, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.DataNode, err = readString(reader)
+	if err != nil {
+		return err
+	}
+
+	b.Path, err = readString(reader)
 	return err
 }
 
 func (b *BlockDescription) Write(writer writables.Writer) error {
 	var err error
 	err = writables.WriteLongInt(b.BlockId, writer)
-	return err
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteLongInt(b.GenerationStamp, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteLongInt(b.Length, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteLongInt(b.BytesCached, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writeString(b.DataNode, writer)
+	if err != nil {
+		return err
+	}
+
+	return writeString(b.Path, writer)
 }
 
 /**
@@ -135,16 +238,21 @@ func NewCachedBlocks() *CachedBlocks {
 }
 
 //creates the CachedBlocks structure given an instance of 
-//caches.WritableDataCache.
-func CreateCachedBlocks(dataCache *caches.WritableDataCache) *CachedBlocks {
+//caches.WritableDataCache. blockIndex (which may be nil) is
+//used to work out block lengths and paths.
+func CreateCachedBlocks(dataCache *caches.WritableDataCache,
+	blockIndex *caches.BlockLocationIndex) *CachedBlocks {
 	c := NewCachedBlocks()
-	c.NumBlocks = uint32(dataCache.CurrSize())
+
+	//blocks that are still being filled (or whose fill
+	//failed) are not in the cache as far as the 
+	//scheduler is concerned
+	pairs := dataCache.CompletePairs()
+	c.NumBlocks = uint32(len(pairs))
 	c.Blocks = make([]*BlockDescription, c.NumBlocks)
 
 	for i := 0; i<int(c.NumBlocks); i++ {
-		blockId := dataCache.RpcStore[i].Request.BlockId
-		c.Blocks[i] = NewBlockDescription()
-		c.Blocks[i].BlockId = blockId
+		c.Blocks[i] = CreateBlockDescription(pairs[i], blockIndex)
 	}
 
 	return c
@@ -205,12 +313,826 @@ func NewCacheInfo(descr *CacheDescription, blocks *CachedBlocks) *CacheInfo {
 }
 
 /**
-** Request types
+* BlockIdList
+* Follows the Request for REQ_PREFETCH_BLOCKS, 
+* REQ_PIN_BLOCKS and REQ_UNPIN_BLOCKS
 */
 
+type BlockIdList struct {
+	NumBlocks uint32
+	BlockIds []uint64
+}
+
+func NewBlockIdList(blockIds []uint64) *BlockIdList {
+	b := BlockIdList{NumBlocks: uint32(len(blockIds)), 
+		BlockIds: blockIds}
+	return &b
+}
+
+func (b *BlockIdList) Read(reader writables.Reader) error {
+	var err error
+	b.NumBlocks, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.BlockIds = make([]uint64, b.NumBlocks)
+	for i := 0; i < int(b.NumBlocks); i++ {
+		b.BlockIds[i], err = writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *BlockIdList) Write(writer writables.Writer) error {
+	err := writables.WriteInt(b.NumBlocks, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(b.NumBlocks); i++ {
+		err = writables.WriteLongInt(b.BlockIds[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/* BlockStatus.Status */
 const (
-	REQ_CACHE_DESCRIPTION = uint16(iota)
-	REQ_CACHED_BLOCKS
+	//done (or, for a prefetch, started)
+	BLOCK_STATUS_OK = uint16(iota)
+
+	//the cache has not seen the block in any
+	//getBlockLocations response
+	BLOCK_STATUS_UNKNOWN
+
+	//none of the block's DataNodes is relayed
+	//through this cache
+	BLOCK_STATUS_NO_REPLICA
+
+	//pinning the block would go over the cache's
+	//pinned-bytes limit
+	BLOCK_STATUS_PIN_LIMIT
+
+	//(unpin) the block was not pinned
+	BLOCK_STATUS_NOT_PINNED
+
+	//the data cache is turned off
+	BLOCK_STATUS_DISABLED
+)
+
+//what users call the BlockStatus.Status values
+var BLOCK_STATUS_NAMES = map[uint16]string{
+	BLOCK_STATUS_OK: "ok",
+	BLOCK_STATUS_UNKNOWN: "unknown",
+	BLOCK_STATUS_NO_REPLICA: "no_replica",
+	BLOCK_STATUS_PIN_LIMIT: "pin_limit",
+	BLOCK_STATUS_NOT_PINNED: "not_pinned",
+	BLOCK_STATUS_DISABLED: "disabled",
+}
+
+/**
+* BlockStatus
+* What happened to one of the blocks of a BlockIdList
+*/
+
+type BlockStatus struct {
+	BlockId uint64
+	Status uint16
+}
+
+func NewBlockStatus(blockId uint64, status uint16) *BlockStatus {
+	b := BlockStatus{BlockId: blockId, Status: status}
+	return &b
+}
+
+func (b *BlockStatus) Read(reader writables.Reader) error {
+	var err error
+	b.BlockId, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.Status, err = writables.ReadShortInt(reader)
+	return err
+}
+
+func (b *BlockStatus) Write(writer writables.Writer) error {
+	err := writables.WriteLongInt(b.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	return writables.WriteShortInt(b.Status, writer)
+}
+
+/**
+* BlockStatuses
+* Response to REQ_PREFETCH_BLOCKS, REQ_PIN_BLOCKS and
+* REQ_UNPIN_BLOCKS; one BlockStatus per requested block,
+* in the order they were asked for
+*/
+
+type BlockStatuses struct {
+	NumBlocks uint32
+	Statuses []*BlockStatus
+}
+
+func NewBlockStatuses() *BlockStatuses {
+	b := BlockStatuses{}
+	b.Statuses = make([]*BlockStatus, 0)
+	return &b
+}
+
+func (b *BlockStatuses) Add(blockId uint64, status uint16) {
+	b.Statuses = append(b.Statuses, NewBlockStatus(blockId, status))
+	b.NumBlocks = uint32(len(b.Statuses))
+}
+
+func (b *BlockStatuses) Read(reader writables.Reader) error {
+	var err error
+	b.NumBlocks, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.Statuses = make([]*BlockStatus, b.NumBlocks)
+	for i := 0; i < int(b.NumBlocks); i++ {
+		b.Statuses[i] = new(BlockStatus)
+		err = b.Statuses[i].Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *BlockStatuses) Write(writer writables.Writer) error {
+	err := writables.WriteInt(b.NumBlocks, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(b.NumBlocks); i++ {
+		err = b.Statuses[i].Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/* CacheEvent.Type */
+const (
+	EVENT_INSERT = caches.CACHE_EVENT_INSERT
+	EVENT_EVICT = caches.CACHE_EVENT_EVICT
+	EVENT_PIN = caches.CACHE_EVENT_PIN
+	EVENT_UNPIN = caches.CACHE_EVENT_UNPIN
+)
+
+/**
+* CacheEvent
+* Something that happened to a block in the cache. Sent 
+* one after another once a client has sent REQ_SUBSCRIBE.
+*/
+
+type CacheEvent struct {
+	//increases by one with every event
+	Seq uint64
+
+	Type uint16
+	BlockId uint64
+}
+
+func NewCacheEvent() *CacheEvent {
+	c := CacheEvent{}
+	return &c
+}
+
+func CreateCacheEvent(event caches.CacheEvent) *CacheEvent {
+	c := CacheEvent{Seq: event.Seq, 
+		Type: event.Type,
+		BlockId: event.BlockId}
+	return &c
+}
+
+func (c *CacheEvent) Read(reader writables.Reader) error {
+	var err error
+	c.Seq, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.Type, err = writables.ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.BlockId, err = writables.ReadLongInt(reader)
+	return err
+}
+
+func (c *CacheEvent) Write(writer writables.Writer) error {
+	err := writables.WriteLongInt(c.Seq, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteShortInt(c.Type, writer)
+	if err != nil {
+		return err
+	}
+
+	return writables.WriteLongInt(c.BlockId, writer)
+}
+
+/**
+* Subscription
+* First thing sent back after REQ_SUBSCRIBE; the events
+* that follow it are the ones after LastSeq
+*/
+
+type Subscription struct {
+	LastSeq uint64
+}
+
+func (s *Subscription) Read(reader writables.Reader) error {
+	var err error
+	s.LastSeq, err = writables.ReadLongInt(reader)
+	return err
+}
+
+func (s *Subscription) Write(writer writables.Writer) error {
+	return writables.WriteLongInt(s.LastSeq, writer)
+}
+
+/**
+* SinceRequest
+* Follows the Request for REQ_CACHED_BLOCKS_SINCE
+*/
+
+type SinceRequest struct {
+	//the last event the client has seen
+	Seq uint64
+}
+
+func (s *SinceRequest) Read(reader writables.Reader) error {
+	var err error
+	s.Seq, err = writables.ReadLongInt(reader)
+	return err
+}
+
+func (s *SinceRequest) Write(writer writables.Writer) error {
+	return writables.WriteLongInt(s.Seq, writer)
+}
+
+/**
+* CacheEvents
+* Response to REQ_CACHED_BLOCKS_SINCE
+*/
+
+type CacheEvents struct {
+	//false if the cache no longer remembers all of the events
+	//asked for (then there are none here and the client has
+	//to start over with REQ_CACHED_BLOCKS)
+	Complete bool
+
+	//sequence number of the last event in the cache
+	LastSeq uint64
+
+	NumEvents uint32
+	Events []*CacheEvent
+}
+
+func NewCacheEvents() *CacheEvents {
+	c := CacheEvents{}
+	c.Events = make([]*CacheEvent, 0)
+	return &c
+}
+
+//creates the CacheEvents that answer a client that has seen
+//everything up to seq
+func CreateCacheEvents(dataCache *caches.WritableDataCache, 
+	seq uint64) *CacheEvents {
+	c := NewCacheEvents()
+	c.LastSeq = dataCache.Events.LastSeq()
+
+	var events []caches.CacheEvent
+	events, c.Complete = dataCache.Events.Since(seq)
+	for i := 0; i < len(events); i++ {
+		c.Events = append(c.Events, CreateCacheEvent(events[i]))
+	}
+	c.NumEvents = uint32(len(c.Events))
+
+	//an event may have come in between the two calls
+	if len(events) > 0 && events[len(events) - 1].Seq > c.LastSeq {
+		c.LastSeq = events[len(events) - 1].Seq
+	}
+
+	return c
+}
+
+func (c *CacheEvents) Read(reader writables.Reader) error {
+	var err error
+	c.Complete, err = writables.ReadBoolean(reader)
+	if err != nil {
+		return err
+	}
+
+	c.LastSeq, err = writables.ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.NumEvents, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.Events = make([]*CacheEvent, c.NumEvents)
+	for i := 0; i < int(c.NumEvents); i++ {
+		c.Events[i] = NewCacheEvent()
+		err = c.Events[i].Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CacheEvents) Write(writer writables.Writer) error {
+	err := writables.WriteBoolean(c.Complete, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteLongInt(c.LastSeq, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteInt(c.NumEvents, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(c.NumEvents); i++ {
+		err = c.Events[i].Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* Latencies
+* Percentiles of read latencies, in nanoseconds
+*/
+
+type Latencies struct {
+	P50 uint64
+	P90 uint64
+	P99 uint64
+	Max uint64
+}
+
+func CreateLatencies(percentiles caches.LatencyPercentiles) Latencies {
+	return Latencies{P50: uint64(percentiles.P50),
+		P90: uint64(percentiles.P90),
+		P99: uint64(percentiles.P99),
+		Max: uint64(percentiles.Max)}
+}
+
+func (l *Latencies) Read(reader writables.Reader) error {
+	values := []*uint64{&l.P50, &l.P90, &l.P99, &l.Max}
+	for i := 0; i < len(values); i++ {
+		value, err := writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+		*values[i] = value
+	}
+
+	return nil
+}
+
+func (l *Latencies) Write(writer writables.Writer) error {
+	values := []uint64{l.P50, l.P90, l.P99, l.Max}
+	for i := 0; i < len(values); i++ {
+		err := writables.WriteLongInt(values[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* BlockAccess
+*/
+
+type BlockAccess struct {
+	BlockId uint64
+
+	//number of times the block was read through the cache
+	Count uint64
+}
+
+/**
+* CacheStats
+* Answers REQ_CACHE_STATS
+*/
+
+type CacheStats struct {
+	//reads served out of the cache and reads that 
+	//had to go to a DataNode
+	Hits uint64
+	Misses uint64
+
+	//bytes of block data sent to clients on hits 
+	//and on misses
+	BytesFromCache uint64
+	BytesFromDataNode uint64
+
+	Evictions uint64
+
+	HitLatency Latencies
+	MissLatency Latencies
+
+	//most read block first
+	NumBlocks uint32
+	Accesses []BlockAccess
+}
+
+func NewCacheStats() *CacheStats {
+	c := CacheStats{}
+	c.Accesses = make([]BlockAccess, 0)
+	return &c
+}
+
+func CreateCacheStats(cache *caches.WritableDataCache) *CacheStats {
+	stats := cache.Stats.Snapshot()
+
+	c := CacheStats{Hits: uint64(stats.Hits),
+		Misses: uint64(stats.Misses),
+		BytesFromCache: uint64(stats.BytesFromCache),
+		BytesFromDataNode: uint64(stats.BytesFromDataNode),
+		Evictions: uint64(stats.Evictions),
+		HitLatency: CreateLatencies(stats.HitLatency),
+		MissLatency: CreateLatencies(stats.MissLatency)}
+
+	c.Accesses = make([]BlockAccess, 0, len(stats.Accesses))
+	for blockId, count := range stats.Accesses {
+		c.Accesses = append(c.Accesses, 
+			BlockAccess{BlockId: blockId, Count: uint64(count)})
+	}
+	c.NumBlocks = uint32(len(c.Accesses))
+
+	sort.Slice(c.Accesses, func(i, j int) bool {
+		if c.Accesses[i].Count != c.Accesses[j].Count {
+			return c.Accesses[i].Count > c.Accesses[j].Count
+		}
+		return c.Accesses[i].BlockId < c.Accesses[j].BlockId
+	})
+
+	return &c
+}
+
+func (c *CacheStats) Read(reader writables.Reader) error {
+	counters := []*uint64{&c.Hits, &c.Misses, &c.BytesFromCache, 
+		&c.BytesFromDataNode, &c.Evictions}
+	for i := 0; i < len(counters); i++ {
+		value, err := writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+		*counters[i] = value
+	}
+
+	err := c.HitLatency.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	err = c.MissLatency.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	c.NumBlocks, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.Accesses = make([]BlockAccess, c.NumBlocks)
+	for i := 0; i < int(c.NumBlocks); i++ {
+		c.Accesses[i].BlockId, err = writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+
+		c.Accesses[i].Count, err = writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CacheStats) Write(writer writables.Writer) error {
+	counters := []uint64{c.Hits, c.Misses, c.BytesFromCache, 
+		c.BytesFromDataNode, c.Evictions}
+	for i := 0; i < len(counters); i++ {
+		err := writables.WriteLongInt(counters[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.HitLatency.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	err = c.MissLatency.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteInt(c.NumBlocks, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(c.NumBlocks); i++ {
+		err = writables.WriteLongInt(c.Accesses[i].BlockId, writer)
+		if err != nil {
+			return err
+		}
+
+		err = writables.WriteLongInt(c.Accesses[i].Count, writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* PathList
+* Follows the Request for REQ_RESOLVE_PATHS. Each path may
+* be a glob (see caches.BlockLocationIndex.Glob()).
+*/
+
+type PathList struct {
+	NumPaths uint32
+	Paths []string
+}
+
+func NewPathList(paths []string) *PathList {
+	p := PathList{NumPaths: uint32(len(paths)), Paths: paths}
+	return &p
+}
+
+func (p *PathList) Read(reader writables.Reader) error {
+	var err error
+	p.NumPaths, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	p.Paths = make([]string, p.NumPaths)
+	for i := 0; i < int(p.NumPaths); i++ {
+		p.Paths[i], err = readString(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PathList) Write(writer writables.Writer) error {
+	err := writables.WriteInt(p.NumPaths, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(p.NumPaths); i++ {
+		err = writeString(p.Paths[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* FileBlocks
+* The blocks of one file, in order
+*/
+
+type FileBlocks struct {
+	Path string
+	NumBlocks uint32
+	BlockIds []uint64
+}
+
+func (f *FileBlocks) Read(reader writables.Reader) error {
+	var err error
+	f.Path, err = readString(reader)
+	if err != nil {
+		return err
+	}
+
+	f.NumBlocks, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	f.BlockIds = make([]uint64, f.NumBlocks)
+	for i := 0; i < int(f.NumBlocks); i++ {
+		f.BlockIds[i], err = writables.ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *FileBlocks) Write(writer writables.Writer) error {
+	err := writeString(f.Path, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writables.WriteInt(f.NumBlocks, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(f.NumBlocks); i++ {
+		err = writables.WriteLongInt(f.BlockIds[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* ResolvedPaths
+* Answers REQ_RESOLVE_PATHS with every file matched by one of 
+* the paths (once, however many paths it matched). Files the 
+* cache has not seen a getBlockLocations for are not included.
+*/
+
+type ResolvedPaths struct {
+	NumFiles uint32
+	Files []*FileBlocks
+}
+
+func NewResolvedPaths() *ResolvedPaths {
+	r := ResolvedPaths{}
+	r.Files = make([]*FileBlocks, 0)
+	return &r
+}
+
+//resolves the paths (or globs) in paths against blockIndex
+func CreateResolvedPaths(blockIndex *caches.BlockLocationIndex,
+	paths []string) (*ResolvedPaths, error) {
+	r := NewResolvedPaths()
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(paths); i++ {
+		matches, err := blockIndex.Glob(paths[i])
+		if err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < len(matches); j++ {
+			if seen[matches[j]] {
+				continue
+			}
+			seen[matches[j]] = true
+
+			blockIds := blockIndex.BlockIds(matches[j])
+			if blockIds == nil {
+				continue
+			}
+
+			r.Files = append(r.Files, &FileBlocks{Path: matches[j],
+				NumBlocks: uint32(len(blockIds)),
+				BlockIds: blockIds})
+		}
+	}
+	r.NumFiles = uint32(len(r.Files))
+
+	return r, nil
+}
+
+//the ids of the blocks of every file, in order
+func (r *ResolvedPaths) BlockIds() []uint64 {
+	res := make([]uint64, 0)
+	for i := 0; i < len(r.Files); i++ {
+		res = append(res, r.Files[i].BlockIds...)
+	}
+
+	return res
+}
+
+func (r *ResolvedPaths) Read(reader writables.Reader) error {
+	var err error
+	r.NumFiles, err = writables.ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	r.Files = make([]*FileBlocks, r.NumFiles)
+	for i := 0; i < int(r.NumFiles); i++ {
+		r.Files[i] = new(FileBlocks)
+		err = r.Files[i].Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ResolvedPaths) Write(writer writables.Writer) error {
+	err := writables.WriteInt(r.NumFiles, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(r.NumFiles); i++ {
+		err = r.Files[i].Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+** Request types
+*/
+
+const (
+	REQ_CACHE_DESCRIPTION = uint16(iota)
+	REQ_CACHED_BLOCKS
+
+	//the following three are followed by a BlockIdList
+	//and answered with BlockStatuses
+
+	//read the blocks into the cache
+	REQ_PREFETCH_BLOCKS
+
+	//read the blocks into the cache and keep 
+	//them there until they are unpinned
+	REQ_PIN_BLOCKS
+	REQ_UNPIN_BLOCKS
+
+	//answered with a Subscription followed by a CacheEvent
+	//for everything that happens to the cache from then on;
+	//the connection is not used for anything else after this
+	REQ_SUBSCRIBE
+
+	//followed by a SinceRequest, answered with CacheEvents
+	REQ_CACHED_BLOCKS_SINCE
+
+	//answered with CacheStats
+	REQ_CACHE_STATS
+
+	//followed by a PathList, answered with ResolvedPaths
+	REQ_RESOLVE_PATHS
+
+	//answered with an AuthChallenge; the client sends back
+	//the AuthResponse for it under the admin secret and is
+	//answered with an empty response (or ERR_UNAUTHORIZED)
+	REQ_ADMIN_LOGIN
+
+	//followed by an AdminCommand, answered with an AdminResult
+	REQ_ADMIN
 )
 
 /** 
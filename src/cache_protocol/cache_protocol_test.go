@@ -4,6 +4,7 @@ import (
 	//go imports
 	"testing"
 	"bytes"
+	"fmt"
 	"reflect"
 
 	//local imports
@@ -83,12 +84,25 @@ func TestNewBlockDescription(t *testing.T) {
 
 func TestBlockDescriptionRead(t *testing.T) {
 	descr := NewBlockDescription()
-	buf := []byte{0, 0, 0, 0, 0, 0, 0, 16}
+	buf := []byte{0, 0, 0, 0, 0, 0, 0, 16,
+		0, 0, 0, 0, 0, 0, 3, 232,
+		0, 0, 0, 0, 0, 0, 0, 10,
+		0, 0, 0, 0, 0, 0, 0, 5,
+		3, 'd', 'n', '1',
+		2, '/', 'f'}
 	byteBuf := bytes.NewBuffer(buf)
 
-	descr.Read(byteBuf)
+	err := descr.Read(byteBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if descr.BlockId != 16 {
+	if descr.BlockId != 16 || descr.GenerationStamp != 1000 ||
+		descr.Length != 10 || descr.BytesCached != 5 {
+		t.Fail()
+	}
+
+	if descr.DataNode != "dn1" || descr.Path != "/f" {
 		t.Fail()
 	}
 }
@@ -96,7 +110,13 @@ func TestBlockDescriptionRead(t *testing.T) {
 func TestBlockDescriptionWrite(t *testing.T) {
 	descr := NewBlockDescription()
 	descr.BlockId = 14
-	buf := []byte{0, 0, 0, 0, 0, 0, 0, 14}
+	descr.GenerationStamp = 1
+	descr.BytesCached = 2
+	buf := []byte{0, 0, 0, 0, 0, 0, 0, 14,
+		0, 0, 0, 0, 0, 0, 0, 1,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 2,
+		0, 0}
 	byteBuf := new(bytes.Buffer)
 
 	descr.Write(byteBuf)
@@ -106,6 +126,41 @@ func TestBlockDescriptionWrite(t *testing.T) {
 	}
 }
 
+func TestCreateBlockDescription(t *testing.T) {
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 7
+	request.Timestamp = 1001
+
+	header := writables.NewBlockResponseHeader()
+	packet := writables.NewBlockPacket(header)
+	packet.Data = []byte{1, 2, 3}
+
+	pair := writables.NewReadPair(request)
+	pair.DataNode = "dn:50010"
+	pair.AddBlockPacket(packet)
+
+	descr := CreateBlockDescription(pair, nil)
+	if descr.BlockId != 7 || descr.GenerationStamp != 1001 ||
+		descr.BytesCached != 3 || descr.DataNode != "dn:50010" ||
+		descr.Length != 0 || descr.Path != "" {
+		t.Fail()
+	}
+
+	block := writables.NewLocatedBlock()
+	block.B.BlockId = 7
+	block.B.NumBytes = 100
+	locatedBlocks := writables.NewLocatedBlocks()
+	locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr, block)
+
+	blockIndex := caches.NewBlockLocationIndex()
+	blockIndex.Add("/file", locatedBlocks)
+
+	descr = CreateBlockDescription(pair, blockIndex)
+	if descr.Length != 100 || descr.Path != "/file" {
+		t.Fail()
+	}
+}
+
 /*
 * CachedBlocks
 */
@@ -126,13 +181,22 @@ func TestCachedBlocksCreate(t *testing.T) {
 	req2 := writables.NewReadBlockHeader()
 	req2.BlockId = 2
 
+	req3 := writables.NewReadBlockHeader()
+	req3.BlockId = 3
+
 	pair := writables.NewReadPair(req)
 	pair2 := writables.NewReadPair(req2)
+	pair.Complete()
+	pair2.Complete()
+
+	//still filling, so it should not be reported
+	pair3 := writables.NewReadPair(req3)
 
 	dataCache.AddReadPair(pair)
 	dataCache.AddReadPair(pair2)
+	dataCache.AddReadPair(pair3)
 
-	cachedBlocks := CreateCachedBlocks(dataCache)
+	cachedBlocks := CreateCachedBlocks(dataCache, nil)
 	if cachedBlocks.NumBlocks != 2 {
 		t.Fail()
 	}
@@ -229,4 +293,193 @@ func TestRequestWrite(t *testing.T) {
 	if !reflect.DeepEqual(resBuf.Bytes(), expectedBuf) {
 		t.Fail()
 	}
-}
\ No newline at end of file
+}
+/*
+* BlockIdList and BlockStatuses
+*/
+
+func TestBlockIdListReadWrite(t *testing.T) {
+	b := NewBlockIdList([]uint64{1, 2, 1 << 40})
+
+	buf := new(bytes.Buffer)
+	err := b.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(BlockIdList)
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(b, res) {
+		t.Fail()
+	}
+}
+
+func TestBlockStatusesReadWrite(t *testing.T) {
+	b := NewBlockStatuses()
+	b.Add(1, BLOCK_STATUS_OK)
+	b.Add(2, BLOCK_STATUS_PIN_LIMIT)
+
+	if b.NumBlocks != 2 {
+		t.Fail()
+	}
+
+	buf := new(bytes.Buffer)
+	err := b.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewBlockStatuses()
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(b, res) {
+		t.Fail()
+	}
+}
+
+/*
+* CacheEvent and CacheEvents
+*/
+
+func TestCreateCacheEvents(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	d.PinnedBytesLimit = 100
+	d.Pin(3, 10)
+	d.Unpin(3)
+
+	c := CreateCacheEvents(d, 1)
+	if !c.Complete || c.LastSeq != 2 || c.NumEvents != 1 {
+		t.FailNow()
+	}
+
+	if c.Events[0].Seq != 2 || c.Events[0].Type != EVENT_UNPIN ||
+		c.Events[0].BlockId != 3 {
+		t.Fail()
+	}
+}
+
+func TestCacheEventsReadWrite(t *testing.T) {
+	c := NewCacheEvents()
+	c.Complete = true
+	c.LastSeq = 12
+	c.Events = append(c.Events, &CacheEvent{Seq: 11, Type: EVENT_INSERT, 
+		BlockId: 1 << 40})
+	c.Events = append(c.Events, &CacheEvent{Seq: 12, Type: EVENT_EVICT, 
+		BlockId: 5})
+	c.NumEvents = 2
+
+	buf := new(bytes.Buffer)
+	err := c.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewCacheEvents()
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(c, res) {
+		t.Fail()
+	}
+}
+
+/*
+* CacheStats
+*/
+
+func TestCreateCacheStats(t *testing.T) {
+	d := caches.NewWritableDataCache(15)
+	d.Stats.RecordHit(1, 10, 5)
+	d.Stats.RecordMiss(2, 20, 7)
+	d.Stats.RecordHit(2, 20, 3)
+
+	c := CreateCacheStats(d)
+	if c.Hits != 2 || c.Misses != 1 || c.BytesFromCache != 30 ||
+		c.BytesFromDataNode != 20 || c.MissLatency.Max != 7 {
+		t.Fail()
+	}
+
+	//most read block first
+	if c.NumBlocks != 2 || c.Accesses[0].BlockId != 2 || 
+		c.Accesses[0].Count != 2 || c.Accesses[1].BlockId != 1 {
+		t.Fail()
+	}
+}
+
+func TestCacheStatsReadWrite(t *testing.T) {
+	c := NewCacheStats()
+	c.Hits = 3
+	c.Misses = 4
+	c.BytesFromCache = 1 << 40
+	c.Evictions = 1
+	c.HitLatency = Latencies{P50: 1, P90: 2, P99: 3, Max: 4}
+	c.MissLatency.Max = 9
+	c.Accesses = append(c.Accesses, BlockAccess{BlockId: 7, Count: 2})
+	c.NumBlocks = 1
+
+	buf := new(bytes.Buffer)
+	err := c.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewCacheStats()
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(c, res) {
+		t.Fail()
+	}
+}
+
+/*
+* PathList and ResolvedPaths
+*/
+
+func TestPathListReadWrite(t *testing.T) {
+	p := NewPathList([]string{"/a/*", "/b"})
+
+	buf := new(bytes.Buffer)
+	err := p.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(PathList)
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(p, res) {
+		t.Fail()
+	}
+}
+
+func TestCreateResolvedPaths(t *testing.T) {
+	blockIndex := caches.NewBlockLocationIndex()
+	for i := 0; i < 3; i++ {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = uint64(i)
+		locatedBlocks := writables.NewLocatedBlocks()
+		locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr, 
+			block)
+		blockIndex.Add(fmt.Sprintf("/data/part-%d", i), locatedBlocks)
+	}
+
+	//part-1 is matched twice but only listed once
+	r, err := CreateResolvedPaths(blockIndex, 
+		[]string{"/data/part-[01]", "/data/part-1", "/other/*"})
+	if err != nil || r.NumFiles != 2 || r.Files[0].Path != "/data/part-0" ||
+		!reflect.DeepEqual(r.BlockIds(), []uint64{0, 1}) {
+		t.FailNow()
+	}
+
+	buf := new(bytes.Buffer)
+	err = r.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewResolvedPaths()
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(r, res) {
+		t.Fail()
+	}
+
+	_, err = CreateResolvedPaths(blockIndex, []string{"["})
+	if err == nil {
+		t.Fail()
+	}
+}
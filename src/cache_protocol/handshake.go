@@ -0,0 +1,223 @@
+/*
+* The hello exchange that starts every connection between the
+* scheduler and a cache, and the header that comes before
+* every response (which is how errors are reported).
+*/
+
+package cache_protocol
+
+import (
+	//go imports
+	"fmt"
+
+	//local imports
+	"writables"
+)
+
+//version of the protocol this build speaks; it is bumped
+//whenever a message changes, and both sides have to agree on it
+//
+//2: BlockDescription carries the generation stamp, length, bytes
+//   cached, DataNode and path of the block
+//3: a cache with CAP_AUTH sends an AuthChallenge after its Hello
+var PROTOCOL_VERSION = uint16(3)
+
+/* Hello.Capabilities */
+const (
+	//REQ_PREFETCH_BLOCKS, REQ_PIN_BLOCKS and REQ_UNPIN_BLOCKS
+	//(only if the cache can look up block locations)
+	CAP_BLOCK_REQUESTS = uint64(1 << iota)
+
+	//REQ_SUBSCRIBE and REQ_CACHED_BLOCKS_SINCE
+	CAP_EVENTS
+
+	//REQ_CACHE_STATS
+	CAP_STATS
+
+	//REQ_RESOLVE_PATHS (only if the cache keeps track
+	//of which blocks make up which file)
+	CAP_RESOLVE_PATHS
+
+	//REQ_ADMIN_LOGIN and REQ_ADMIN (only if the cache
+	//has admin credentials set up)
+	CAP_ADMIN
+
+	//an AuthChallenge follows the Hello, and nothing the client
+	//sends is answered until it has passed it (see auth.go)
+	CAP_AUTH
+)
+
+/* ResponseHeader.Status */
+const (
+	//followed by the response itself
+	RESPONSE_OK = uint16(iota)
+
+	//followed by an ErrorResponse
+	RESPONSE_ERROR
+)
+
+/* ErrorResponse.Code */
+const (
+	//the two sides do not speak the same PROTOCOL_VERSION
+	ERR_VERSION_MISMATCH = uint16(iota + 1)
+
+	//the RequestType is not one the cache knows
+	ERR_UNKNOWN_REQUEST
+
+	//the request could not be read or makes no sense
+	ERR_BAD_REQUEST
+
+	//the cache does not offer what was asked for
+	//(see Hello.Capabilities)
+	ERR_NOT_SUPPORTED
+
+	//the client has not authenticated (or logged in as
+	//an admin), or its credentials are wrong
+	ERR_UNAUTHORIZED
+)
+
+/**
+* Hello
+* Sent by the client as soon as it connects and answered
+* with the cache's own Hello
+*/
+
+type Hello struct {
+	Version uint16
+	Capabilities uint64
+}
+
+func NewHello(capabilities uint64) *Hello {
+	h := Hello{Version: PROTOCOL_VERSION, Capabilities: capabilities}
+	return &h
+}
+
+func (h *Hello) HasCapability(capability uint64) bool {
+	return h.Capabilities & capability != 0
+}
+
+func (h *Hello) Read(reader writables.Reader) error {
+	var err error
+	h.Version, err = writables.ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	h.Capabilities, err = writables.ReadLongInt(reader)
+	return err
+}
+
+func (h *Hello) Write(writer writables.Writer) error {
+	err := writables.WriteShortInt(h.Version, writer)
+	if err != nil {
+		return err
+	}
+
+	return writables.WriteLongInt(h.Capabilities, writer)
+}
+
+/**
+* ResponseHeader
+*/
+
+type ResponseHeader struct {
+	Status uint16
+}
+
+func (r *ResponseHeader) Read(reader writables.Reader) error {
+	var err error
+	r.Status, err = writables.ReadShortInt(reader)
+	return err
+}
+
+func (r *ResponseHeader) Write(writer writables.Writer) error {
+	return writables.WriteShortInt(r.Status, writer)
+}
+
+/**
+* ErrorResponse
+* Also an error, so that clients can hand it back as is
+*/
+
+type ErrorResponse struct {
+	Code uint16
+	Message string
+}
+
+func NewErrorResponse(code uint16, message string) *ErrorResponse {
+	e := ErrorResponse{Code: code, Message: message}
+	return &e
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("Cache error %d: %s", e.Code, e.Message)
+}
+
+func (e *ErrorResponse) Read(reader writables.Reader) error {
+	var err error
+	e.Code, err = writables.ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	e.Message, err = readString(reader)
+	return err
+}
+
+func (e *ErrorResponse) Write(writer writables.Writer) error {
+	err := writables.WriteShortInt(e.Code, writer)
+	if err != nil {
+		return err
+	}
+
+	return writeString(e.Message, writer)
+}
+
+//writes a RESPONSE_OK header followed by body (if there is one)
+func WriteResponse(writer writables.Writer, body writables.Writable) error {
+	header := ResponseHeader{Status: RESPONSE_OK}
+	err := header.Write(writer)
+	if err != nil || body == nil {
+		return err
+	}
+
+	return body.Write(writer)
+}
+
+//writes a RESPONSE_ERROR header followed by an ErrorResponse
+func WriteError(writer writables.Writer, code uint16, message string) error {
+	header := ResponseHeader{Status: RESPONSE_ERROR}
+	err := header.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	return NewErrorResponse(code, message).Write(writer)
+}
+
+//reads a response into body. If the other side sent an error
+//instead, the *ErrorResponse is returned.
+func ReadResponse(reader writables.Reader, body writables.Writable) error {
+	header := new(ResponseHeader)
+	err := header.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	switch header.Status {
+	case RESPONSE_OK:
+		if body == nil {
+			return nil
+		}
+		return body.Read(reader)
+	case RESPONSE_ERROR:
+		res := new(ErrorResponse)
+		err = res.Read(reader)
+		if err != nil {
+			return err
+		}
+		return res
+	}
+
+	return fmt.Errorf("Unknown response status %d.", header.Status)
+}
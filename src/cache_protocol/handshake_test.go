@@ -0,0 +1,113 @@
+package cache_protocol
+
+import (
+	//go imports
+	"bytes"
+	"reflect"
+	"testing"
+
+	//local imports
+)
+
+func TestHelloReadWrite(t *testing.T) {
+	h := NewHello(CAP_EVENTS | CAP_STATS)
+	if h.Version != PROTOCOL_VERSION || !h.HasCapability(CAP_STATS) ||
+		h.HasCapability(CAP_BLOCK_REQUESTS) {
+		t.Fail()
+	}
+
+	buf := new(bytes.Buffer)
+	err := h.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(Hello)
+	err = res.Read(buf)
+	if err != nil || !reflect.DeepEqual(h, res) {
+		t.Fail()
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteResponse(buf, &SinceRequest{Seq: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := new(SinceRequest)
+	err = ReadResponse(buf, res)
+	if err != nil || res.Seq != 5 {
+		t.Fail()
+	}
+
+	err = WriteError(buf, ERR_BAD_REQUEST, "No good.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ReadResponse(buf, res)
+	errorResponse, ok := err.(*ErrorResponse)
+	if !ok || errorResponse.Code != ERR_BAD_REQUEST || 
+		errorResponse.Message != "No good." {
+		t.Fail()
+	}
+
+	//an unknown status
+	buf.Write([]byte{0, 7})
+	err = ReadResponse(buf, res)
+	if err == nil {
+		t.Fail()
+	}
+	_, ok = err.(*ErrorResponse)
+	if ok {
+		t.Fail()
+	}
+}
+
+func TestAuthMac(t *testing.T) {
+	challenge, err := NewAuthChallenge()
+	if err != nil || len(challenge.Nonce) != AUTH_NONCE_SIZE {
+		t.Fatal(err)
+	}
+
+	res := NewAuthResponse([]byte("key"), challenge)
+	if len(res.Mac) != AUTH_MAC_SIZE {
+		t.Fail()
+	}
+
+	if !CheckAuthMac([]byte("key"), challenge.Nonce, res.Mac) {
+		t.Fail()
+	}
+
+	if CheckAuthMac([]byte("other"), challenge.Nonce, res.Mac) {
+		t.Fail()
+	}
+
+	other, _ := NewAuthChallenge()
+	if CheckAuthMac([]byte("key"), other.Nonce, res.Mac) {
+		t.Fail()
+	}
+}
+
+func TestAuthReadWrite(t *testing.T) {
+	challenge, _ := NewAuthChallenge()
+	res := NewAuthResponse([]byte("key"), challenge)
+
+	buf := new(bytes.Buffer)
+	challenge.Write(buf)
+	res.Write(buf)
+
+	challengeRead := new(AuthChallenge)
+	resRead := new(AuthResponse)
+	err := challengeRead.Read(buf)
+	if err != nil || !bytes.Equal(challengeRead.Nonce, challenge.Nonce) {
+		t.Fail()
+	}
+
+	err = resRead.Read(buf)
+	if err != nil || !bytes.Equal(resRead.Mac, res.Mac) || buf.Len() != 0 {
+		t.Fail()
+	}
+}
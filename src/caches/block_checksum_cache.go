@@ -0,0 +1,142 @@
+/**
+* Caches OP_BLOCK_CHECKSUM responses. These are what
+* getFileChecksum (and so distcp) asks every DataNode for, and
+* computing one means the DataNode reading a whole .meta file.
+* A block's contents never change without its generation stamp
+* changing, so (block id, generation stamp) is a safe key.
+*/
+package caches
+
+import (
+	//go packages
+	"sync"
+
+	//local packages
+	"writables"
+)
+
+type BlockChecksumKey struct {
+	BlockId uint64
+	GenerationStamp uint64
+}
+
+type BlockChecksumCache struct {
+	sync.RWMutex
+
+	CacheSize int
+	Store map[BlockChecksumKey]*writables.BlockChecksumResponse
+
+	//keys in the order they were added; used for eviction
+	order []BlockChecksumKey
+
+	Hits int
+	Misses int
+
+	//turned off along with the data cache; while it is off,
+	//nothing is answered from or added to the cache
+	Enabled bool
+}
+
+func NewBlockChecksumCache(cacheSize int) *BlockChecksumCache {
+	b := BlockChecksumCache{CacheSize: cacheSize, Enabled: true}
+	b.Store = make(map[BlockChecksumKey]*writables.BlockChecksumResponse)
+	b.order = make([]BlockChecksumKey, 0)
+	return &b
+}
+
+func (b *BlockChecksumCache) Query(
+	request *writables.BlockChecksumHeader) *writables.BlockChecksumResponse {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.Enabled {
+		return nil
+	}
+
+	key := BlockChecksumKey{request.BlockId, request.GenerationStamp}
+	res, ok := b.Store[key]
+	if !ok {
+		b.Misses += 1
+		return nil
+	}
+
+	b.Hits += 1
+	return res
+}
+
+//only successful responses are cached; an error may well
+//be temporary (e.g. the DataNode was too busy)
+func (b *BlockChecksumCache) Add(request *writables.BlockChecksumHeader,
+	response *writables.BlockChecksumResponse) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.Enabled || b.CacheSize <= 0 || 
+		response.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		return
+	}
+
+	key := BlockChecksumKey{request.BlockId, request.GenerationStamp}
+	_, ok := b.Store[key]
+	if ok {
+		return
+	}
+
+	if len(b.order) >= b.CacheSize {
+		delete(b.Store, b.order[0])
+		b.order = b.order[1:]
+	}
+
+	b.Store[key] = response
+	b.order = append(b.order, key)
+}
+
+func (b *BlockChecksumCache) IsEnabled() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.Enabled
+}
+
+func (b *BlockChecksumCache) SetEnabled(enabled bool) {
+	b.Lock()
+	defer b.Unlock()
+	b.Enabled = enabled
+}
+
+func (b *BlockChecksumCache) CurrSize() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.Store)
+}
+
+//drops the responses for blockId (whatever their generation
+//stamp); returns how many were dropped
+func (b *BlockChecksumCache) EvictBlock(blockId uint64) int {
+	b.Lock()
+	defer b.Unlock()
+
+	count := 0
+	order := make([]BlockChecksumKey, 0, len(b.order))
+	for _, key := range b.order {
+		if key.BlockId == blockId {
+			delete(b.Store, key)
+			count++
+			continue
+		}
+		order = append(order, key)
+	}
+
+	b.order = order
+	return count
+}
+
+//drops every response; returns how many there were
+func (b *BlockChecksumCache) Clear() int {
+	b.Lock()
+	defer b.Unlock()
+
+	count := len(b.Store)
+	b.Store = make(map[BlockChecksumKey]*writables.BlockChecksumResponse)
+	b.order = make([]BlockChecksumKey, 0)
+	return count
+}
@@ -0,0 +1,93 @@
+package caches
+
+import (
+	"testing"
+	"writables"
+)
+
+func makeChecksumRequest(blockId uint64, genStamp uint64) *writables.BlockChecksumHeader {
+	req := writables.NewBlockChecksumHeader()
+	req.BlockId = blockId
+	req.GenerationStamp = genStamp
+	return req
+}
+
+func makeChecksumResponse(status int8) *writables.BlockChecksumResponse {
+	res := writables.NewBlockChecksumResponse()
+	res.Status = uint16(status)
+	res.BytesPerCrc = 512
+	res.CrcPerBlock = 8
+	res.Md5 = make([]byte, writables.MD5_SIZE)
+	return res
+}
+
+func TestBCCQuery(t *testing.T) {
+	b := NewBlockChecksumCache(10)
+	res := makeChecksumResponse(writables.OP_STATUS_SUCCESS)
+	b.Add(makeChecksumRequest(1, 1001), res)
+
+	if b.Query(makeChecksumRequest(1, 1001)) != res {
+		t.Fail()
+	}
+
+	//a new generation stamp means the block changed
+	if b.Query(makeChecksumRequest(1, 1002)) != nil {
+		t.Fail()
+	}
+
+	if b.Hits != 1 || b.Misses != 1 {
+		t.Fail()
+	}
+}
+
+func TestBCCAddError(t *testing.T) {
+	b := NewBlockChecksumCache(10)
+	b.Add(makeChecksumRequest(1, 1001), 
+		makeChecksumResponse(writables.OP_STATUS_ERROR))
+
+	if b.CurrSize() != 0 {
+		t.Fail()
+	}
+}
+
+func TestBCCEviction(t *testing.T) {
+	b := NewBlockChecksumCache(2)
+	for i := uint64(1); i <= 3; i++ {
+		b.Add(makeChecksumRequest(i, 1001), 
+			makeChecksumResponse(writables.OP_STATUS_SUCCESS))
+	}
+
+	if b.CurrSize() != 2 {
+		t.Fail()
+	}
+
+	if b.Query(makeChecksumRequest(1, 1001)) != nil {
+		t.Fail()
+	}
+
+	if b.Query(makeChecksumRequest(3, 1001)) == nil {
+		t.Fail()
+	}
+}
+
+func TestBCCDisabled(t *testing.T) {
+	b := NewBlockChecksumCache(10)
+	b.Add(makeChecksumRequest(1, 1001),
+		makeChecksumResponse(writables.OP_STATUS_SUCCESS))
+
+	b.SetEnabled(false)
+	if b.IsEnabled() || b.Query(makeChecksumRequest(1, 1001)) != nil {
+		t.Fail()
+	}
+
+	b.Add(makeChecksumRequest(2, 1001),
+		makeChecksumResponse(writables.OP_STATUS_SUCCESS))
+	if b.CurrSize() != 1 {
+		t.Fail()
+	}
+
+	b.SetEnabled(true)
+	if b.Query(makeChecksumRequest(1, 1001)) == nil {
+		t.Fail()
+	}
+}
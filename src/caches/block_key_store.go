@@ -0,0 +1,127 @@
+package caches
+
+/*
+* Holds the block keys that the NameNode hands out to DataNodes
+* (ExportedBlockKeys, seen when a DataNode registers through the
+* proxy) so that block tokens can be checked without asking a
+* DataNode. Cached block data is shared between every client, so
+* before a client is given a block out of the cache, the token it
+* presented has to be one the DataNode would have accepted.
+*/
+
+import (
+	//go packages
+	"crypto/hmac"
+	"errors"
+	"sync"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+type BlockKeyStore struct {
+	//written by hdfs_requests.Processor instances, read by
+	//writable_processor.WritableProcessor instances
+	sync.RWMutex
+
+	//set once keys have been seen at all; until then, no
+	//token can be validated
+	Captured bool
+
+	//false if the cluster does not use block tokens (then
+	//every token is fine)
+	TokensEnabled bool
+
+	//key id -> key
+	Keys map[int64]*writables.BlockKey
+}
+
+func NewBlockKeyStore() *BlockKeyStore {
+	b := BlockKeyStore{}
+	b.Keys = make(map[int64]*writables.BlockKey)
+	return &b
+}
+
+//records the keys sent along with a DataNode registration. Keys
+//that we already know are kept (the NameNode rolls keys, and tokens
+//signed with the previous key are still good until they expire).
+func (b *BlockKeyStore) Update(keys *writables.ExportedBlockKeys) {
+	if keys == nil {
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.Captured = true
+	b.TokensEnabled = keys.IsBlockTokenEnabled
+
+	if keys.CurrentKey != nil && len(keys.CurrentKey.KeyBytes) > 0 {
+		b.Keys[keys.CurrentKey.KeyId] = keys.CurrentKey
+	}
+
+	for i := 0; i < len(keys.AllKeys); i++ {
+		if keys.AllKeys[i] != nil {
+			b.Keys[keys.AllKeys[i].KeyId] = keys.AllKeys[i]
+		}
+	}
+
+	//throw out the keys that have expired
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for keyId, key := range b.Keys {
+		if key.ExpiryDate != 0 && key.ExpiryDate < now {
+			delete(b.Keys, keyId)
+		}
+	}
+}
+
+//returns nil if token lets its holder access blockId in the given
+//mode (e.g. writables.ACCESS_MODE_READ), otherwise the reason why not
+func (b *BlockKeyStore) Validate(token *writables.Token, blockId uint64,
+	mode string) error {
+	b.RLock()
+	defer b.RUnlock()
+
+	if !b.Captured {
+		return errors.New("No block keys have been seen yet.")
+	}
+
+	if !b.TokensEnabled {
+		return nil
+	}
+
+	if token == nil || token.IsEmpty() {
+		return errors.New("Block tokens are enabled but no token was given.")
+	}
+
+	identifier, err := writables.ParseBlockTokenIdentifier(token)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if identifier.ExpiryDate < now {
+		return errors.New("Block token has expired.")
+	}
+
+	if !identifier.HasBlock(blockId) {
+		return errors.New("Block token was not issued for this block.")
+	}
+
+	if !identifier.HasMode(mode) {
+		return errors.New("Block token does not grant " + mode + " access.")
+	}
+
+	key, present := b.Keys[identifier.KeyId]
+	if !present {
+		return errors.New("Block token was signed with an unknown key.")
+	}
+
+	password := writables.BlockTokenPassword(token.Identifier, key)
+	if !hmac.Equal(password, token.Password) {
+		return errors.New("Block token password does not match.")
+	}
+
+	return nil
+}
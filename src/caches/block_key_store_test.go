@@ -0,0 +1,122 @@
+package caches
+
+import (
+	//go packages
+	"testing"
+	"bytes"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+func makeBlockKeys(enabled bool) *writables.ExportedBlockKeys {
+	keys := writables.NewExportedBlockKeys()
+	keys.IsBlockTokenEnabled = enabled
+	keys.CurrentKey.KeyId = 3
+	keys.CurrentKey.KeyBytes = []byte("the current key")
+	keys.CurrentKey.Len = int64(len(keys.CurrentKey.KeyBytes))
+	keys.CurrentKey.ExpiryDate = 
+		time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	return keys
+}
+
+//returns a token for blockId signed with key that expires in expiresIn
+func makeBlockToken(key *writables.BlockKey, blockId uint64, 
+	expiresIn time.Duration) *writables.Token {
+	identifier := writables.NewBlockTokenIdentifier()
+	identifier.ExpiryDate = 
+		time.Now().Add(expiresIn).UnixNano() / int64(time.Millisecond)
+	identifier.KeyId = key.KeyId
+	identifier.UserId = "hduser"
+	identifier.BlockIds = []int64{int64(blockId)}
+	identifier.Modes = []string{writables.ACCESS_MODE_READ}
+
+	buf := new(bytes.Buffer)
+	identifier.Write(buf)
+
+	token := writables.NewToken()
+	token.Identifier = buf.Bytes()
+	token.IdentifierLength = int64(len(token.Identifier))
+	token.Password = writables.BlockTokenPassword(token.Identifier, key)
+	token.PasswordLength = int64(len(token.Password))
+	return token
+}
+
+func TestBlockKeyStoreValidate(t *testing.T) {
+	b := NewBlockKeyStore()
+	keys := makeBlockKeys(true)
+	token := makeBlockToken(keys.CurrentKey, 10, time.Hour)
+
+	//nothing to check against yet
+	if b.Validate(token, 10, writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+
+	b.Update(keys)
+	if b.Validate(token, 10, writables.ACCESS_MODE_READ) != nil {
+		t.Fail()
+	}
+
+	//wrong block and wrong mode
+	if b.Validate(token, 11, writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+
+	if b.Validate(token, 10, writables.ACCESS_MODE_WRITE) == nil {
+		t.Fail()
+	}
+
+	//expired
+	expired := makeBlockToken(keys.CurrentKey, 10, -time.Minute)
+	if b.Validate(expired, 10, writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+
+	//forged
+	token.Password[0] ^= 0x01
+	if b.Validate(token, 10, writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+
+	//no token at all
+	if b.Validate(writables.NewToken(), 10, 
+		writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+
+	//signed with a key we have never seen
+	unknown := writables.NewBlockKey()
+	unknown.KeyId = 4
+	unknown.KeyBytes = []byte("some other key")
+	other := makeBlockToken(unknown, 10, time.Hour)
+	if b.Validate(other, 10, writables.ACCESS_MODE_READ) == nil {
+		t.Fail()
+	}
+}
+
+func TestBlockKeyStoreKeepsOldKeys(t *testing.T) {
+	b := NewBlockKeyStore()
+	oldKeys := makeBlockKeys(true)
+	token := makeBlockToken(oldKeys.CurrentKey, 10, time.Hour)
+	b.Update(oldKeys)
+
+	newKeys := makeBlockKeys(true)
+	newKeys.CurrentKey.KeyId = 5
+	newKeys.CurrentKey.KeyBytes = []byte("the next key")
+	b.Update(newKeys)
+
+	if b.Validate(token, 10, writables.ACCESS_MODE_READ) != nil {
+		t.Fail()
+	}
+}
+
+func TestBlockKeyStoreTokensDisabled(t *testing.T) {
+	b := NewBlockKeyStore()
+	b.Update(makeBlockKeys(false))
+
+	if b.Validate(writables.NewToken(), 10, 
+		writables.ACCESS_MODE_READ) != nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,158 @@
+package caches
+
+/*
+* Keeps track of the getBlockLocations responses that pass
+* through the proxy so that the data layer knows which file a
+* block belongs to and which block of that file comes after it.
+*/
+
+import (
+	//go packages
+	"path"
+	"sort"
+	"sync"
+
+	//local packages
+	"writables"
+)
+
+//where in a file a given block is
+type BlockPosition struct {
+	Path string
+
+	//index into LocatedBlocks.LocatedBlockArr
+	Index int
+}
+
+type BlockLocationIndex struct {
+	//shared between all of the hdfs_requests.Processor and
+	//writable_processor.WritableProcessor instances
+	sync.RWMutex
+
+	//path -> the last LocatedBlocks seen for that path
+	Files map[string]*writables.LocatedBlocks
+
+	//block id -> where that block is in Files
+	Blocks map[uint64]BlockPosition
+}
+
+func NewBlockLocationIndex() *BlockLocationIndex {
+	b := BlockLocationIndex{}
+	b.Files = make(map[string]*writables.LocatedBlocks)
+	b.Blocks = make(map[uint64]BlockPosition)
+	return &b
+}
+
+//records the blocks of path; replaces whatever was
+//known about path before
+func (b *BlockLocationIndex) Add(path string, 
+	locatedBlocks *writables.LocatedBlocks) {
+	b.Lock()
+	defer b.Unlock()
+
+	old, present := b.Files[path]
+	if present {
+		for i := 0; i < len(old.LocatedBlockArr); i++ {
+			delete(b.Blocks, old.LocatedBlockArr[i].B.BlockId)
+		}
+	}
+
+	b.Files[path] = locatedBlocks
+	for i := 0; i < len(locatedBlocks.LocatedBlockArr); i++ {
+		blockId := locatedBlocks.LocatedBlockArr[i].B.BlockId
+		b.Blocks[blockId] = BlockPosition{Path: path, Index: i}
+	}
+}
+
+//returns the blocks of path, or nil if we 
+//haven't seen a getBlockLocations for it
+func (b *BlockLocationIndex) Lookup(path string) *writables.LocatedBlocks {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.Files[path]
+}
+
+//returns the LocatedBlock for blockId, or nil if
+//we don't know which file it belongs to
+func (b *BlockLocationIndex) LookupBlock(blockId uint64) *writables.LocatedBlock {
+	b.RLock()
+	defer b.RUnlock()
+
+	pos, present := b.Blocks[blockId]
+	if !present {
+		return nil
+	}
+
+	return b.Files[pos.Path].LocatedBlockArr[pos.Index]
+}
+
+//returns the path of the file blockId belongs to, or "" if
+//we don't know
+func (b *BlockLocationIndex) LookupPath(blockId uint64) string {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.Blocks[blockId].Path
+}
+
+//returns up to count blocks that follow blockId in its file
+func (b *BlockLocationIndex) NextBlocks(blockId uint64, 
+	count int) []*writables.LocatedBlock {
+	b.RLock()
+	defer b.RUnlock()
+
+	res := make([]*writables.LocatedBlock, 0)
+	pos, present := b.Blocks[blockId]
+	if !present {
+		return res
+	}
+
+	blocks := b.Files[pos.Path].LocatedBlockArr
+	for i := pos.Index+1; i < len(blocks) && len(res) < count; i++ {
+		res = append(res, blocks[i])
+	}
+
+	return res
+}
+
+//returns the paths we have seen blocks of that match pattern 
+//(see path.Match; a "*" does not match across a "/"), in order
+func (b *BlockLocationIndex) Glob(pattern string) ([]string, error) {
+	//path.Match only reports a bad pattern once it gets 
+	//far enough into a name to notice
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+
+	res := make([]string, 0)
+	for filePath := range b.Files {
+		matched, _ := path.Match(pattern, filePath)
+		if matched {
+			res = append(res, filePath)
+		}
+	}
+
+	sort.Strings(res)
+	return res, nil
+}
+
+//returns the ids of the blocks of filePath (in order), or nil
+//if we haven't seen a getBlockLocations for it
+func (b *BlockLocationIndex) BlockIds(filePath string) []uint64 {
+	locatedBlocks := b.Lookup(filePath)
+	if locatedBlocks == nil {
+		return nil
+	}
+
+	res := make([]uint64, 0, len(locatedBlocks.LocatedBlockArr))
+	for i := 0; i < len(locatedBlocks.LocatedBlockArr); i++ {
+		res = append(res, locatedBlocks.LocatedBlockArr[i].B.BlockId)
+	}
+
+	return res
+}
@@ -0,0 +1,92 @@
+package caches
+
+import (
+	//go packages
+	"testing"
+
+	//local packages
+	"writables"
+)
+
+func makeLocatedBlocks(blockIds ...uint64) *writables.LocatedBlocks {
+	l := writables.NewLocatedBlocks()
+	l.NumberOfBlocks = uint32(len(blockIds))
+	l.LocatedBlockArr = make([]*writables.LocatedBlock, len(blockIds))
+	for i := 0; i < len(blockIds); i++ {
+		l.LocatedBlockArr[i] = writables.NewLocatedBlock()
+		l.LocatedBlockArr[i].B.BlockId = blockIds[i]
+	}
+
+	return l
+}
+
+func TestBlockLocationIndexNextBlocks(t *testing.T) {
+	b := NewBlockLocationIndex()
+	b.Add("/user/a", makeLocatedBlocks(1, 2, 3))
+
+	next := b.NextBlocks(1, 1)
+	if len(next) != 1 || next[0].B.BlockId != 2 {
+		t.Fail()
+	}
+
+	//only as many as there are
+	next = b.NextBlocks(2, 5)
+	if len(next) != 1 || next[0].B.BlockId != 3 {
+		t.Fail()
+	}
+
+	if len(b.NextBlocks(3, 1)) != 0 || len(b.NextBlocks(42, 1)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestBlockLocationIndexReplace(t *testing.T) {
+	b := NewBlockLocationIndex()
+	b.Add("/user/a", makeLocatedBlocks(1, 2))
+	b.Add("/user/a", makeLocatedBlocks(4))
+
+	if b.LookupBlock(1) != nil {
+		t.Fail()
+	}
+
+	if b.LookupBlock(4) == nil || b.Lookup("/user/a").NumberOfBlocks != 1 {
+		t.Fail()
+	}
+
+	if b.LookupPath(4) != "/user/a" || b.LookupPath(1) != "" {
+		t.Fail()
+	}
+}
+
+func TestBlockLocationIndexGlob(t *testing.T) {
+	b := NewBlockLocationIndex()
+	b.Add("/user/a/part-1", makeLocatedBlocks(1, 2))
+	b.Add("/user/a/part-0", makeLocatedBlocks(3))
+	b.Add("/user/a/sub/part-2", makeLocatedBlocks(4))
+
+	paths, err := b.Glob("/user/a/part-*")
+	if err != nil || len(paths) != 2 || paths[0] != "/user/a/part-0" ||
+		paths[1] != "/user/a/part-1" {
+		t.Fail()
+	}
+
+	//a literal path is a pattern too
+	paths, err = b.Glob("/user/a/sub/part-2")
+	if err != nil || len(paths) != 1 {
+		t.Fail()
+	}
+
+	_, err = b.Glob("/user/[")
+	if err == nil {
+		t.Fail()
+	}
+
+	blockIds := b.BlockIds("/user/a/part-1")
+	if len(blockIds) != 2 || blockIds[0] != 1 || blockIds[1] != 2 {
+		t.Fail()
+	}
+
+	if b.BlockIds("/nope") != nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,161 @@
+/**
+* Keeps a log of what happens to the blocks in a
+* WritableDataCache (inserted, evicted, pinned...) so that
+* the scheduler can follow the cache without polling it
+* for its whole contents (see cache_info_server).
+*/
+package caches
+
+import (
+	//go packages
+	"sync"
+
+	//local packages
+)
+
+/* CacheEvent.Type */
+const (
+	//a block is now (completely) in the cache
+	CACHE_EVENT_INSERT = uint16(iota)
+
+	//a block is no longer in the cache
+	CACHE_EVENT_EVICT
+
+	CACHE_EVENT_PIN
+	CACHE_EVENT_UNPIN
+)
+
+//number of events a CacheEventLog remembers by default
+var CACHE_EVENT_LOG_SIZE = 4096
+
+//number of events that may be waiting for a subscriber before
+//it is considered too slow and dropped
+var CACHE_SUBSCRIPTION_BUFFER = 256
+
+type CacheEvent struct {
+	//starts at 1 and goes up by one per event
+	Seq uint64
+
+	Type uint16
+	BlockId uint64
+
+	//DataNode the block was read from (not set for pins)
+	DataNode string
+}
+
+//an event stream handed out by CacheEventLog.Subscribe()
+type CacheSubscription struct {
+	//closed if the subscriber falls more than
+	//CACHE_SUBSCRIPTION_BUFFER events behind
+	Events chan CacheEvent
+
+	//sequence number of the last event before the 
+	//subscription started
+	StartSeq uint64
+
+	log *CacheEventLog
+}
+
+//stops the subscription
+func (c *CacheSubscription) Close() {
+	c.log.unsubscribe(c)
+}
+
+type CacheEventLog struct {
+	sync.Mutex
+
+	//number of events kept for Since()
+	Size int
+
+	lastSeq uint64
+
+	//the last Size events, oldest first
+	events []CacheEvent
+
+	subscribers map[*CacheSubscription]bool
+}
+
+func NewCacheEventLog(size int) *CacheEventLog {
+	c := CacheEventLog{Size: size}
+	c.events = make([]CacheEvent, 0)
+	c.subscribers = make(map[*CacheSubscription]bool)
+	return &c
+}
+
+//gives event the next sequence number, records it and
+//passes it on to the subscribers
+func (c *CacheEventLog) Publish(event CacheEvent) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.lastSeq++
+	event.Seq = c.lastSeq
+
+	c.events = append(c.events, event)
+	if len(c.events) > c.Size {
+		c.events = c.events[len(c.events) - c.Size:]
+	}
+
+	for subscriber := range c.subscribers {
+		select {
+		case subscriber.Events <- event:
+		default:
+			//we never wait on a subscriber; one that has
+			//fallen behind has to catch up with Since()
+			delete(c.subscribers, subscriber)
+			close(subscriber.Events)
+		}
+	}
+}
+
+//sequence number of the last event (0 => none yet)
+func (c *CacheEventLog) LastSeq() uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.lastSeq
+}
+
+//returns the events that came after seq. The boolean is false if
+//some of them have already been forgotten, in which case the caller
+//has to start over from a full listing of the cache.
+func (c *CacheEventLog) Since(seq uint64) ([]CacheEvent, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	res := make([]CacheEvent, 0)
+	if seq >= c.lastSeq {
+		return res, true
+	}
+
+	//sequence number of the oldest event we have
+	oldest := c.lastSeq - uint64(len(c.events)) + 1
+	if seq + 1 < oldest {
+		return res, false
+	}
+
+	res = append(res, c.events[seq + 1 - oldest:]...)
+	return res, true
+}
+
+//starts passing every new event on to the returned subscription
+func (c *CacheEventLog) Subscribe() *CacheSubscription {
+	c.Lock()
+	defer c.Unlock()
+
+	s := CacheSubscription{log: c, StartSeq: c.lastSeq}
+	s.Events = make(chan CacheEvent, CACHE_SUBSCRIPTION_BUFFER)
+	c.subscribers[&s] = true
+	return &s
+}
+
+func (c *CacheEventLog) unsubscribe(s *CacheSubscription) {
+	c.Lock()
+	defer c.Unlock()
+
+	_, present := c.subscribers[s]
+	if present {
+		delete(c.subscribers, s)
+		close(s.Events)
+	}
+}
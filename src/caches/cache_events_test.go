@@ -0,0 +1,121 @@
+package caches
+
+import (
+	//go packages
+	"testing"
+
+	//local packages
+	"writables"
+)
+
+func TestCacheEventLogSince(t *testing.T) {
+	c := NewCacheEventLog(3)
+
+	events, complete := c.Since(0)
+	if len(events) != 0 || !complete {
+		t.Fail()
+	}
+
+	for i := 1; i <= 5; i++ {
+		c.Publish(CacheEvent{Type: CACHE_EVENT_INSERT, BlockId: uint64(i)})
+	}
+
+	if c.LastSeq() != 5 {
+		t.Fail()
+	}
+
+	//3, 4 and 5 are still there
+	events, complete = c.Since(2)
+	if !complete || len(events) != 3 || events[0].Seq != 3 || 
+		events[2].BlockId != 5 {
+		t.Fail()
+	}
+
+	//2 is gone
+	_, complete = c.Since(1)
+	if complete {
+		t.Fail()
+	}
+
+	events, complete = c.Since(5)
+	if !complete || len(events) != 0 {
+		t.Fail()
+	}
+}
+
+func TestCacheEventLogSubscribe(t *testing.T) {
+	c := NewCacheEventLog(10)
+	c.Publish(CacheEvent{Type: CACHE_EVENT_PIN, BlockId: 1})
+
+	s := c.Subscribe()
+	if s.StartSeq != 1 {
+		t.Fail()
+	}
+
+	c.Publish(CacheEvent{Type: CACHE_EVENT_UNPIN, BlockId: 1})
+	event := <-s.Events
+	if event.Seq != 2 || event.Type != CACHE_EVENT_UNPIN {
+		t.Fail()
+	}
+
+	s.Close()
+	s.Close()
+	_, open := <-s.Events
+	if open {
+		t.Fail()
+	}
+}
+
+//a subscriber that doesn't keep up is dropped rather than
+//holding up the cache
+func TestCacheEventLogSlowSubscriber(t *testing.T) {
+	c := NewCacheEventLog(10)
+	s := c.Subscribe()
+
+	for i := 0; i < CACHE_SUBSCRIPTION_BUFFER + 1; i++ {
+		c.Publish(CacheEvent{Type: CACHE_EVENT_INSERT})
+	}
+
+	received := 0
+	for _ = range s.Events {
+		received++
+	}
+
+	if received != CACHE_SUBSCRIPTION_BUFFER {
+		t.Fail()
+	}
+}
+
+func TestWDCEvents(t *testing.T) {
+	setupWDC()
+	cache.CacheSize = 1
+	cache.PinnedBytesLimit = 10
+
+	//nothing until the pair is complete
+	filling, _ := cache.Join("dn:1", request)
+	if cache.Events.LastSeq() != 0 {
+		t.Fail()
+	}
+
+	filling.Complete()
+
+	//a pair that never completes is never reported
+	other := writables.NewReadBlockHeader()
+	other.BlockId = 9
+	failed, _ := cache.Join("dn:1", other)
+	failed.Fail()
+	cache.Remove(failed)
+
+	cache.Pin(9, 1)
+
+	events, _ := cache.Events.Since(0)
+	if len(events) != 3 {
+		t.FailNow()
+	}
+
+	if events[0].Type != CACHE_EVENT_INSERT || events[0].DataNode != "dn:1" ||
+		events[1].Type != CACHE_EVENT_EVICT || events[1].BlockId != 0 ||
+		events[2].Type != CACHE_EVENT_PIN || events[2].BlockId != 9 {
+		t.Fail()
+	}
+}
@@ -1,5 +1,10 @@
 package caches
 
+import (
+	//go packages
+	"sync"
+)
+
 //holds a structure w/ all the enabled caches
 //this is shared amongst different hdfs_request.Processor
 //instances
@@ -7,11 +12,26 @@ type CacheSet struct {
 	//this should be enabled
 	GfiCache *GetFileInfoCache
 	GetListingCache *GetListingCache
+
+	//not really a cache; filled in from getBlockLocations
+	//responses and used by the data layer
+	BlockIndex *BlockLocationIndex
+
+	//block keys from DataNode registrations; used by the data
+	//layer to check block tokens before serving cached blocks
+	BlockKeys *BlockKeyStore
+
+	//the OP_BLOCK_CHECKSUM caches of the DataNode relays (one
+	//per DataNode); see AddChecksumCache()
+	checksumLock sync.RWMutex
+	checksumCaches []*BlockChecksumCache
 }
 
 func NewCacheSet() *CacheSet {
 	cs := CacheSet{}
 	cs.GfiCache = NewGetFileInfoCache(0)
+	cs.BlockIndex = NewBlockLocationIndex()
+	cs.BlockKeys = NewBlockKeyStore()
 	return &cs
 }
 
@@ -19,4 +39,20 @@ func NewCacheSet() *CacheSet {
 func (cs *CacheSet) Disable() {
 	cs.GfiCache.Disable()
 	cs.GetListingCache.Disable()	
-}
\ No newline at end of file
+}
+
+//registers the checksum cache of a DataNode relay, so that
+//evicting or clearing blocks reaches it too
+func (cs *CacheSet) AddChecksumCache(b *BlockChecksumCache) {
+	cs.checksumLock.Lock()
+	defer cs.checksumLock.Unlock()
+
+	cs.checksumCaches = append(cs.checksumCaches, b)
+}
+
+func (cs *CacheSet) ChecksumCaches() []*BlockChecksumCache {
+	cs.checksumLock.RLock()
+	defer cs.checksumLock.RUnlock()
+
+	return append([]*BlockChecksumCache{}, cs.checksumCaches...)
+}
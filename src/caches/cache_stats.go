@@ -0,0 +1,203 @@
+/**
+* Counts how well a WritableDataCache is doing (hits, misses,
+* where the bytes handed to clients came from, how long reads
+* took) so that it can be reported through cache_info_server.
+*/
+package caches
+
+import (
+	//go packages
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	//local packages
+)
+
+//number of read latencies kept (for hits and for misses
+//each) to work out the percentiles from
+var LATENCY_SAMPLES = 1024
+
+//number of blocks whose reads are counted; once there are
+//more, the block read least recently is forgotten
+var ACCESSES_TRACKED = 1024
+
+//percentiles of the latencies of the last LATENCY_SAMPLES
+//reads (all zero if there were none)
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+//the last (up to) size latencies recorded
+type latencySamples struct {
+	samples []time.Duration
+
+	//where the next sample goes once samples is full
+	next int
+	size int
+}
+
+func newLatencySamples(size int) *latencySamples {
+	l := latencySamples{size: size}
+	l.samples = make([]time.Duration, 0, size)
+	return &l
+}
+
+func (l *latencySamples) add(latency time.Duration) {
+	if len(l.samples) < l.size {
+		l.samples = append(l.samples, latency)
+		return
+	}
+
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % l.size
+}
+
+func (l *latencySamples) percentiles() LatencyPercentiles {
+	if len(l.samples) == 0 {
+		return LatencyPercentiles{}
+	}
+
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	//nearest rank
+	at := func(percentile int) time.Duration {
+		rank := (percentile * len(sorted) + 99) / 100
+		return sorted[rank - 1]
+	}
+
+	return LatencyPercentiles{P50: at(50),
+		P90: at(90),
+		P99: at(99),
+		Max: sorted[len(sorted) - 1]}
+}
+
+type CacheStats struct {
+	sync.Mutex
+
+	//reads served out of the cache (including reads that
+	//joined a fill in progress) and reads that went to a DataNode
+	hits int64
+	misses int64
+
+	//bytes of block data sent to clients on hits and on misses
+	bytesFromCache int64
+	bytesFromDataNode int64
+
+	//pairs thrown out to make room for others
+	evictions int64
+
+	//block id -> number of reads (hits or misses) of the block,
+	//for the last ACCESSES_TRACKED blocks read (the elements of
+	//accessOrder hold *blockAccesses, most recently read first)
+	accesses map[uint64]*list.Element
+	accessOrder *list.List
+
+	hitLatencies *latencySamples
+	missLatencies *latencySamples
+}
+
+//a copy of CacheStats at some point in time
+type CacheStatsSnapshot struct {
+	Hits int64
+	Misses int64
+	BytesFromCache int64
+	BytesFromDataNode int64
+	Evictions int64
+
+	//reads of the (up to ACCESSES_TRACKED) blocks read most recently
+	Accesses map[uint64]int64
+	HitLatency LatencyPercentiles
+	MissLatency LatencyPercentiles
+}
+
+//the reads of a block counted in CacheStats
+type blockAccesses struct {
+	blockId uint64
+	count int64
+}
+
+func NewCacheStats() *CacheStats {
+	c := CacheStats{}
+	c.accesses = make(map[uint64]*list.Element)
+	c.accessOrder = list.New()
+	c.hitLatencies = newLatencySamples(LATENCY_SAMPLES)
+	c.missLatencies = newLatencySamples(LATENCY_SAMPLES)
+	return &c
+}
+
+//records a read of blockId that was served out of the cache; bytes
+//of block data were sent and it took latency to send them
+func (c *CacheStats) RecordHit(blockId uint64, bytes int64,
+	latency time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.hits++
+	c.bytesFromCache += bytes
+	c.recordAccess(blockId)
+	c.hitLatencies.add(latency)
+}
+
+//records a read of blockId that had to go to a DataNode
+func (c *CacheStats) RecordMiss(blockId uint64, bytes int64,
+	latency time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.misses++
+	c.bytesFromDataNode += bytes
+	c.recordAccess(blockId)
+	c.missLatencies.add(latency)
+}
+
+//counts a read of blockId; c must be locked
+func (c *CacheStats) recordAccess(blockId uint64) {
+	element, present := c.accesses[blockId]
+	if present {
+		element.Value.(*blockAccesses).count++
+		c.accessOrder.MoveToFront(element)
+		return
+	}
+
+	c.accesses[blockId] = c.accessOrder.PushFront(
+		&blockAccesses{blockId: blockId, count: 1})
+	for c.accessOrder.Len() > ACCESSES_TRACKED {
+		last := c.accessOrder.Back()
+		c.accessOrder.Remove(last)
+		delete(c.accesses, last.Value.(*blockAccesses).blockId)
+	}
+}
+
+func (c *CacheStats) RecordEviction() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.evictions++
+}
+
+func (c *CacheStats) Snapshot() *CacheStatsSnapshot {
+	c.Lock()
+	defer c.Unlock()
+
+	s := CacheStatsSnapshot{Hits: c.hits,
+		Misses: c.misses,
+		BytesFromCache: c.bytesFromCache,
+		BytesFromDataNode: c.bytesFromDataNode,
+		Evictions: c.evictions,
+		HitLatency: c.hitLatencies.percentiles(),
+		MissLatency: c.missLatencies.percentiles()}
+
+	s.Accesses = make(map[uint64]int64, len(c.accesses))
+	for blockId, element := range c.accesses {
+		s.Accesses[blockId] = element.Value.(*blockAccesses).count
+	}
+
+	return &s
+}
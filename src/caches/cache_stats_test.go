@@ -0,0 +1,103 @@
+package caches
+
+import (
+	//go packages
+	"testing"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+func TestCacheStatsCounters(t *testing.T) {
+	c := NewCacheStats()
+	c.RecordHit(1, 100, time.Millisecond)
+	c.RecordHit(1, 100, time.Millisecond)
+	c.RecordMiss(2, 50, time.Second)
+	c.RecordEviction()
+
+	s := c.Snapshot()
+	if s.Hits != 2 || s.Misses != 1 || s.Evictions != 1 {
+		t.Fail()
+	}
+
+	if s.BytesFromCache != 200 || s.BytesFromDataNode != 50 {
+		t.Fail()
+	}
+
+	if s.Accesses[1] != 2 || s.Accesses[2] != 1 {
+		t.Fail()
+	}
+
+	if s.HitLatency.P99 != time.Millisecond || s.MissLatency.Max != time.Second {
+		t.Fail()
+	}
+
+	//the snapshot is a copy
+	s.Accesses[1] = 10
+	if c.Snapshot().Accesses[1] != 2 {
+		t.Fail()
+	}
+}
+
+func TestCacheStatsAccessesBounded(t *testing.T) {
+	tracked := ACCESSES_TRACKED
+	ACCESSES_TRACKED = 3
+	defer func() { ACCESSES_TRACKED = tracked }()
+
+	c := NewCacheStats()
+	for blockId := uint64(1); blockId <= 3; blockId++ {
+		c.RecordMiss(blockId, 1, time.Millisecond)
+	}
+	c.RecordHit(1, 1, time.Millisecond)
+
+	//block 2 is the one read least recently
+	c.RecordMiss(4, 1, time.Millisecond)
+	s := c.Snapshot()
+	if len(s.Accesses) != 3 || s.Accesses[1] != 2 || s.Accesses[4] != 1 {
+		t.Fatal("Wrong accesses: ", s.Accesses)
+	}
+
+	_, present := s.Accesses[2]
+	if present {
+		t.Fail()
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	l := newLatencySamples(100)
+	if l.percentiles() != (LatencyPercentiles{}) {
+		t.Fail()
+	}
+
+	for i := 1; i <= 100; i++ {
+		l.add(time.Duration(i))
+	}
+
+	p := l.percentiles()
+	if p.P50 != 50 || p.P90 != 90 || p.P99 != 99 || p.Max != 100 {
+		t.Fail()
+	}
+
+	//the oldest samples make way for new ones
+	for i := 0; i < 100; i++ {
+		l.add(1000)
+	}
+
+	if l.percentiles().P50 != 1000 {
+		t.Fail()
+	}
+}
+
+func TestWDCCountsEvictions(t *testing.T) {
+	c := NewWritableDataCache(1)
+	for i := 0; i < 3; i++ {
+		request := writables.NewReadBlockHeader()
+		request.BlockId = uint64(i)
+		c.Join("", request)
+	}
+
+	if c.Stats.Snapshot().Evictions != 2 {
+		t.Fail()
+	}
+}
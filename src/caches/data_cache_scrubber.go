@@ -0,0 +1,151 @@
+/**
+* Periodically re-verifies the blocks held in a
+* WritableDataCache so that a bit flip in memory
+* is caught before the data is replayed to a job.
+*/
+package caches
+
+import (
+	//go packages
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"sync"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+//asks the DataNode at address for the OP_BLOCK_CHECKSUM of the block
+//requested by request (see writable_processor.FetchBlockChecksum())
+type BlockChecksumFunc func(address string,
+	request *writables.ReadBlockHeader) (*writables.BlockChecksumResponse, error)
+
+type DataCacheScrubber struct {
+	DataCache *WritableDataCache
+
+	//time between two passes over the cache
+	Interval time.Duration
+
+	//optional; if set, entries that hold a whole block
+	//are also compared against the DataNode's checksum
+	BlockChecksum BlockChecksumFunc
+
+	//closed by Stop()
+	stop chan bool
+	stopOnce sync.Once
+}
+
+func NewDataCacheScrubber(dataCache *WritableDataCache, 
+	interval time.Duration) *DataCacheScrubber {
+
+	s := DataCacheScrubber{DataCache: dataCache,
+		Interval: interval}
+	s.stop = make(chan bool)
+	return &s
+}
+
+//runs ScrubOnce() every Interval until Stop() is called
+//should be run as a goroutine
+func (s *DataCacheScrubber) Start() {
+	for {
+		select {
+		case <- s.stop:
+			return
+		case <- time.After(s.Interval):
+			s.ScrubOnce()
+		}
+	}
+}
+
+func (s *DataCacheScrubber) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+//verifies every complete pair in the cache, evicting (and counting)
+//the ones that fail. Returns the number of pairs evicted.
+func (s *DataCacheScrubber) ScrubOnce() int {
+	evicted := 0
+
+	pairs := s.DataCache.CompletePairs()
+	for i := 0; i < len(pairs); i++ {
+		err := s.Verify(pairs[i])
+		if err != nil {
+			s.DataCache.RecordChecksumMismatch(pairs[i])
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+//checks every packet of pair against its own checksums and, if
+//BlockChecksum is set, the block's checksum against the DataNode's
+func (s *DataCacheScrubber) Verify(pair *writables.ReadPair) error {
+	chunks := pair.ResponseSet.Chunks
+	for i := 0; i < len(chunks); i++ {
+		err := chunks[i].VerifyChecksums()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.BlockChecksum == nil || !holdsWholeBlock(pair) {
+		return nil
+	}
+
+	res, err := s.BlockChecksum(pair.DataNode, pair.Request)
+	//not being able to ask the DataNode does not
+	//mean that our copy is bad
+	if err != nil || res.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		return nil
+	}
+
+	//the pair might only hold part of the block after all
+	if res.CrcPerBlock != uint64(numChecksums(pair)) {
+		return nil
+	}
+
+	if !bytes.Equal(res.Md5, BlockChecksumMd5(pair)) {
+		return errors.New("Cached block does not match the DataNode's checksum.")
+	}
+
+	return nil
+}
+
+//true if pair was read from the start of the block with 
+//CRC32 checksums (so its checksums can be compared with the
+//ones in the DataNode's metadata file)
+func holdsWholeBlock(pair *writables.ReadPair) bool {
+	header := pair.ResponseSet.Header
+	if header == nil || header.Checksum.Type != writables.CHECKSUM_CRC32 {
+		return false
+	}
+
+	return pair.Request.StartOffset == 0 && header.ChunkOffset == 0
+}
+
+func numChecksums(pair *writables.ReadPair) int {
+	total := 0
+	chunks := pair.ResponseSet.Chunks
+	for i := 0; i < len(chunks); i++ {
+		total += len(chunks[i].ChecksumData)
+	}
+
+	return total/int(writables.CHECKSUM_CRC32_SIZE)
+}
+
+//computes the same MD5 the DataNode returns for OP_BLOCK_CHECKSUM 
+//(an MD5 over all of the CRCs) from the packets held by pair
+func BlockChecksumMd5(pair *writables.ReadPair) []byte {
+	hash := md5.New()
+	chunks := pair.ResponseSet.Chunks
+	for i := 0; i < len(chunks); i++ {
+		hash.Write(chunks[i].ChecksumData)
+	}
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,81 @@
+package caches
+
+import (
+	//go packages
+	"testing"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+func makeScrubPair(blockId uint64, data []byte) *writables.ReadPair {
+	request := writables.NewReadBlockHeader()
+	request.BlockId = blockId
+
+	header := writables.NewBlockResponseHeader()
+	header.Checksum.Type = writables.CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = 4
+
+	packet := writables.NewBlockPacket(header)
+	packet.Length = uint32(len(data))
+	packet.Data = data
+	packet.FillChecksums()
+
+	p := writables.NewReadPair(request)
+	p.SetResponseHeader(header)
+	p.AddBlockPacket(packet)
+	p.Complete()
+	return p
+}
+
+func TestScrubOnce(t *testing.T) {
+	dataCache := NewWritableDataCache(10)
+	good := makeScrubPair(1, []byte{1, 2, 3, 4, 5})
+	bad := makeScrubPair(2, []byte{1, 2, 3, 4, 5})
+	dataCache.AddReadPair(good)
+	dataCache.AddReadPair(bad)
+
+	//flip a bit in the "cached" data
+	bad.ResponseSet.Chunks[0].Data[0] ^= 0x80
+
+	s := NewDataCacheScrubber(dataCache, time.Second)
+	if s.ScrubOnce() != 1 {
+		t.Fail()
+	}
+
+	if dataCache.CurrSize() != 1 || dataCache.Query("", good.Request) != good {
+		t.Fail()
+	}
+
+	if dataCache.GetChecksumMismatches() != 1 {
+		t.Fail()
+	}
+}
+
+func TestScrubBlockChecksum(t *testing.T) {
+	dataCache := NewWritableDataCache(10)
+	pair := makeScrubPair(1, []byte{1, 2, 3, 4, 5})
+	dataCache.AddReadPair(pair)
+
+	res := writables.NewBlockChecksumResponse()
+	res.BytesPerCrc = 4
+	res.CrcPerBlock = 2
+	res.Md5 = BlockChecksumMd5(pair)
+
+	s := NewDataCacheScrubber(dataCache, time.Second)
+	s.BlockChecksum = func(address string, r *writables.ReadBlockHeader) (
+		*writables.BlockChecksumResponse, error) {
+		return res, nil
+	}
+
+	if s.ScrubOnce() != 0 {
+		t.Fail()
+	}
+
+	//the DataNode disagrees with us
+	res.Md5 = make([]byte, writables.MD5_SIZE)
+	if s.ScrubOnce() != 1 || dataCache.CurrSize() != 0 {
+		t.Fail()
+	}
+}
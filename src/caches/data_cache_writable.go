@@ -1,5 +1,5 @@
 /**
-* Implements a cache for the 
+* Implements a cache for the
 * writable_processor. It caches
 * OP_READ_BLOCK requests and responses
 */
@@ -7,112 +7,496 @@ package caches
 
 import (
 	//go packages
+	"container/list"
 	"sync"
 
 	//local packages
 	"writables"
 )
 
+//number of stripes the pairs of a WritableDataCache are spread
+//over; readers of blocks in different stripes never wait on
+//each other
+var DATA_CACHE_STRIPES = 16
+
+//what a ReadPair is stored under. Reads of the same block through
+//different DataNodes are kept apart.
+type DataCacheKey struct {
+	//address of the DataNode the block is read from
+	DataNode string
+
+	writables.ReadBlockKey
+}
+
+func NewDataCacheKey(dataNode string,
+	request *writables.ReadBlockHeader) DataCacheKey {
+	return DataCacheKey{DataNode: dataNode, ReadBlockKey: request.CacheKey()}
+}
+
+//one lock's worth of the cache
+type dataCacheStripe struct {
+	sync.RWMutex
+	pairs map[DataCacheKey]*writables.ReadPair
+}
+
+//one of these is shared by every writable_processor in the process
+//(whichever DataNode they relay to)
 type WritableDataCache struct {
-	//mutexed because multiple
-	//goroutines/threads will be accessing the structure
+	//guards everything but the stripes (which have their own
+	//locks). A stripe lock is never held while taking this
+	//one or the other way around.
 	sync.RWMutex
 
 	CacheSize int
 
-	//this structure is a map between OP_READ_BLOCK requests
-	//and the set of BlockPacket responses as well as a header
-	RpcStore []*writables.ReadPair
+	//key -> pair, split up by DataCacheKey.BlockId
+	stripes []*dataCacheStripe
+
+	//every pair in the cache, oldest first (the oldest
+	//one that is not pinned is the one that gets evicted)
+	order *list.List
+	elements map[*writables.ReadPair]*list.Element
 
-	//hits, misses not yet implemented
-	Hits int
-	Misses int
+	//hits, misses, evictions... (readers record their
+	//hits and misses themselves)
+	Stats *CacheStats
+
+	//number of blocks that were thrown out because their
+	//data did not match their checksums (see RecordChecksumMismatch())
+	ChecksumMismatches int
 
 	Enabled bool
+
+	//most bytes of blocks that may be pinned at once
+	//(0 => nothing can be pinned)
+	PinnedBytesLimit int64
+
+	//block id -> length of the block; pairs of these blocks
+	//are never evicted
+	pins map[uint64]int64
+	pinnedBytes int64
+
+	//what has happened to the blocks in the cache. Only
+	//complete pairs are inserted as far as this is concerned.
+	Events *CacheEventLog
 }
 
 func NewWritableDataCache(cacheSize int) *WritableDataCache {
 	w := WritableDataCache{CacheSize: cacheSize,
-		Enabled: true,
-		Hits: 0,
-		Misses: 0}
+		Enabled: true}
+
+	w.stripes = make([]*dataCacheStripe, DATA_CACHE_STRIPES)
+	for i := 0; i < len(w.stripes); i++ {
+		w.stripes[i] = &dataCacheStripe{}
+		w.stripes[i].pairs = make(map[DataCacheKey]*writables.ReadPair)
+	}
 
-	w.RpcStore = make([]*writables.ReadPair, 0)
+	w.order = list.New()
+	w.elements = make(map[*writables.ReadPair]*list.Element)
+	w.pins = make(map[uint64]int64)
+	w.Events = NewCacheEventLog(CACHE_EVENT_LOG_SIZE)
+	w.Stats = NewCacheStats()
 
 	return &w
 }
 
-func (w *WritableDataCache) CurrSize() int {
+func (w *WritableDataCache) stripe(key DataCacheKey) *dataCacheStripe {
+	return w.stripes[key.BlockId % uint64(len(w.stripes))]
+}
+
+func (w *WritableDataCache) IsEnabled() bool {
 	w.RLock()
 	defer w.RUnlock()
-	return w.currSize()
+	return w.Enabled
+}
+
+//turns the cache on or off; while it is off, every reader
+//goes to the DataNode and nothing is cached or reported
+func (w *WritableDataCache) SetEnabled(enabled bool) {
+	w.Lock()
+	defer w.Unlock()
+	w.Enabled = enabled
 }
 
-func (w *WritableDataCache) currSize() int {
+func (w *WritableDataCache) CurrSize() int {
+	w.RLock()
+	defer w.RUnlock()
+
 	if !w.Enabled {
 		return 0
 	}
-	return len(w.RpcStore)
+	return w.order.Len()
 }
 
+//puts pair in the cache under pair.DataNode unless
+//something is already cached for that read
 func (w *WritableDataCache) AddReadPair(pair *writables.ReadPair) {
-	w.Lock()
-	defer w.Unlock()
-
-	if !w.Enabled {
+	if !w.IsEnabled() {
 		return
 	}
 
-	//see if the pair is already in the cache
-	resPair := w.Query(pair.Request)
+	key := NewDataCacheKey(pair.DataNode, pair.Request)
+	s := w.stripe(key)
+
+	s.Lock()
 	//if it is already in the cache, we do not
 	//need to add it again
-	if resPair != nil {
-		return
+	_, present := s.pairs[key]
+	if !present {
+		s.pairs[key] = pair
 	}
+	s.Unlock()
 
-	//if the size is already at the max point, we have to remove 
-	//the last element before we can insert another one
-	if w.currSize() >= w.CacheSize {
-		w.RpcStore = w.RpcStore[1:]
+	if !present {
+		w.track(pair)
+		pair.OnComplete(w.completed)
 	}
+}
+
+//adds pair (which has just been put in its stripe) to the
+//eviction order, evicting the oldest pairs if the cache is
+//now over CacheSize
+func (w *WritableDataCache) track(pair *writables.ReadPair) {
+	w.Lock()
+	w.elements[pair] = w.order.PushBack(pair)
+	evicted := w.trim()
+	w.Unlock()
 
-	w.RpcStore = append(w.RpcStore, pair)
+	for i := 0; i < len(evicted); i++ {
+		w.untrack(evicted[i])
+	}
 }
 
-//return a pair by providing a ReadBlockHeader
-func (w *WritableDataCache) Query(
+//assumes the lock is held; takes the oldest unpinned pairs out 
+//of the eviction order until the cache is down to CacheSize (or
+//everything left is pinned) and returns them so that they can 
+//be taken out of their stripes once the lock is released
+func (w *WritableDataCache) trim() []*writables.ReadPair {
+	evicted := make([]*writables.ReadPair, 0)
+
+	e := w.order.Front()
+	for e != nil && w.order.Len() > w.CacheSize {
+		next := e.Next()
+
+		oldest := e.Value.(*writables.ReadPair)
+		_, pinned := w.pins[oldest.Request.BlockId]
+		if !pinned {
+			w.order.Remove(e)
+			delete(w.elements, oldest)
+			w.published(CACHE_EVENT_EVICT, oldest)
+			w.Stats.RecordEviction()
+			evicted = append(evicted, oldest)
+		}
+
+		e = next
+	}
+
+	return evicted
+}
+
+//takes pair out of its stripe (if it is still there)
+func (w *WritableDataCache) untrack(pair *writables.ReadPair) {
+	key := NewDataCacheKey(pair.DataNode, pair.Request)
+	s := w.stripe(key)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.pairs[key] == pair {
+		delete(s.pairs, key)
+	}
+}
+
+//finds the pair for request to dataNode, inserting a new (empty)
+//one if there is none. The boolean returned is true if the caller
+//created the pair and so is responsible for filling it from
+//the DataNode (and for calling Complete() or Fail() on it).
+//Otherwise, some other reader is already filling (or has
+//filled) the pair and the caller should stream the packets
+//out of it with ReadPair.WaitBlockPacket() instead of going
+//to the DataNode itself.
+func (w *WritableDataCache) Join(dataNode string,
+	request *writables.ReadBlockHeader) (*writables.ReadPair, bool) {
+
+	//if the cache is off, everyone fills their own pair
+	if !w.IsEnabled() {
+		pair := writables.NewReadPair(request)
+		pair.DataNode = dataNode
+		return pair, true
+	}
+
+	key := NewDataCacheKey(dataNode, request)
+	s := w.stripe(key)
+
+	s.Lock()
+	pair := s.pairs[key]
+	if pair != nil && pair.State() != writables.FILL_FAILED {
+		s.Unlock()
+		return pair, false
+	}
+
+	//a failed pair is normally removed by its filler, but
+	//it may not have gotten around to it yet
+	failed := pair
+
+	pair = writables.NewReadPair(request)
+	pair.DataNode = dataNode
+	s.pairs[key] = pair
+	s.Unlock()
+
+	if failed != nil {
+		w.forget(failed)
+	}
+
+	w.track(pair)
+	pair.OnComplete(w.completed)
+	return pair, true
+}
+
+//called once pair is complete
+func (w *WritableDataCache) completed(pair *writables.ReadPair) {
+	w.RLock()
+	defer w.RUnlock()
+
+	//it may have been evicted while it was filling
+	_, present := w.elements[pair]
+	if present {
+		w.published(CACHE_EVENT_INSERT, pair)
+	}
+}
+
+//assumes the lock is held; publishes an event about pair, unless
+//it is about a pair that was never complete (and so never inserted
+//as far as anyone following Events is concerned)
+func (w *WritableDataCache) published(eventType uint16, 
+	pair *writables.ReadPair) {
+	if eventType == CACHE_EVENT_EVICT && !pair.IsComplete() {
+		return
+	}
+
+	w.Events.Publish(CacheEvent{Type: eventType, 
+		BlockId: pair.Request.BlockId, 
+		DataNode: pair.DataNode})
+}
+
+//return the pair for a read of toFind from dataNode. Only
+//complete pairs are returned; pairs that are still filling
+//can be joined with Join().
+func (w *WritableDataCache) Query(dataNode string,
 	toFind *writables.ReadBlockHeader) *writables.ReadPair {
 
-	if !w.Enabled {
+	pair := w.query(dataNode, toFind)
+	if pair == nil || !pair.IsComplete() {
 		return nil
 	}
 
-	//iterate through the availbale pairs
-	//and check if any of the requests matches toFind
-	for i := 0; i < w.currSize(); i++ {
-		pair := w.RpcStore[i]
-		if pair.Request.Equals(toFind) {
-			return pair
+	return pair
+}
+
+//drops pair from the cache (e.g. once its fill has failed)
+func (w *WritableDataCache) Remove(pair *writables.ReadPair) {
+	w.untrack(pair)
+	w.forget(pair)
+}
+
+//takes pair out of the eviction order
+func (w *WritableDataCache) forget(pair *writables.ReadPair) {
+	w.Lock()
+	defer w.Unlock()
+
+	element, present := w.elements[pair]
+	if present {
+		w.order.Remove(element)
+		delete(w.elements, pair)
+		w.published(CACHE_EVENT_EVICT, pair)
+	}
+}
+
+//drops every pair of blockId (whatever DataNode or range it was
+//read from) and unpins it. Returns the number of pairs dropped.
+func (w *WritableDataCache) EvictBlock(blockId uint64) int {
+	w.Unpin(blockId)
+	return w.evict(func(pair *writables.ReadPair) bool {
+		return pair.Request.BlockId == blockId
+	})
+}
+
+//drops every pair in the cache (pins are kept). Returns the
+//number of pairs dropped.
+func (w *WritableDataCache) Clear() int {
+	return w.evict(func(pair *writables.ReadPair) bool {
+		return true
+	})
+}
+
+//drops the pairs for which match returns true
+func (w *WritableDataCache) evict(match func(*writables.ReadPair) bool) int {
+	w.Lock()
+	evicted := make([]*writables.ReadPair, 0)
+	for e := w.order.Front(); e != nil; {
+		next := e.Next()
+
+		pair := e.Value.(*writables.ReadPair)
+		if match(pair) {
+			w.order.Remove(e)
+			delete(w.elements, pair)
+			w.published(CACHE_EVENT_EVICT, pair)
+			evicted = append(evicted, pair)
 		}
+
+		e = next
 	}
+	w.Unlock()
 
-	return nil
+	for i := 0; i < len(evicted); i++ {
+		w.untrack(evicted[i])
+	}
+
+	return len(evicted)
 }
 
-//add a BlockPacket to the pair in RpcStore that contains
-//header argument as the "Request" field.
-func (w *WritableDataCache) AddBlockPacket(
-	header *writables.ReadBlockHeader,
-	blockPacket *writables.BlockPacket) {
+//returns every pair in the cache (whatever its state),
+//oldest first
+func (w *WritableDataCache) Pairs() []*writables.ReadPair {
+	w.RLock()
+	defer w.RUnlock()
 
+	res := make([]*writables.ReadPair, 0, w.order.Len())
 	if !w.Enabled {
-		return
+		return res
+	}
+
+	for e := w.order.Front(); e != nil; e = e.Next() {
+		res = append(res, e.Value.(*writables.ReadPair))
+	}
+
+	return res
+}
+
+//returns the pairs that hold a complete block; these
+//are the only ones that should be reported or served
+func (w *WritableDataCache) CompletePairs() []*writables.ReadPair {
+	pairs := w.Pairs()
+
+	res := make([]*writables.ReadPair, 0, len(pairs))
+	for i := 0; i < len(pairs); i++ {
+		if pairs[i].IsComplete() {
+			res = append(res, pairs[i])
+		}
+	}
+
+	return res
+}
+
+//finds the pair for a read of toFind from dataNode, whatever
+//token it was made with and whatever state it is in
+func (w *WritableDataCache) query(dataNode string,
+	toFind *writables.ReadBlockHeader) *writables.ReadPair {
+
+	if !w.IsEnabled() {
+		return nil
 	}
 
+	key := NewDataCacheKey(dataNode, toFind)
+	s := w.stripe(key)
+
+	s.RLock()
+	defer s.RUnlock()
+	return s.pairs[key]
+}
+
+//add a BlockPacket to the pair that holds the read of
+//header from dataNode
+func (w *WritableDataCache) AddBlockPacket(dataNode string,
+	header *writables.ReadBlockHeader,
+	blockPacket *writables.BlockPacket) {
+
 	//get the pair that the header is in
-	pair := w.Query(header)
+	pair := w.query(dataNode, header)
+	if pair == nil {
+		return
+	}
 
 	//ad the BlockPacket to that pair
 	pair.AddBlockPacket(blockPacket)
 }
+
+//drops pair from the cache and counts it as a checksum mismatch
+func (w *WritableDataCache) RecordChecksumMismatch(pair *writables.ReadPair) {
+	w.Lock()
+	w.ChecksumMismatches++
+	w.Unlock()
+
+	w.Remove(pair)
+}
+
+func (w *WritableDataCache) GetChecksumMismatches() int {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.ChecksumMismatches
+}
+
+//keeps every pair of blockId (which is length bytes long) in the
+//cache until it is unpinned. Returns false if that would put more
+//than PinnedBytesLimit bytes under pins, and whether this call
+//added the pin (rather than blockId being pinned already).
+func (w *WritableDataCache) Pin(blockId uint64, length int64) (bool, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	_, present := w.pins[blockId]
+	if present {
+		return true, false
+	}
+
+	if w.pinnedBytes + length > w.PinnedBytesLimit {
+		return false, false
+	}
+
+	w.pins[blockId] = length
+	w.pinnedBytes += length
+	w.Events.Publish(CacheEvent{Type: CACHE_EVENT_PIN, BlockId: blockId})
+	return true, true
+}
+
+//lets the pairs of blockId be evicted again. Returns false
+//if blockId was not pinned.
+func (w *WritableDataCache) Unpin(blockId uint64) bool {
+	w.Lock()
+	length, present := w.pins[blockId]
+	if !present {
+		w.Unlock()
+		return false
+	}
+
+	delete(w.pins, blockId)
+	w.pinnedBytes -= length
+	w.Events.Publish(CacheEvent{Type: CACHE_EVENT_UNPIN, BlockId: blockId})
+
+	//the cache may have grown past CacheSize while
+	//everything in it was pinned
+	evicted := w.trim()
+	w.Unlock()
+
+	for i := 0; i < len(evicted); i++ {
+		w.untrack(evicted[i])
+	}
+
+	return true
+}
+
+func (w *WritableDataCache) IsPinned(blockId uint64) bool {
+	w.RLock()
+	defer w.RUnlock()
+
+	_, present := w.pins[blockId]
+	return present
+}
+
+func (w *WritableDataCache) PinnedBytes() int64 {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.pinnedBytes
+}
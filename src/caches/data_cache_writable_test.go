@@ -4,6 +4,7 @@ import (
 	//go packages
 	"testing"
 	"fmt"
+	"sync"
 
 	//local packages
 	"writables"
@@ -29,7 +30,7 @@ func TestNewWDC(t *testing.T) {
 		t.Fail()
 	}
 
-	if cache.RpcStore == nil {
+	if cache.Pairs() == nil {
 		t.Fail()
 	}
 
@@ -46,24 +47,39 @@ func TestAddReadPair(t *testing.T) {
 		t.Fail()
 	}
 
-	if cache.RpcStore[0] != pair {
+	if cache.Pairs()[0] != pair {
 		t.Fail()
 	}
 
-	//add 15 more, then the cache should 
-	//consistently discard items and keep
-	//the number of items at cacheSize
+	//adding the same read again does not add a second pair
+	cache.AddReadPair(pair)
+	if cache.CurrSize() != 1 {
+		t.Fail()
+	}
+
+	//add cacheSize+45 more reads of other blocks, then
+	//the cache should consistently discard the oldest and
+	//keep the number of items at cacheSize
+	var last *writables.ReadPair
 	for i := 0; i < cacheSize+45; i++ {
-		cache.AddReadPair(pair)
+		other := writables.NewReadBlockHeader()
+		other.BlockId = uint64(i + 1)
+		last = writables.NewReadPair(other)
+		cache.AddReadPair(last)
+
+		if cache.CurrSize() > cacheSize {
+			t.Fatal("Cache grew past its size: ", cache.CurrSize())
+		}
 	}
 
-	if cache.CurrSize() != 10 {
-		fmt.Println("Current size doesn't match, expected 1, got: ", 
-			cache.CurrSize())
+	if cache.CurrSize() != cacheSize {
+		fmt.Println("Current size doesn't match, expected ", cacheSize,
+			", got: ", cache.CurrSize())
 		t.Fail()
 	}
 
-	if cache.RpcStore[0] != pair {
+	pairs := cache.Pairs()
+	if pairs[0] == pair || pairs[len(pairs) - 1] != last {
 		t.Fail()
 	}
 }
@@ -72,7 +88,14 @@ func TestWDCQuery(t *testing.T) {
 	setupWDC()
 
 	cache.AddReadPair(pair)
-	resPair := cache.Query(pair.Request)
+
+	//pairs that are still filling are not hits
+	if cache.Query("", pair.Request) != nil {
+		t.Fail()
+	}
+
+	pair.Complete()
+	resPair := cache.Query("", pair.Request)
 
 	//nil => not found
 	if resPair == nil {
@@ -84,16 +107,323 @@ func TestWDCQuery(t *testing.T) {
 	}
 }
 
+//clients with different block tokens share entries
+func TestWDCQueryIgnoresToken(t *testing.T) {
+	setupWDC()
+
+	request.BlockId = 4
+	request.AccessToken.Password = []byte{1, 2}
+	request.AccessToken.PasswordLength = 2
+	cache.AddReadPair(pair)
+	pair.Complete()
+
+	other := writables.NewReadBlockHeader()
+	other.BlockId = 4
+	other.AccessToken.Password = []byte{3, 4}
+	other.AccessToken.PasswordLength = 2
+
+	if cache.Query("", other) != pair {
+		t.Fail()
+	}
+
+	other.Timestamp = 1
+	if cache.Query("", other) != nil {
+		t.Fail()
+	}
+}
+
 func TestWDCAddBlockPacket(t *testing.T) {
 	setupWDC()
 
 	cache.AddReadPair(pair)
 	header := writables.NewBlockResponseHeader()
 	bp := writables.NewBlockPacket(header)
-	cache.AddBlockPacket(request, bp)
+	cache.AddBlockPacket("", request, bp)
 
-	resPair := cache.Query(pair.Request)
+	pair.Complete()
+	resPair := cache.Query("", pair.Request)
 	if resPair.ResponseSet.Chunks[0] != bp {
 		t.Fail()
 	}
-}
\ No newline at end of file
+}
+func TestWDCJoin(t *testing.T) {
+	setupWDC()
+
+	//the first reader of a block fills it
+	joined, filler := cache.Join("", request)
+	if !filler || joined == nil {
+		t.Fail()
+	}
+
+	if cache.CurrSize() != 1 {
+		t.Fail()
+	}
+
+	//everyone after that streams from the same pair
+	other, filler := cache.Join("", request)
+	if filler || other != joined {
+		t.Fail()
+	}
+
+	if cache.CurrSize() != 1 {
+		t.Fail()
+	}
+
+	//with the cache off, nobody shares
+	cache.Enabled = false
+	other, filler = cache.Join("", request)
+	if !filler || other == joined {
+		t.Fail()
+	}
+}
+
+//a failed fill is dropped and the next reader becomes the filler
+func TestWDCJoinFailed(t *testing.T) {
+	setupWDC()
+
+	joined, _ := cache.Join("", request)
+	joined.Fail()
+
+	other, filler := cache.Join("", request)
+	if !filler || other == joined {
+		t.Fail()
+	}
+
+	if cache.CurrSize() != 1 {
+		t.Fail()
+	}
+
+	cache.Remove(other)
+	if cache.CurrSize() != 0 {
+		t.Fail()
+	}
+}
+
+func TestWDCCompletePairs(t *testing.T) {
+	setupWDC()
+
+	filling, _ := cache.Join("", request)
+
+	other := writables.NewReadBlockHeader()
+	other.BlockId = 5
+	complete, _ := cache.Join("", other)
+	complete.Complete()
+
+	pairs := cache.CompletePairs()
+	if len(pairs) != 1 || pairs[0] != complete || pairs[0] == filling {
+		t.Fail()
+	}
+}
+
+//the same block read through two DataNodes is cached twice
+func TestWDCKeyedByDataNode(t *testing.T) {
+	setupWDC()
+
+	first, filler := cache.Join("10.0.0.1:50010", request)
+	if !filler {
+		t.Fail()
+	}
+
+	second, filler := cache.Join("10.0.0.2:50010", request)
+	if !filler || second == first || second.DataNode != "10.0.0.2:50010" {
+		t.Fail()
+	}
+
+	first.Complete()
+	if cache.Query("10.0.0.1:50010", request) != first {
+		t.Fail()
+	}
+
+	if cache.Query("10.0.0.2:50010", request) != nil {
+		t.Fail()
+	}
+}
+
+//eviction goes oldest first, whichever stripes the blocks are in
+func TestWDCEvictsOldest(t *testing.T) {
+	setupWDC()
+
+	pairs := make([]*writables.ReadPair, 0)
+	for i := 0; i < cacheSize+3; i++ {
+		r := writables.NewReadBlockHeader()
+		r.BlockId = uint64(i)
+		p, _ := cache.Join("", r)
+		p.Complete()
+		pairs = append(pairs, p)
+	}
+
+	if cache.CurrSize() != cacheSize {
+		t.FailNow()
+	}
+
+	for i := 0; i < len(pairs); i++ {
+		res := cache.Query("", pairs[i].Request)
+		if i < 3 && res != nil {
+			t.Fail()
+		}
+
+		if i >= 3 && res != pairs[i] {
+			t.Fail()
+		}
+	}
+
+	if cache.Pairs()[0] != pairs[3] {
+		t.Fail()
+	}
+}
+
+//readers of one block all end up on the same pair and the cache
+//stays within its size while many blocks come and go
+func TestWDCConcurrentJoin(t *testing.T) {
+	setupWDC()
+
+	var wg sync.WaitGroup
+	fillers := make(chan *writables.ReadPair, 1000)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				r := writables.NewReadBlockHeader()
+				r.BlockId = uint64(j)
+				p, filler := cache.Join("", r)
+				if filler {
+					fillers <- p
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(fillers)
+
+	if cache.CurrSize() > cacheSize {
+		t.Fail()
+	}
+
+	//every pair still in the cache was filled by exactly one reader
+	filled := make(map[*writables.ReadPair]bool)
+	for p := range fillers {
+		if filled[p] {
+			t.Fail()
+		}
+		filled[p] = true
+	}
+
+	pairs := cache.Pairs()
+	for i := 0; i < len(pairs); i++ {
+		if !filled[pairs[i]] {
+			t.Fail()
+		}
+	}
+}
+
+func TestWDCPin(t *testing.T) {
+	setupWDC()
+	cache.PinnedBytesLimit = 100
+
+	pinned, added := cache.Pin(0, 60)
+	if !pinned || !added || !cache.IsPinned(0) {
+		t.Fail()
+	}
+
+	//pinning twice is not charged twice
+	pinned, added = cache.Pin(0, 60)
+	if !pinned || added || cache.PinnedBytes() != 60 {
+		t.Fail()
+	}
+
+	//over the limit
+	pinned, _ = cache.Pin(1, 60)
+	if pinned || cache.IsPinned(1) {
+		t.Fail()
+	}
+
+	if !cache.Unpin(0) || cache.Unpin(0) || cache.PinnedBytes() != 0 {
+		t.Fail()
+	}
+}
+
+//pinned blocks stay while everything else is evicted around them
+func TestWDCPinnedNotEvicted(t *testing.T) {
+	setupWDC()
+	cache.PinnedBytesLimit = 100
+	cache.Pin(0, 10)
+
+	pinned, _ := cache.Join("", request)
+	pinned.Complete()
+
+	for i := 1; i < cacheSize*2; i++ {
+		r := writables.NewReadBlockHeader()
+		r.BlockId = uint64(i)
+		cache.Join("", r)
+	}
+
+	if cache.CurrSize() != cacheSize || cache.Query("", request) != pinned {
+		t.Fail()
+	}
+
+	//once unpinned it is the oldest again
+	cache.Unpin(0)
+	r := writables.NewReadBlockHeader()
+	r.BlockId = uint64(cacheSize*2)
+	cache.Join("", r)
+
+	if cache.Query("", request) != nil {
+		t.Fail()
+	}
+}
+
+//with everything pinned, the cache grows until something is unpinned
+func TestWDCAllPinned(t *testing.T) {
+	setupWDC()
+	cache.CacheSize = 1
+	cache.PinnedBytesLimit = 100
+	cache.Pin(1, 10)
+	cache.Pin(2, 10)
+
+	for i := 1; i <= 2; i++ {
+		r := writables.NewReadBlockHeader()
+		r.BlockId = uint64(i)
+		cache.Join("", r)
+	}
+
+	if cache.CurrSize() != 2 {
+		t.Fail()
+	}
+
+	cache.Unpin(1)
+	if cache.CurrSize() != 1 {
+		t.Fail()
+	}
+}
+
+func TestWDCEvictBlockAndClear(t *testing.T) {
+	c := NewWritableDataCache(10)
+	c.PinnedBytesLimit = 100
+	for i := 0; i < 3; i++ {
+		request := writables.NewReadBlockHeader()
+		request.BlockId = uint64(i % 2)
+		request.StartOffset = uint64(i)
+		c.Join("", request)
+	}
+	c.Pin(0, 10)
+
+	//both reads of block 0 go, and it is no longer pinned
+	if c.EvictBlock(0) != 2 || c.CurrSize() != 1 || c.IsPinned(0) {
+		t.Fail()
+	}
+
+	request := writables.NewReadBlockHeader()
+	if c.Query("", request) != nil {
+		t.Fail()
+	}
+
+	if c.Clear() != 1 || c.CurrSize() != 0 {
+		t.Fail()
+	}
+
+	c.SetEnabled(false)
+	if c.IsEnabled() {
+		t.Fail()
+	}
+}
@@ -12,27 +12,28 @@ import (
 )
 
 type GetFileInfoCache struct {
-	//past requests received by this cache
+	//past requests received by this cache (whether the
+	//cache is enabled is kept there too)
 	Cache *RequestCache
-
-	//set whether or the cache is enabled
-	enabled bool
 }
 
 //constructor
 func NewGetFileInfoCache(cache_size int) *GetFileInfoCache {
 	gf := GetFileInfoCache{}
 	gf.Cache =  NewRequestCache(cache_size)
-	gf.enabled = true
 	return &gf
 }
 
+func (gfi_cache *GetFileInfoCache) IsEnabled() bool {
+	return gfi_cache.Cache.IsEnabled()
+}
+
 func (gfi_cache *GetFileInfoCache) Disable() {
-	gfi_cache.enabled = false
+	gfi_cache.Cache.Disable()
+}
 
-	//we have to set the RequestCache to the correct
-	//value as well
-	gfi_cache.Cache.Enabled = false
+func (gfi_cache *GetFileInfoCache) Enable() {
+	gfi_cache.Cache.Enable()
 }
 
 //Query the cache. Returns nil if req is not found in the cache or the Enabled is set to 
@@ -44,7 +45,7 @@ func (gfi_cache *GetFileInfoCache) Query(
 	
 	util.DebugLogger.Println("in GetFileInfoCache.Query()")
 	//if the cache is not enabled, we keep returning nil
-	if !gfi_cache.enabled {
+	if !gfi_cache.IsEnabled() {
 		return nil
 	}
 
@@ -11,27 +11,28 @@ import (
 )
 
 type GetListingCache struct {
+	//(whether the cache is enabled is kept here too)
 	Cache *RequestCache
-
-	enabled bool
 }
 
 func NewGetListingCache(cacheSize int) *GetListingCache {
 	glc := GetListingCache{}
 	glc.Cache = NewRequestCache(cacheSize)
-	glc.enabled = true
 	return &glc
 }
 
 func (glc *GetListingCache) IsEnabled() bool {
-	return glc.enabled
+	return glc.Cache.IsEnabled()
 }
 
 func (glc *GetListingCache) Disable() {
-	glc.enabled = false
 	glc.Cache.Disable()
 }
 
+func (glc *GetListingCache) Enable() {
+	glc.Cache.Enable()
+}
+
 
 func (glc *GetListingCache) Query(req namenode_rpc.ReqPacket) namenode_rpc.ResponsePacket {
 	//if the cache is not enabled, we keep returning nil
@@ -62,6 +62,20 @@ func (rc *RequestCache) Disable() {
 	rc.Enabled = false
 }
 
+func (rc *RequestCache) Enable() {
+	rc.Lock()
+	defer rc.Unlock()
+
+	rc.Enabled = true
+}
+
+func (rc *RequestCache) IsEnabled() bool {
+	rc.RLock()
+	defer rc.RUnlock()
+
+	return rc.Enabled
+}
+
 //this a private method because it assumes that the mutex has already 
 //been locked
 func (rc *RequestCache) add(rp namenode_rpc.ReqPacket, 
@@ -139,6 +153,89 @@ func (rc *RequestCache) Clear() {
 	rc.Lock()
 	defer rc.Unlock()
 	rc.RequestResponse = make(map[PacketNumber](namenode_rpc.PacketPair))
+	rc.PacketNumbers = nil
+}
+
+//returns the number of request-response pairs in the cache
+func (rc *RequestCache) Len() int {
+	rc.RLock()
+	defer rc.RUnlock()
+	return len(rc.RequestResponse)
+}
+
+//drops every pair whose request is about path (i.e. has path
+//as its first parameter, as getFileInfo and getListing do).
+//Returns the number of pairs dropped.
+func (rc *RequestCache) RemovePath(path string) int {
+	rc.Lock()
+	defer rc.Unlock()
+
+	removed := 0
+	for packetNum, pair := range rc.RequestResponse {
+		req, ok := pair.Request.(*namenode_rpc.RequestPacket)
+		if !ok || len(req.Parameters) == 0 {
+			continue
+		}
+
+		if string(req.GetParameter(0).Value) == path {
+			delete(rc.RequestResponse, packetNum)
+			removed++
+		}
+	}
+
+	//keep the order of the packet numbers that are left
+	packetNumbers := make([]PacketNumber, 0, len(rc.RequestResponse))
+	for i := 0; i < len(rc.PacketNumbers); i++ {
+		_, present := rc.RequestResponse[rc.PacketNumbers[i]]
+		if present {
+			packetNumbers = append(packetNumbers, rc.PacketNumbers[i])
+		}
+	}
+	rc.PacketNumbers = packetNumbers
+
+	return removed
+}
+
+//what is known about one request-response pair in a RequestCache
+type RequestCacheEntry struct {
+	PacketNumber PacketNumber
+
+	//RPC method of the request and its first parameter (the path
+	//for getFileInfo and getListing); empty if there is no request
+	Method string
+	Path string
+
+	//false if only the request has been seen so far
+	HasResponse bool
+}
+
+//describes the pairs in the cache, least recently added first
+func (rc *RequestCache) Entries() []RequestCacheEntry {
+	rc.RLock()
+	defer rc.RUnlock()
+
+	res := make([]RequestCacheEntry, 0, len(rc.RequestResponse))
+	for i := 0; i < len(rc.PacketNumbers); i++ {
+		packetNum := rc.PacketNumbers[i]
+		pair, present := rc.RequestResponse[packetNum]
+		if !present {
+			continue
+		}
+
+		entry := RequestCacheEntry{PacketNumber: packetNum,
+			HasResponse: pair.Response != nil}
+		req, ok := pair.Request.(*namenode_rpc.RequestPacket)
+		if ok {
+			entry.Method = string(req.MethodName)
+			if len(req.Parameters) > 0 {
+				entry.Path = string(req.GetParameter(0).Value)
+			}
+		}
+
+		res = append(res, entry)
+	}
+
+	return res
 }
 
 func (rc *RequestCache) Query(rp namenode_rpc.ReqPacket) 
@@ -202,3 +202,68 @@ func TestRequestCacheQueryCustom (t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRequestCacheRemovePath(t *testing.T) {
+	rc := NewRequestCache(5)
+	paths := []string{"/a", "/b", "/a"}
+	for i := 0; i < len(paths); i++ {
+		rp := namenode_rpc.NewRequestPacket()
+		rp.PacketNumber = uint32(i)
+		rp.Parameters = []namenode_rpc.Parameter{*namenode_rpc.NewParameter()}
+		rp.Parameters[0].Value = []byte(paths[i])
+
+		resp := namenode_rpc.NewGetFileInfoResponse()
+		resp.PacketNumber = uint32(i)
+		rc.Add(rp, resp)
+	}
+
+	//a request with no parameters is left alone
+	rp := namenode_rpc.NewRequestPacket()
+	rp.PacketNumber = 3
+	rc.AddRequest(rp)
+
+	if rc.RemovePath("/a") != 2 || rc.Len() != 2 {
+		t.Fail()
+	}
+
+	if !reflect.DeepEqual(rc.PacketNumbers, []PacketNumber{1, 3}) {
+		t.Fail()
+	}
+}
+
+func TestRequestCacheEnable(t *testing.T) {
+	rc := NewRequestCache(3)
+	rc.Disable()
+	if rc.IsEnabled() {
+		t.Fail()
+	}
+
+	rc.Enable()
+	if !rc.IsEnabled() {
+		t.Fail()
+	}
+}
+
+func TestRequestCacheEntries(t *testing.T) {
+	rc := NewRequestCache(5)
+	rp := namenode_rpc.NewRequestPacket()
+	rp.PacketNumber = 4
+	rp.MethodName = []byte("getFileInfo")
+	rp.Parameters = []namenode_rpc.Parameter{*namenode_rpc.NewParameter()}
+	rp.Parameters[0].Value = []byte("/a")
+	resp := namenode_rpc.NewGetFileInfoResponse()
+	resp.PacketNumber = 4
+	rc.Add(rp, resp)
+
+	rp = namenode_rpc.NewRequestPacket()
+	rp.PacketNumber = 2
+	rc.AddRequest(rp)
+
+	expected := []RequestCacheEntry{
+		RequestCacheEntry{PacketNumber: 4, Method: "getFileInfo", Path: "/a",
+			HasResponse: true},
+		RequestCacheEntry{PacketNumber: 2}}
+	if !reflect.DeepEqual(rc.Entries(), expected) {
+		t.Fail()
+	}
+}
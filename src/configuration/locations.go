@@ -41,6 +41,20 @@ type Port string
 
 type DataNodeMap map[Port]*DataNodeLocation
 
+//DataNodes register with the NameNode through us, so the names
+//clients are given for them may be our own relay ports. Returns
+//the address of the DataNode behind name in that case and name
+//itself otherwise.
+func (m DataNodeMap) Resolve(name string) string {
+	pieces := strings.Split(name, ":")
+	location, present := m[Port(pieces[len(pieces)-1])]
+	if !present {
+		return name
+	}
+
+	return location.Address()
+}
+
 //makes a map out of the given port offeset and locations
 func MakeDataNodeMap(dnls []*DataNodeLocation, portOffset int) DataNodeMap {
 	res := make(DataNodeMap)
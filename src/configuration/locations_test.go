@@ -65,3 +65,16 @@ func TestDataNodeLocationAddress (t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDataNodeMapResolve(t *testing.T) {
+	dnl := NewDataNodeLocation("10.0.0.5", "50010")
+	dnm := MakeDataNodeMap([]*DataNodeLocation{dnl}, 1389)
+
+	if dnm.Resolve("127.0.0.1:1389") != "10.0.0.5:50010" {
+		t.Fail()
+	}
+
+	if dnm.Resolve("10.0.0.6:50010") != "10.0.0.6:50010" {
+		t.Fail()
+	}
+}
@@ -21,6 +21,64 @@ type Configuration struct {
 	//states the port number on which to run the cache_info_server
 	//instance
 	CacheInfoPort string
+
+	//number of block reads the data cache (shared by all of 
+	//the DataNodes) holds (0 means main's default)
+	DataCacheSize int
+
+	//most bytes of blocks that the scheduler may pin in 
+	//the data cache at once (0 => no pinning)
+	PinnedBytesLimit int64
+
+	//number of seconds between two passes of the data cache
+	//scrubber (0 turns scrubbing off)
+	ScrubInterval int
+
+	//number of blocks of a file to read ahead of a client
+	//that is reading it sequentially (0 turns read-ahead off)
+	PrefetchDepth int
+
+	//bytes/second that read-ahead may take from each DataNode 
+	//(0 means no limit)
+	PrefetchBandwidth int
+
+	//put blocks that are written through Panthera in the
+	//data cache once the write pipeline acknowledges them
+	WriteThroughCache bool
+
+	//number of OP_BLOCK_CHECKSUM responses to remember
+	//(0 turns off caching them)
+	ChecksumCacheSize int
+
+	//milliseconds a client connection to the data port may sit 
+	//idle between two operations before it is closed (0 means 
+	//the default of writable_processor.DEFAULT_IDLE_TIMEOUT)
+	IdleTimeout int
+
+	//failed connections in a row after which a DataNode is 
+	//skipped for BreakerCooldown seconds (0 means the defaults
+	//in writable_processor)
+	BreakerThreshold int
+	BreakerCooldown int
+
+	//check the block token of a client against the keys the
+	//NameNode gave out before serving it cached data (clients
+	//that fail the check are passed on to the DataNode)
+	ValidateBlockTokens bool
+
+	//secret that clients of the cache info server have to log
+	//in with before they may evict, clear or turn off caches
+	//(empty turns those requests off)
+	AdminSecret string
+
+	//key shared with the scheduler; clients of the cache info
+	//server have to prove they know it before any request is
+	//answered (empty turns authentication off)
+	CacheInfoKey string
+
+	//host:port of the HTTP admin server (see admin_server); it
+	//is only started if this is set. Its actions need AdminSecret.
+	AdminHttpAddress string
 }
 
 //constructor for the configuration object
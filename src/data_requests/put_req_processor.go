@@ -45,46 +45,57 @@ func NewPutRequestProcessor(id int64) *PutRequestProcessor {
 }
 
 //reads from the client, forwards to the server
+//(writables-aware relaying of writes is done by 
+//writable_processor; this is only a byte-level relay)
 func (p *PutRequestProcessor) HandleConnection(conn net.Conn, dataNode net.Conn) {
-	utill.DebugLogger.Println("HANDLING CONNECTION FROM PutRequestProcessor")
+	util.DebugLogger.Println("HANDLING CONNECTION FROM PutRequestProcessor")
+
+	//once either side is gone, the write is over
+	defer conn.Close()
+	defer dataNode.Close()
+
 	for {
 		buf := make([]byte, 1024)
 		bytesRead, err := conn.Read(buf)
-		if err != nil {
-			//we can't really do anything if there's an error: just gotta keep going
-			util.DebugLogger.Println("Error occurred while reading in 
-			PutRequestProcessor.HandleConnection: ", err)
-			continue
-		}
 
-		//truncate the buffer to the correct index
+		//forward whatever was read before looking at the error
 		buf = buf[0:bytesRead]
-		_, err = dataNode.Write(buf)
+		if bytesRead > 0 {
+			_, writeErr := dataNode.Write(buf)
+			if writeErr != nil {
+				util.DebugLogger.Println("Errror occurred while writing in PutRequestProcessor.HandleConnection: ", writeErr)
+				return
+			}
+		}
+
 		if err != nil {
-			util.DebugLogger.Println("Errror occurred while writing in 
-			PutRequestProcessor.HandleConnection: ", err)
+			util.DebugLogger.Println("Error occurred while reading in PutRequestProcessor.HandleConnection: ", err)
+			return
 		}
 	}
 }
 
 //reads from the server forwards to the client
 func (p *PutRequestProcessor) HandleHDFS(conn net.Conn, dataNode net.Conn) {
+	defer conn.Close()
+	defer dataNode.Close()
+
 	for {
 		buf := make([]byte, 1024)
 		bytesRead, err := dataNode.Read(buf)
 
-		if err != nil {
-			util.DebugLogger.Println("Error occurred while reading in
-			 PutRequestProcessor.HandleHDFS: ", err)
-			continue
+		buf = buf[0:bytesRead]
+		if bytesRead > 0 {
+			_, writeErr := conn.Write(buf)
+			if writeErr != nil {
+				util.DebugLogger.Println("Error occurred while writing in PutRequestProcessor.HandleHDFS", writeErr)
+				return
+			}
 		}
 
-		buf = buf[0:bytesRead]
-		_, err = conn.Write(buf)
 		if err != nil {
-			util.DebugLogger.Println("Error occurred while writing in 
-			PutRequestProcessor.HandleHDFS", err)
-			continue
+			util.DebugLogger.Println("Error occurred while reading in PutRequestProcessor.HandleHDFS: ", err)
+			return
 		}
 	}
 }
@@ -15,6 +15,7 @@ import (
 	"io"
 	"encoding/hex"
 	"reflect"
+	"errors"
 
 	//used for the the DataNodeMap
 	"configuration"
@@ -810,6 +811,16 @@ func (p *Processor) preprocessRegistrationResponse(genericResp *namenode_rpc.Gen
 	fmt.Println("Writable: ", writable)
 	fmt.Println("StrWrita: ", string(writable))
 
+	//the NameNode's answer carries the block keys that it
+	//signs block tokens with
+	nameNodeRegistration := writables.NewDataNodeRegistration()
+	err := nameNodeRegistration.ReadWithoutName(bytes.NewBuffer(writable))
+	if err != nil {
+		util.DebugLogger.Println("Could not decode registration response: ", err)
+	} else {
+		p.recordBlockKeys(nameNodeRegistration)
+	}
+
 	//testing method code
 	genericResp.ParameterValue = []byte("127.0.0.1:1389")
 	genericResp.ParameterLength = uint16(len(genericResp.ParameterValue))
@@ -820,7 +831,7 @@ func (p *Processor) preprocessRegistrationResponse(genericResp *namenode_rpc.Gen
 	//set the storageid to the local value
 	p.dataNodeRegistration.StorageID = 
 	"DS-2096826136-127.0.1.1-1389-1395205739838"
-	err := p.dataNodeRegistration.WriteWithoutName(resDiffBuffer)
+	err = p.dataNodeRegistration.WriteWithoutName(resDiffBuffer)
 	if err != nil {
 		util.DebugLogger.Println("Failed to preprocess registration response.")
 		return nil
@@ -885,6 +896,64 @@ func (p *Processor) preprocessLocatedBlocks(
 	return resPacket
 }
 
+//decodes the writables.LocatedBlocks carried by a getBlockLocations response
+func decodeLocatedBlocks(
+	genericResp *namenode_rpc.GenericResponsePacket) (*writables.LocatedBlocks, error) {
+	//total byte structure
+	loadedBytes := genericResp.GetBuf()
+
+	//only the packet parameters
+	packetBytes := genericResp.Bytes()
+	if len(packetBytes) > len(loadedBytes) {
+		return nil, errors.New("Response is shorter than its parameters.")
+	}
+
+	//see preprocessLocatedBlocks() for the two-byte adjustment
+	dataBytes := loadedBytes[len(packetBytes):]
+	dataBytes = append([]byte{0, 0}, dataBytes...)
+
+	locatedBlocks := writables.NewLocatedBlocks()
+	err := locatedBlocks.Read(bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return locatedBlocks, nil
+}
+
+//remembers the block keys in registration (if any) so that
+//the data layer can check block tokens. Only registrations
+//coming from the NameNode should be passed in; the ones that
+//DataNodes send carry dummy keys.
+func (p *Processor) recordBlockKeys(
+	registration *writables.DataNodeRegistration) {
+	if p.cacheSet.BlockKeys == nil || registration.Keys == nil {
+		return
+	}
+
+	p.cacheSet.BlockKeys.Update(registration.Keys)
+}
+
+//records the blocks of the file that the current getBlockLocations
+//request asked about in the cacheSet's BlockIndex (used by the data
+//layer, e.g. for read-ahead)
+func (p *Processor) recordLocatedBlocks(
+	genericResp *namenode_rpc.GenericResponsePacket) {
+	if p.currentRequest == nil || p.cacheSet.BlockIndex == nil ||
+	string(p.currentRequest.MethodName) != "getBlockLocations" {
+		return
+	}
+
+	locatedBlocks, err := decodeLocatedBlocks(genericResp)
+	if err != nil {
+		util.DebugLogger.Println("Could not decode LocatedBlocks: ", err)
+		return
+	}
+
+	path := string(p.currentRequest.GetParameter(0).Value)
+	p.cacheSet.BlockIndex.Add(path, locatedBlocks)
+}
+
 //preprocess HDFS responses. Calls other methods depending on the type
 //of modification needed.
 func (p *Processor) preprocessHDFS(genericResp *namenode_rpc.GenericResponsePacket) *namenode_rpc.GenericResponsePacket {
@@ -897,6 +966,7 @@ func (p *Processor) preprocessHDFS(genericResp *namenode_rpc.GenericResponsePack
 		fmt.Println(hex.Dump(genericResp.LoadedBytes()))
 	} else if string(genericResp.ObjectName1) == "org.apache.hadoop.hdfs.protocol.LocatedBlocks" {
 		//genericResp = p.preprocessLocatedBlocks(genericResp)
+		p.recordLocatedBlocks(genericResp)
 
 	} else {
 		fmt.Println("Did not have to preprocess registration response.")
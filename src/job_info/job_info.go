@@ -14,7 +14,6 @@ import (
 
 	//local imports
 	"cache_protocol"
-	"namenode_rpc"
 )
 
 /**
@@ -22,7 +21,7 @@ import (
 * 
 *{
 *	JobName: "wordcount"
-* FilesAccessed: "gutenberg/*"
+* FilesAccessed: ["gutenberg/*"]
 *}
 */
 
@@ -30,16 +29,27 @@ type JobInfo struct {
 	Name string
 	BlocksAccessed []uint64
 
+	//HDFS paths (or globs) of the files the job reads; relative
+	//ones are relative to the working directory of the
+	//BlockResolver. Their blocks are added to BlocksAccessed by
+	//GetBlocksAccessed().
+	FilesAccessed []string `json:",omitempty"`
+
 	//shell command used to execute
 	//this job
-	CommandPath string
-	CommandArgs []string
+	CommandPath string `json:",omitempty"`
+	CommandArgs []string `json:",omitempty"`
 
 	//the directory in which to execute Command
-	ExecutionDir string
+	ExecutionDir string `json:",omitempty"`
 
 	//not written or accessed from the JSON
 	blocksAccessed []*cache_protocol.BlockDescription
+
+	//the blocks that GetBlocksAccessed() added to BlocksAccessed
+	//from FilesAccessed (and takes out again before resolving
+	//them anew)
+	blocksResolved map[uint64]bool
 }
 
 func NewJobInfo() *JobInfo {
@@ -95,20 +105,45 @@ func (j *JobInfo) ReadFromFile(filepath string) error {
 	return j.Read(buf)
 }
 
-//create a getBlockLocations request
-func (j *JobInfo) getBlockLocations() {
-	r := namenode_rpc.NewRequestPacket()
-	r.PacketNumber = 2
-
-}
-
 //This is probably the most complicated method in this package.
 //Though the JSON file specifies what files will be needed, it
 //does not specify what blocks will be needed, so we have
-//to translate the files list into a block list
-func (j *JobInfo) GetBlocksAccessed() {
-	//don't need the implementation yet, because currently the configuration
-	//with block ids, not filenames
+//to translate the files list into a block list. BlocksAccessed
+//ends up with the blocks listed in the JSON followed by those of
+//FilesAccessed (once each).
+func (j *JobInfo) GetBlocksAccessed(resolver BlockResolver) error {
+	//the blocks listed in the JSON are those that an earlier
+	//call did not resolve
+	res := make([]uint64, 0, len(j.BlocksAccessed))
+	seen := make(map[uint64]bool)
+	for _, blockId := range j.BlocksAccessed {
+		if !seen[blockId] && !j.blocksResolved[blockId] {
+			seen[blockId] = true
+			res = append(res, blockId)
+		}
+	}
+
+	resolvedSet := make(map[uint64]bool)
+
+	if len(j.FilesAccessed) > 0 {
+		resolved, err := resolver.ResolveBlocks(j.FilesAccessed)
+		if err != nil {
+			return err
+		}
+
+		for _, blockId := range resolved {
+			if !seen[blockId] {
+				seen[blockId] = true
+				resolvedSet[blockId] = true
+				res = append(res, blockId)
+			}
+		}
+	}
+
+	j.BlocksAccessed = res
+	j.blocksResolved = resolvedSet
+	j.SetBlockDescriptions()
+	return nil
 }
 
 func (j *JobInfo) ScoreCacheInfo(cacheInfo *cache_protocol.CacheInfo) float64 {
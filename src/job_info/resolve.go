@@ -0,0 +1,277 @@
+package job_info
+
+/*
+* Turning the FilesAccessed of a JobInfo (paths and globs, like
+* "gutenberg/*") into the ids of the blocks the job will read.
+* A BlockResolver does the work, either by asking the NameNode
+* (NameNodeResolver) or the caches, which know the files they
+* have seen getBlockLocations for; a RoundResolver remembers
+* what was resolved for the length of a scheduling round.
+*/
+
+import (
+	//golang imports
+	"path"
+	"strings"
+	"sync"
+
+	//local imports
+	"writables"
+)
+
+type BlockResolver interface {
+	//the ids of the blocks of the files matching globs
+	ResolveBlocks(globs []string) ([]uint64, error)
+}
+
+/**
+* NameNodeResolver
+* Expands globs with getListing and resolves the files they
+* match with getBlockLocations
+*/
+
+//the calls of namenode_rpc.Client that NameNodeResolver needs
+type NameNode interface {
+	GetFileInfo(path string) (*writables.HdfsFileStatus, error)
+	GetListing(path string, startAfter []byte) (
+		*writables.DirectoryListing, error)
+	GetBlockLocations(path string, offset int64, length int64) (
+		*writables.LocatedBlocks, error)
+}
+
+type NameNodeResolver struct {
+	NameNode NameNode
+
+	//what relative paths are relative to (e.g. /user/hduser)
+	WorkingDir string
+}
+
+func NewNameNodeResolver(nameNode NameNode,
+	workingDir string) *NameNodeResolver {
+	n := NameNodeResolver{NameNode: nameNode, WorkingDir: workingDir}
+	return &n
+}
+
+//a file or directory found while expanding a glob
+type matchedPath struct {
+	path string
+	status *writables.HdfsFileStatus
+}
+
+func hasGlob(component string) bool {
+	return strings.ContainsAny(component, "*?[\\")
+}
+
+//files that MapReduce leaves out of its input (_SUCCESS,
+//_logs, .crc files and so on)
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".")
+}
+
+//the whole of the directory at dir
+func (n *NameNodeResolver) list(dir string) ([]*writables.HdfsFileStatus,
+	error) {
+	res := make([]*writables.HdfsFileStatus, 0)
+	startAfter := []byte{}
+	for {
+		listing, err := n.NameNode.GetListing(dir, startAfter)
+		if err != nil || listing == nil {
+			return res, err
+		}
+
+		res = append(res, listing.PartialListing...)
+		if listing.RemainingEntries == 0 || len(listing.PartialListing) == 0 {
+			return res, nil
+		}
+		startAfter = listing.LastName()
+	}
+}
+
+//the files and directories that glob matches, in order
+func (n *NameNodeResolver) expand(glob string) ([]matchedPath, error) {
+	if !path.IsAbs(glob) {
+		glob = path.Join(n.WorkingDir, glob)
+	}
+
+	//path.Match only reports a bad pattern once it gets
+	//far enough into a name to notice
+	_, err := path.Match(glob, "")
+	if err != nil {
+		return nil, err
+	}
+
+	//expand the glob one component at a time, listing only
+	//the directories a component with a glob in it is under
+	current := []matchedPath{matchedPath{path: "/"}}
+	components := strings.Split(strings.Trim(path.Clean(glob), "/"), "/")
+	for _, component := range components {
+		if component == "" {
+			continue
+		}
+
+		next := make([]matchedPath, 0)
+		for _, parent := range current {
+			if parent.status != nil && !parent.status.IsDir {
+				continue
+			}
+
+			if !hasGlob(component) {
+				next = append(next,
+					matchedPath{path: path.Join(parent.path, component)})
+				continue
+			}
+
+			entries, err := n.list(parent.path)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entry := range entries {
+				name := string(entry.Path)
+				matched, _ := path.Match(component, name)
+				if matched {
+					next = append(next, matchedPath{
+						path: path.Join(parent.path, name), status: entry})
+				}
+			}
+		}
+		current = next
+	}
+
+	//paths without globs in their last component have not been
+	//looked at yet
+	res := make([]matchedPath, 0, len(current))
+	for _, match := range current {
+		if match.status == nil {
+			match.status, err = n.NameNode.GetFileInfo(match.path)
+			if err != nil {
+				return nil, err
+			}
+
+			if match.status == nil {
+				continue
+			}
+		}
+		res = append(res, match)
+	}
+
+	return res, nil
+}
+
+//the files that match stands for: itself, or the files (but
+//not the subdirectories) in it if it is a directory
+func (n *NameNodeResolver) files(match matchedPath) ([]matchedPath, error) {
+	if !match.status.IsDir {
+		return []matchedPath{match}, nil
+	}
+
+	entries, err := n.list(match.path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]matchedPath, 0)
+	for _, entry := range entries {
+		name := string(entry.Path)
+		if entry.IsDir || isHidden(name) {
+			continue
+		}
+		res = append(res, matchedPath{path: path.Join(match.path, name),
+			status: entry})
+	}
+
+	return res, nil
+}
+
+func (n *NameNodeResolver) ResolveBlocks(globs []string) ([]uint64, error) {
+	res := make([]uint64, 0)
+	seen := make(map[string]bool)
+	for _, glob := range globs {
+		matches, err := n.expand(glob)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			files, err := n.files(match)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, file := range files {
+				if seen[file.path] {
+					continue
+				}
+				seen[file.path] = true
+
+				locatedBlocks, err := n.NameNode.GetBlockLocations(file.path, 0,
+					int64(file.status.Length))
+				if err != nil {
+					return nil, err
+				}
+
+				if locatedBlocks == nil {
+					continue
+				}
+
+				for _, block := range locatedBlocks.LocatedBlockArr {
+					res = append(res, block.B.BlockId)
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+/**
+* RoundResolver
+* Resolves each glob once per scheduling round, however many
+* jobs ask for it
+*/
+
+type RoundResolver struct {
+	sync.Mutex
+
+	Resolver BlockResolver
+
+	//glob => the blocks it resolved to this round
+	resolved map[string][]uint64
+}
+
+func NewRoundResolver(resolver BlockResolver) *RoundResolver {
+	r := RoundResolver{Resolver: resolver}
+	r.resolved = make(map[string][]uint64)
+	return &r
+}
+
+//forgets what was resolved, so that files that have been written
+//or removed since are picked up
+func (r *RoundResolver) NextRound() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.resolved = make(map[string][]uint64)
+}
+
+func (r *RoundResolver) ResolveBlocks(globs []string) ([]uint64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	res := make([]uint64, 0)
+	for _, glob := range globs {
+		blockIds, present := r.resolved[glob]
+		if !present {
+			var err error
+			blockIds, err = r.Resolver.ResolveBlocks([]string{glob})
+			if err != nil {
+				return nil, err
+			}
+			r.resolved[glob] = blockIds
+		}
+
+		res = append(res, blockIds...)
+	}
+
+	return res, nil
+}
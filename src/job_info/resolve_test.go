@@ -0,0 +1,219 @@
+package job_info
+
+import (
+	//golang imports
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	//local imports
+	"writables"
+)
+
+//a NameNode that holds files (path => block ids) and the
+//directories above them; lists at most two entries at a time
+type fakeNameNode struct {
+	files map[string][]uint64
+	calls map[string]int
+}
+
+func newFakeNameNode(files map[string][]uint64) *fakeNameNode {
+	f := fakeNameNode{files: files, calls: make(map[string]int)}
+	return &f
+}
+
+func (f *fakeNameNode) isDir(p string) bool {
+	for file := range f.files {
+		if strings.HasPrefix(file, strings.TrimSuffix(p, "/") + "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeNameNode) status(p string) *writables.HdfsFileStatus {
+	h := writables.NewHdfsFileStatus()
+	h.Path = []byte(path.Base(p))
+	h.IsDir = f.isDir(p)
+	h.Length = uint64(len(f.files[p]))
+	return h
+}
+
+func (f *fakeNameNode) GetFileInfo(p string) (*writables.HdfsFileStatus,
+	error) {
+	f.calls["getFileInfo"]++
+	_, present := f.files[p]
+	if !present && !f.isDir(p) {
+		return nil, nil
+	}
+	return f.status(p), nil
+}
+
+func (f *fakeNameNode) GetListing(dir string, startAfter []byte) (
+	*writables.DirectoryListing, error) {
+	f.calls["getListing"]++
+	if !f.isDir(dir) {
+		return nil, nil
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	names := make(map[string]bool)
+	for file := range f.files {
+		if strings.HasPrefix(file, prefix) {
+			names[strings.Split(file[len(prefix):], "/")[0]] = true
+		}
+	}
+
+	sorted := make([]string, 0)
+	for name := range names {
+		if name > string(startAfter) {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	listing := writables.NewDirectoryListing()
+	for i := 0; i < len(sorted) && i < 2; i++ {
+		listing.PartialListing = append(listing.PartialListing,
+			f.status(prefix + sorted[i]))
+	}
+	listing.RemainingEntries = uint32(len(sorted) - len(listing.PartialListing))
+	return listing, nil
+}
+
+func (f *fakeNameNode) GetBlockLocations(p string, offset int64,
+	length int64) (*writables.LocatedBlocks, error) {
+	f.calls["getBlockLocations"]++
+	blockIds, present := f.files[p]
+	if !present {
+		return nil, nil
+	}
+
+	res := writables.NewLocatedBlocks()
+	for _, blockId := range blockIds {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = blockId
+		res.LocatedBlockArr = append(res.LocatedBlockArr, block)
+	}
+	res.NumberOfBlocks = uint32(len(blockIds))
+	return res, nil
+}
+
+var FAKE_FILES = map[string][]uint64{
+	"/user/hduser/gutenberg/a.txt": []uint64{1, 2},
+	"/user/hduser/gutenberg/b.txt": []uint64{3},
+	"/user/hduser/gutenberg/c.dat": []uint64{4},
+	"/user/hduser/gutenberg/_SUCCESS": []uint64{},
+	"/user/hduser/gutenberg/sub/d.txt": []uint64{5},
+	"/data/x/part-0": []uint64{6},
+	"/data/y/part-0": []uint64{7},
+}
+
+func TestNameNodeResolver(t *testing.T) {
+	resolver := NewNameNodeResolver(newFakeNameNode(FAKE_FILES),
+		"/user/hduser")
+
+	cases := []struct {
+		globs []string
+		expected []uint64
+	}{
+		{[]string{"gutenberg/*.txt"}, []uint64{1, 2, 3}},
+		{[]string{"/user/hduser/gutenberg/c.dat"}, []uint64{4}},
+
+		//a directory stands for the files in it
+		{[]string{"gutenberg"}, []uint64{1, 2, 3, 4}},
+		{[]string{"/data/*/part-*"}, []uint64{6, 7}},
+		{[]string{"/data/[x]/part-0", "/data/x/*"}, []uint64{6}},
+		{[]string{"/missing/*", "nothing"}, []uint64{}},
+	}
+
+	for _, c := range cases {
+		res, err := resolver.ResolveBlocks(c.globs)
+		if err != nil || !reflect.DeepEqual(res, c.expected) {
+			t.Fatal("Resolved ", c.globs, " to ", res, ", err: ", err)
+		}
+	}
+
+	_, err := resolver.ResolveBlocks([]string{"/data/["})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestRoundResolver(t *testing.T) {
+	nameNode := newFakeNameNode(FAKE_FILES)
+	resolver := NewRoundResolver(NewNameNodeResolver(nameNode,
+		"/user/hduser"))
+
+	for i := 0; i < 3; i++ {
+		res, err := resolver.ResolveBlocks([]string{"/data/*/part-0"})
+		if err != nil || !reflect.DeepEqual(res, []uint64{6, 7}) {
+			t.Fatal(res, err)
+		}
+	}
+
+	if nameNode.calls["getBlockLocations"] != 2 {
+		t.Fatal("Resolved more than once: ", nameNode.calls)
+	}
+
+	resolver.NextRound()
+	resolver.ResolveBlocks([]string{"/data/*/part-0"})
+	if nameNode.calls["getBlockLocations"] != 4 {
+		t.Fatal("Not resolved again: ", nameNode.calls)
+	}
+}
+
+func TestGetBlocksAccessed(t *testing.T) {
+	jobInfo := NewJobInfo()
+	err := jobInfo.Read([]byte(`{"Name": "wordcount",
+		"BlocksAccessed": [9, 3],
+		"FilesAccessed": ["gutenberg/*.txt"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewRoundResolver(NewNameNodeResolver(
+		newFakeNameNode(FAKE_FILES), "/user/hduser"))
+	for i := 0; i < 2; i++ {
+		err = jobInfo.GetBlocksAccessed(resolver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(jobInfo.BlocksAccessed, []uint64{9, 3, 1, 2}) {
+			t.Fatal("Wrong blocks: ", jobInfo.BlocksAccessed)
+		}
+
+		if len(jobInfo.blocksAccessed) != 4 ||
+		jobInfo.blocksAccessed[2].BlockId != 1 {
+			t.Fail()
+		}
+	}
+}
+
+func TestGetBlocksAccessedRemoved(t *testing.T) {
+	jobInfo := NewJobInfo()
+	jobInfo.BlocksAccessed = []uint64{9}
+	jobInfo.FilesAccessed = []string{"/data/*/part-0"}
+
+	files := map[string][]uint64{"/data/x/part-0": []uint64{6},
+		"/data/y/part-0": []uint64{7}}
+	err := jobInfo.GetBlocksAccessed(NewNameNodeResolver(
+		newFakeNameNode(files), "/"))
+	if err != nil || !reflect.DeepEqual(jobInfo.BlocksAccessed,
+		[]uint64{9, 6, 7}) {
+		t.Fatal(jobInfo.BlocksAccessed, err)
+	}
+
+	//the blocks of a file that is gone are dropped, those
+	//listed in the JSON kept
+	delete(files, "/data/y/part-0")
+	err = jobInfo.GetBlocksAccessed(NewNameNodeResolver(
+		newFakeNameNode(files), "/"))
+	if err != nil || !reflect.DeepEqual(jobInfo.BlocksAccessed,
+		[]uint64{9, 6}) {
+		t.Fatal(jobInfo.BlocksAccessed, err)
+	}
+}
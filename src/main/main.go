@@ -11,6 +11,7 @@ import (
 	//"data_requests"
 	"writable_processor"
 	"cache_info_server"
+	"admin_server"
 	"time"
 	"configuration"
 	"runtime/pprof"
@@ -23,6 +24,13 @@ import (
 var config *configuration.Configuration;
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
+//the client connections being relayed (see admin_server)
+var sessions = admin_server.NewSessionList()
+
+//number of block reads kept in the data cache unless
+//the configuration says otherwise
+var DEFAULT_DATA_CACHE_SIZE = 15
+
 //main reactor function called by main
 func loop(server net.Listener, caches *caches.CacheSet, 
 dnMap *configuration.DataNodeMap) {
@@ -63,26 +71,91 @@ dnMap *configuration.DataNodeMap) {
 		//create new process and process the connected client
 		//pass it the caches that are currently initialized
 		processor := hdfs_requests.NewProcessor(eventChannel, caches, dnMap)
-		go processor.HandleConnectionReimp(conn, hdfs);
+		session := sessions.Add(admin_server.SESSION_NAMENODE, 
+			conn.RemoteAddr().String(), hdfs.RemoteAddr().String())
+		go func() {
+			defer sessions.Remove(session)
+			processor.HandleConnectionReimp(conn, hdfs);
+		}()
 		go processor.HandleHDFS(conn, hdfs);
 	}
 }
 
 //create an run an instance of cache_info_server
-func startCacheInfoServer(dataCache *caches.WritableDataCache) {
+func startCacheInfoServer(dataCache *caches.WritableDataCache,
+	cacheSet *caches.CacheSet, 
+	prefetchers *writable_processor.PrefetcherSet) {
 	port := config.CacheInfoPort
 	server := cache_info_server.NewCacheInfoServer(port, dataCache)
+	server.BlockIndex = cacheSet.BlockIndex
+	server.Fetcher = prefetchers
+	server.Caches = cacheSet
+	server.AdminSecret = []byte(config.AdminSecret)
+	server.AuthKey = []byte(config.CacheInfoKey)
 	go server.Start()
 }
 
+//create and run the HTTP admin server if the configuration
+//asks for one
+func startAdminServer(dataCache *caches.WritableDataCache,
+	cacheSet *caches.CacheSet, 
+	prefetchers *writable_processor.PrefetcherSet, 
+	dataNodeMap configuration.DataNodeMap) {
+	if config.AdminHttpAddress == "" {
+		return
+	}
+
+	control := cache_info_server.NewCacheControl(dataCache)
+	control.BlockIndex = cacheSet.BlockIndex
+	control.Fetcher = prefetchers
+	control.Caches = cacheSet
+
+	server := admin_server.NewAdminServer(config.AdminHttpAddress, control)
+	server.Config = config
+	server.DataNodeMap = dataNodeMap
+	server.Sessions = sessions
+	server.Secret = []byte(config.AdminSecret)
+	go func() {
+		err := server.Start()
+		if err != nil {
+			log.Println("Admin server stopped: ", err)
+		}
+	}()
+}
+
+//start re-verifying the blocks in dataCache every config.ScrubInterval
+//seconds, comparing them against the DataNodes they were read from
+func startScrubber(dataCache *caches.WritableDataCache) {
+	if config.ScrubInterval <= 0 {
+		return
+	}
+
+	interval := time.Duration(config.ScrubInterval) * time.Second
+	scrubber := caches.NewDataCacheScrubber(dataCache, interval)
+	scrubber.BlockChecksum = writable_processor.FetchBlockChecksum
+
+	go scrubber.Start()
+}
+
 //listen on a port connected to one of the datanodes
 //will be run as a goroutine
 func loopData(listener net.Listener, 
-	location *configuration.DataNodeLocation, cache *caches.DataCache) {
-	dataCacheSize := 15
-	dataCache := caches.NewWritableDataCache(dataCacheSize)
-
-	startCacheInfoServer(dataCache)
+	location *configuration.DataNodeLocation, 
+	dataCache *caches.WritableDataCache,
+	prefetcher *writable_processor.Prefetcher, 
+	failover *writable_processor.Failover, 
+	blockKeys *caches.BlockKeyStore,
+	checksumCache *caches.BlockChecksumCache) {
+
+	//the DataNode is only dialed if the client needs
+	//something that is not in the cache
+	dialDataNode := func() (net.Conn, error) {
+		dataNode, err := net.Dial("tcp", location.Address())
+		if err != nil {
+			log.Println("Could not connet to DataNode: ", err)
+		}
+		return dataNode, err
+	}
 
 	for {
 		util.DebugLogger.Println("Waiting to accept data connection...")
@@ -91,19 +164,28 @@ func loopData(listener net.Listener,
 		if err != nil {
 			util.LogError("Could not accept connection from the DataNode: "
 			 + err.Error())
-		}
-
-		//connection object to the datanode location
-		dataNode, err := net.Dial("tcp", location.Address())
-		if err != nil || dataNode == nil {
-			log.Println("Could not connet to DataNode: ", err)
-			dataNode = nil
+			continue
 		}
 
 		dataProcessor := writable_processor.New(dataCache)
+		dataProcessor.Prefetcher = prefetcher
+		dataProcessor.WriteThrough = config.WriteThroughCache
+		dataProcessor.ChecksumCache = checksumCache
+		dataProcessor.DataNodeAddress = location.Address()
+		dataProcessor.Failover = failover
+		dataProcessor.BlockKeys = blockKeys
+		if config.IdleTimeout > 0 {
+			dataProcessor.IdleTimeout = 
+				time.Duration(config.IdleTimeout) * time.Millisecond
+		}
 		//go dataProcessor.GeneralProcessing(conn, dataNode, true)
 
-		go dataProcessor.HandleClient(conn, dataNode)
+		session := sessions.Add(admin_server.SESSION_DATANODE, 
+			conn.RemoteAddr().String(), location.Address())
+		go func() {
+			defer sessions.Remove(session)
+			dataProcessor.HandleClient(conn, dialDataNode)
+		}()
 		//go dataProcessor.HandleDataNode(conn, dataNode)
 
 		/*
@@ -119,9 +201,37 @@ func loopData(listener net.Listener,
 }
 
 //takes a data node map and runs a main loop for each of 
-//the location and port combinations
+//the location and port combinations. The block keys of cacheSet
+//are only used if block tokens are to be validated (see
+//ValidateBlockTokens). The Prefetcher of each DataNode is added
+//to prefetchers and its checksum cache to cacheSet.
 func runDataNodeMap(dataNodeMap configuration.DataNodeMap, 
-	cache *caches.DataCache) {
+	dataCache *caches.WritableDataCache, 
+	cacheSet *caches.CacheSet,
+	prefetchers *writable_processor.PrefetcherSet) {
+	blockIndex := cacheSet.BlockIndex
+	blockKeys := cacheSet.BlockKeys
+
+
+	//the health of the DataNodes is shared by all of the loops
+	threshold := writable_processor.DEFAULT_BREAKER_THRESHOLD
+	if config.BreakerThreshold > 0 {
+		threshold = config.BreakerThreshold
+	}
+
+	cooldown := writable_processor.DEFAULT_BREAKER_COOLDOWN
+	if config.BreakerCooldown > 0 {
+		cooldown = time.Duration(config.BreakerCooldown) * time.Second
+	}
+
+	health := writable_processor.NewDataNodeHealth(threshold, cooldown)
+	failover := writable_processor.NewFailover(blockIndex, health, 
+		dataNodeMap.Resolve)
+
+	if !config.ValidateBlockTokens {
+		blockKeys = nil
+	}
+
 	for port, location := range dataNodeMap {
 		listener, err := net.Listen("tcp", ":" + string(port))
 		if err != nil || listener == nil {
@@ -134,8 +244,17 @@ func runDataNodeMap(dataNodeMap configuration.DataNodeMap,
 		fmt.Println("Listener: ", listener)
 		time.Sleep(100)
 
+		prefetcher := writable_processor.NewPrefetcher(dataCache, blockIndex,
+			location.Address(), config.PrefetchDepth, config.PrefetchBandwidth,
+			dataNodeMap.Resolve)
+		prefetchers.Add(prefetcher)
+
+		checksumCache := caches.NewBlockChecksumCache(config.ChecksumCacheSize)
+		cacheSet.AddChecksumCache(checksumCache)
+
 		//set up a main loop for this (port, location) tuple
-		go loopData(listener, location, cache)
+		go loopData(listener, location, dataCache, prefetcher, failover, 
+			blockKeys, checksumCache)
 	}
 }
 
@@ -191,7 +310,8 @@ func main() {
 	cacheSet.GetListingCache = caches.NewGetListingCache(
 	getListingCacheSize)
 	
-	//disable the metadata cache for now
+	//disable the metadata cache for now (an admin can
+	//turn it on through the cache info server)
 	cacheSet.Disable()
 
   /*
@@ -205,9 +325,17 @@ func main() {
 	}
 	util.TempLogger.Println("init()ed temporary logging") */
 
-	/* setup the data cache */
-	dataCacheSize := 10
-	dataCache := caches.NewDataCache(dataCacheSize)
+	/* setup the data cache (shared by all of the DataNode relays) */
+	dataCacheSize := DEFAULT_DATA_CACHE_SIZE
+	if config.DataCacheSize > 0 {
+		dataCacheSize = config.DataCacheSize
+	}
+	dataCache := caches.NewWritableDataCache(dataCacheSize)
+	dataCache.PinnedBytesLimit = config.PinnedBytesLimit
+	prefetchers := writable_processor.NewPrefetcherSet()
+
+	startCacheInfoServer(dataCache, cacheSet, prefetchers)
+	startScrubber(dataCache)
 	
 	/* setup the data layer */
 	portOffset := 1389 
@@ -221,7 +349,8 @@ func main() {
 
 	
 	//start the datanode servers
-	runDataNodeMap(dataNodeMap, dataCache)
+	runDataNodeMap(dataNodeMap, dataCache, cacheSet, prefetchers)
+	startAdminServer(dataCache, cacheSet, prefetchers, dataNodeMap)
 
 	//start namenode relay servers
 	loop(server, cacheSet, &dataNodeMap)
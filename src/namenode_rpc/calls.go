@@ -0,0 +1,145 @@
+package namenode_rpc
+
+import (
+	//go packages
+	"bytes"
+	"encoding/binary"
+
+	//local packages
+	"writables"
+)
+
+//protocol and version a client asks for in its connection
+//header (ClientProtocol.versionID in Hadoop 1)
+var CLIENT_PROTOCOL string = "org.apache.hadoop.hdfs.protocol.ClientProtocol"
+var CLIENT_PROTOCOL_VERSION uint64 = 61
+
+//Hadoop's UTF8.writeString: a short length and the bytes
+func utf8Bytes(val string) []byte {
+	buffer := new(bytes.Buffer)
+	writables.WriteString(val, buffer)
+	return buffer.Bytes()
+}
+
+//a parameter of type declaredClass whose ObjectWritable
+//encoding is value. Parameter splits the value into a short
+//"length" and the rest, which only lines up for strings; for
+//anything else we put the first two bytes of the encoding in
+//ValueLength so that BytesNoPad() writes it out unchanged.
+func newRawParameter(declaredClass string, value []byte) Parameter {
+	p := *(NewParameter())
+	p.TypeLength = uint16(len(declaredClass))
+	p.Type = []byte(declaredClass)
+
+	for len(value) < 2 {
+		value = append(value, 0)
+	}
+	p.ValueLength = binary.BigEndian.Uint16(value[0:2])
+	p.Value = value[2:]
+	return p
+}
+
+func NewStringParameter(val string) Parameter {
+	p := *(NewParameter())
+	p.TypeLength = uint16(len("java.lang.String"))
+	p.Type = []byte("java.lang.String")
+	p.ValueLength = uint16(len(val))
+	p.Value = []byte(val)
+	return p
+}
+
+func NewLongParameter(val uint64) Parameter {
+	buffer := new(bytes.Buffer)
+	writables.WriteLongInt(val, buffer)
+	return newRawParameter("long", buffer.Bytes())
+}
+
+//a byte[]: the length, then every element as an ObjectWritable
+//of the "byte" class
+func NewByteArrayParameter(val []byte) Parameter {
+	buffer := new(bytes.Buffer)
+	writables.WriteInt(uint32(len(val)), buffer)
+	for i := 0; i < len(val); i++ {
+		buffer.Write(utf8Bytes("byte"))
+		buffer.WriteByte(val[i])
+	}
+	return newRawParameter("[B", buffer.Bytes())
+}
+
+//a Writable: the declared class, the class of the instance and
+//then whatever value writes
+func NewWritableParameter(declaredClass string, instanceClass string,
+	value writables.Writable) Parameter {
+	buffer := new(bytes.Buffer)
+	buffer.Write(utf8Bytes(instanceClass))
+	value.Write(buffer)
+	return newRawParameter(declaredClass, buffer.Bytes())
+}
+
+//FsPermission writes itself as a short
+type fsPermission struct {
+	Permission uint16
+}
+
+func (f *fsPermission) Read(reader writables.Reader) error {
+	var err error
+	f.Permission, err = writables.ReadShortInt(reader)
+	return err
+}
+
+func (f *fsPermission) Write(writer writables.Writer) error {
+	return writables.WriteShortInt(f.Permission, writer)
+}
+
+func NewPermissionParameter(permission uint16) Parameter {
+	class := "org.apache.hadoop.fs.permission.FsPermission"
+	return NewWritableParameter(class, class, &fsPermission{permission})
+}
+
+//a call of method with params, ready to be sent with BytesNoPad()
+func NewCallPacket(callId uint32, method string,
+	params ...Parameter) *RequestPacket {
+	rp := NewRequestPacket()
+	rp.PacketNumber = callId
+	rp.NameLength = uint16(len(method))
+	rp.MethodName = []byte(method)
+	rp.ParameterNumber = uint32(len(params))
+	rp.Parameters = params
+
+	//the length covers everything after itself
+	rp.Length = uint32(len(rp.BytesNoPad()) - 4)
+	return rp
+}
+
+//the connection header of a user that does not authenticate
+//with Kerberos or a token (ConnectionHeader in Hadoop 1),
+//followed by the getProtocolVersion call every client starts
+//with
+func NewConnectionAuthPacket(user string) *AuthPacket {
+	header := new(bytes.Buffer)
+	protocol := writables.NewText()
+	protocol.Length = int64(len(CLIENT_PROTOCOL))
+	protocol.Bytes = []byte(CLIENT_PROTOCOL)
+	protocol.Write(header)
+
+	writables.WriteBoolean(true, header)
+	writables.WriteString(user, header)
+
+	//no real user behind this one
+	writables.WriteBoolean(false, header)
+
+	call := NewCallPacket(0, "getProtocolVersion",
+		NewStringParameter(CLIENT_PROTOCOL),
+		NewLongParameter(CLIENT_PROTOCOL_VERSION))
+
+	ap := NewAuthPacket()
+	ap.AuthenticationLength = uint32(header.Len())
+	ap.AuthenticationBits = header.Bytes()
+	ap.Length = call.Length
+	ap.PacketNumber = call.PacketNumber
+	ap.NameLength = call.NameLength
+	ap.MethodName = call.MethodName
+	ap.ParameterNumber = call.ParameterNumber
+	ap.Parameters = call.Parameters
+	return ap
+}
@@ -0,0 +1,420 @@
+/*
+* A client for the NameNode's ClientProtocol (Hadoop 1 writable
+* RPC). It connects, sends the HeaderPacket preamble and an
+* AuthPacket and then issues calls, each with its own call id.
+* Responses are matched to calls by id as they come in, so calls
+* from several goroutines can be outstanding at the same time.
+*/
+
+package namenode_rpc
+
+import (
+	//go packages
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+//how long a call waits for its response by default
+var DEFAULT_RPC_TIMEOUT time.Duration = 30 * time.Second
+
+//status a response starts with (after the call id)
+var RPC_STATUS_SUCCESS uint32 = 0
+var RPC_STATUS_ERROR uint32 = 1
+var RPC_STATUS_FATAL uint32 = 0xFFFFFFFF
+
+//what ObjectWritable says instead of a class for a null value
+var NULL_INSTANCE_CLASS string = "org.apache.hadoop.io.ObjectWritable$NullInstance"
+
+var ErrClientClosed = errors.New("NameNode client is closed.")
+
+//an exception thrown by the NameNode while running a call
+type RemoteError struct {
+	Class string
+	Message string
+}
+
+func (r *RemoteError) Error() string {
+	return fmt.Sprintf("%s: %s", r.Class, r.Message)
+}
+
+//reads the value of a successful response (everything after
+//the status)
+type decodeFunc func(reader writables.Reader) (interface{}, error)
+
+type callResult struct {
+	value interface{}
+	err error
+}
+
+type pendingCall struct {
+	decode decodeFunc
+
+	//buffered so that the reader never blocks on a call that
+	//has timed out
+	done chan callResult
+}
+
+type Client struct {
+	Address string
+	User string
+
+	//how long each call waits for its response
+	Timeout time.Duration
+
+	conn net.Conn
+	reader *bufio.Reader
+
+	//one call is written at a time
+	writeLock sync.Mutex
+
+	//guards everything below
+	lock sync.Mutex
+	nextCallId uint32
+	pending map[uint32]*pendingCall
+
+	//why the client stopped (nil => running)
+	err error
+}
+
+//connects to the NameNode at address as user and checks that
+//it speaks the version of ClientProtocol we do
+func NewClient(address string, user string) (*Client, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Client{Address: address, User: user, Timeout: DEFAULT_RPC_TIMEOUT}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.pending = make(map[uint32]*pendingCall)
+
+	//the connection header carries call 0
+	c.nextCallId = 1
+	versionCall := c.addCall(0, decodeLong)
+
+	preamble := NewHeaderPacket().Preamble()
+	auth := NewConnectionAuthPacket(user).Bytes()
+	err = c.write(append(preamble, auth...))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readResponses()
+
+	version, err := c.wait(0, versionCall)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	if version.(uint64) != CLIENT_PROTOCOL_VERSION {
+		c.Close()
+		return nil, fmt.Errorf("NameNode speaks version %d of %s, not %d.",
+			version.(uint64), CLIENT_PROTOCOL, CLIENT_PROTOCOL_VERSION)
+	}
+
+	return &c, nil
+}
+
+func (c *Client) Close() error {
+	c.fail(ErrClientClosed)
+	return c.conn.Close()
+}
+
+func (c *Client) addCall(callId uint32, decode decodeFunc) *pendingCall {
+	call := &pendingCall{decode: decode, done: make(chan callResult, 1)}
+	c.pending[callId] = call
+	return call
+}
+
+func (c *Client) write(buf []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.Timeout))
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+//stops the client: every outstanding call fails with err
+func (c *Client) fail(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+
+	for callId, call := range c.pending {
+		call.done <- callResult{err: c.err}
+		delete(c.pending, callId)
+	}
+}
+
+//waits for the response to callId. A call that times out stays
+//pending, so that its response is still read off the connection
+//when it comes (and dropped).
+func (c *Client) wait(callId uint32, call *pendingCall) (interface{}, error) {
+	select {
+	case res := <-call.done:
+		return res.value, res.err
+	case <-time.After(c.Timeout):
+		return nil, fmt.Errorf("Call %d to the NameNode timed out after %v.",
+			callId, c.Timeout)
+	}
+}
+
+//sends a call of method and waits for the response, which is
+//read with decode
+func (c *Client) call(method string, decode decodeFunc,
+	params ...Parameter) (interface{}, error) {
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return nil, c.err
+	}
+
+	callId := c.nextCallId
+	c.nextCallId++
+	call := c.addCall(callId, decode)
+	c.lock.Unlock()
+
+	err := c.write(NewCallPacket(callId, method, params...).BytesNoPad())
+	if err != nil {
+		c.fail(err)
+		c.conn.Close()
+		return nil, err
+	}
+
+	return c.wait(callId, call)
+}
+
+//runs until the connection breaks, handing every response to
+//the call it belongs to
+func (c *Client) readResponses() {
+	for {
+		err := c.readResponse()
+		if err != nil {
+			c.fail(err)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *Client) readResponse() error {
+	callId, err := writables.ReadInt(c.reader)
+	if err != nil {
+		return err
+	}
+
+	status, err := writables.ReadInt(c.reader)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	call, present := c.pending[callId]
+	delete(c.pending, callId)
+	c.lock.Unlock()
+
+	//without the call we do not know how long the value is, so
+	//there is no way to carry on reading
+	if !present {
+		return fmt.Errorf("Response to unknown call %d.", callId)
+	}
+
+	if status != RPC_STATUS_SUCCESS {
+		remoteErr, err := readRemoteError(c.reader)
+		if err != nil {
+			call.done <- callResult{err: err}
+			return err
+		}
+
+		call.done <- callResult{err: remoteErr}
+		if status == RPC_STATUS_FATAL {
+			return remoteErr
+		}
+		return nil
+	}
+
+	value, err := call.decode(c.reader)
+	call.done <- callResult{value: value, err: err}
+	return err
+}
+
+//WritableUtils.writeString: an int length (-1 => null) and
+//the bytes
+func readWritableString(reader writables.Reader) (string, error) {
+	length, err := writables.ReadInt(reader)
+	if err != nil {
+		return "", err
+	}
+
+	if int32(length) < 0 {
+		return "", nil
+	}
+
+	buf, err := writables.ReadBytesIO(int64(length), reader)
+	return string(buf), err
+}
+
+func readRemoteError(reader writables.Reader) (*RemoteError, error) {
+	class, err := readWritableString(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := readWritableString(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteError{Class: class, Message: message}, nil
+}
+
+//UTF8.readString
+func readUTF8(reader writables.Reader) (string, error) {
+	length, err := writables.ReadShortInt(reader)
+	if err != nil {
+		return "", err
+	}
+
+	buf, err := writables.ReadBytesIO(int64(length), reader)
+	return string(buf), err
+}
+
+//reads the ObjectWritable head of a Writable return value and
+//then the value itself. Returns false if the NameNode returned
+//null.
+func readWritable(reader writables.Reader, value writables.Writable) (
+	bool, error) {
+	//declared class
+	_, err := readUTF8(reader)
+	if err != nil {
+		return false, err
+	}
+
+	instanceClass, err := readUTF8(reader)
+	if err != nil {
+		return false, err
+	}
+
+	if instanceClass == NULL_INSTANCE_CLASS {
+		//the class the null stands in for
+		_, err = readUTF8(reader)
+		return false, err
+	}
+
+	return true, value.Read(reader)
+}
+
+func decodeLong(reader writables.Reader) (interface{}, error) {
+	_, err := readUTF8(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return writables.ReadLongInt(reader)
+}
+
+func decodeBoolean(reader writables.Reader) (interface{}, error) {
+	_, err := readUTF8(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return writables.ReadBoolean(reader)
+}
+
+//decodes a Writable return value into what newValue makes (or
+//nil if the NameNode returned null)
+func decodeWritable(newValue func() writables.Writable) decodeFunc {
+	return func(reader writables.Reader) (interface{}, error) {
+		value := newValue()
+		present, err := readWritable(reader, value)
+		if err != nil || !present {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+/* ClientProtocol calls */
+
+//nil if there is nothing at path
+func (c *Client) GetFileInfo(path string) (*writables.HdfsFileStatus, error) {
+	res, err := c.call("getFileInfo", decodeWritable(func() writables.Writable {
+		return writables.NewHdfsFileStatus()
+	}), NewStringParameter(path))
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return res.(*writables.HdfsFileStatus), nil
+}
+
+//the entries of the directory at path that come after the name
+//startAfter (empty => from the start). nil if there is no
+//directory at path.
+func (c *Client) GetListing(path string, startAfter []byte) (
+	*writables.DirectoryListing, error) {
+	res, err := c.call("getListing", decodeWritable(func() writables.Writable {
+		return writables.NewDirectoryListing()
+	}), NewStringParameter(path), NewByteArrayParameter(startAfter))
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return res.(*writables.DirectoryListing), nil
+}
+
+//the blocks of the file at path that hold the length bytes
+//starting at offset. nil if there is no file at path.
+func (c *Client) GetBlockLocations(path string, offset int64, length int64) (
+	*writables.LocatedBlocks, error) {
+	res, err := c.call("getBlockLocations",
+		decodeWritable(func() writables.Writable {
+			return writables.NewLocatedBlocks()
+		}), NewStringParameter(path), NewLongParameter(uint64(offset)),
+		NewLongParameter(uint64(length)))
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return res.(*writables.LocatedBlocks), nil
+}
+
+func (c *Client) GetContentSummary(path string) (*writables.ContentSummary,
+	error) {
+	res, err := c.call("getContentSummary",
+		decodeWritable(func() writables.Writable {
+			return writables.NewContentSummary()
+		}), NewStringParameter(path))
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return res.(*writables.ContentSummary), nil
+}
+
+//creates the directory at path (and its parents) with the
+//given permission bits
+func (c *Client) Mkdirs(path string, permission uint16) (bool, error) {
+	res, err := c.call("mkdirs", decodeBoolean, NewStringParameter(path),
+		NewPermissionParameter(permission))
+	if err != nil {
+		return false, err
+	}
+
+	return res.(bool), nil
+}
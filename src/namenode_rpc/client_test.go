@@ -0,0 +1,360 @@
+package namenode_rpc
+
+import (
+	"testing"
+	"bytes"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"writables"
+)
+
+func TestConnectionAuthPacket(t *testing.T) {
+	ap := NewConnectionAuthPacket("dhaivat")
+
+	//the captured packet has a stray byte at the end
+	expected := AuthPacketTestCase[0:len(AuthPacketTestCase) - 1]
+	if !bytes.Equal(ap.Bytes(), expected) {
+		t.Fatal("Wrong auth packet: ", ap.Bytes())
+	}
+
+	if !bytes.Equal(NewHeaderPacket().Preamble(),
+		[]byte{104, 114, 112, 99, 4, 80}) {
+		t.Fail()
+	}
+}
+
+func TestCallPacketParameters(t *testing.T) {
+	rp := NewCallPacket(7, "getListing", NewStringParameter("/a"),
+		NewByteArrayParameter([]byte{9}))
+
+	expected := []byte{0, 0, 0, 57, 0, 0, 0, 7, 0, 10}
+	expected = append(expected, []byte("getListing")...)
+	expected = append(expected, 0, 0, 0, 2, 0, 16)
+	expected = append(expected, []byte("java.lang.String")...)
+	expected = append(expected, 0, 2, '/', 'a', 0, 2, '[', 'B', 0, 0, 0, 1,
+		0, 4, 'b', 'y', 't', 'e', 9)
+
+	if !bytes.Equal(rp.BytesNoPad(), expected) {
+		t.Fatal("Wrong call: ", rp.BytesNoPad())
+	}
+
+	class := "org.apache.hadoop.fs.permission.FsPermission"
+	p := NewPermissionParameter(0755)
+	value := append([]byte{byte(p.ValueLength >> 8), byte(p.ValueLength)},
+		p.Value...)
+	expected = append([]byte{0, byte(len(class))}, []byte(class)...)
+	expected = append(expected, 1, 0xed)
+	if string(p.Type) != class || !bytes.Equal(value, expected) {
+		t.Fatal("Wrong permission: ", value)
+	}
+}
+
+/* a NameNode that answers calls with respond */
+
+type call struct {
+	id uint32
+	method string
+	path string
+}
+
+//writes the response to c into buf (nothing => no response)
+type responder func(c call, buf *bytes.Buffer)
+
+func writeUTF8(val string, buf *bytes.Buffer) {
+	writables.WriteString(val, buf)
+}
+
+func writeSuccess(c call, buf *bytes.Buffer) {
+	writables.WriteInt(c.id, buf)
+	writables.WriteInt(RPC_STATUS_SUCCESS, buf)
+}
+
+func writeWritable(c call, class string, value writables.Writable,
+	buf *bytes.Buffer) {
+	writeSuccess(c, buf)
+	writeUTF8(class, buf)
+	if value == nil {
+		writeUTF8(NULL_INSTANCE_CLASS, buf)
+		writeUTF8(class, buf)
+		return
+	}
+
+	writeUTF8(class, buf)
+	value.Write(buf)
+}
+
+func writeRemoteError(c call, class string, message string,
+	buf *bytes.Buffer) {
+	writables.WriteInt(c.id, buf)
+	writables.WriteInt(RPC_STATUS_ERROR, buf)
+	writables.WriteInt(uint32(len(class)), buf)
+	buf.WriteString(class)
+	writables.WriteInt(uint32(len(message)), buf)
+	buf.WriteString(message)
+}
+
+func startNameNode(t *testing.T, respond responder) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveNameNode(conn, respond)
+	}()
+
+	return listener.Addr().String()
+}
+
+func toInt(buf []byte) int {
+	return int(buf[0]) << 24 | int(buf[1]) << 16 | int(buf[2]) << 8 |
+		int(buf[3])
+}
+
+func serveNameNode(conn net.Conn, respond responder) {
+	preamble := make([]byte, 6)
+	_, err := io.ReadFull(conn, preamble)
+	if err != nil {
+		return
+	}
+
+	headerLength := make([]byte, 4)
+	_, err = io.ReadFull(conn, headerLength)
+	if err != nil {
+		return
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, toInt(headerLength)))
+	if err != nil {
+		return
+	}
+
+	for {
+		length := make([]byte, 4)
+		_, err = io.ReadFull(conn, length)
+		if err != nil {
+			return
+		}
+
+		payload := make([]byte, toInt(length))
+		_, err = io.ReadFull(conn, payload)
+		if err != nil {
+			return
+		}
+
+		rp := NewRequestPacket()
+		rp.Load(append(length, payload...))
+		c := call{id: rp.PacketNumber, method: string(rp.MethodName)}
+		if rp.ParameterNumber > 0 {
+			c.path = string(rp.Parameters[0].Value)
+		}
+
+		buf := new(bytes.Buffer)
+		if c.method == "getProtocolVersion" {
+			writeSuccess(c, buf)
+			writeUTF8("long", buf)
+			writables.WriteLongInt(CLIENT_PROTOCOL_VERSION, buf)
+		} else {
+			respond(c, buf)
+		}
+
+		_, err = conn.Write(buf.Bytes())
+		if err != nil {
+			return
+		}
+	}
+}
+
+func makeFileStatus(name string) *writables.HdfsFileStatus {
+	h := writables.NewHdfsFileStatus()
+	h.Path = []byte(name)
+	h.Length = 100
+	h.BlockReplication = 3
+	h.BlockSize = 64
+	h.Owner.Bytes = []byte("hduser")
+	h.Owner.Length = 6
+	return h
+}
+
+func TestClientCalls(t *testing.T) {
+	listing := writables.NewDirectoryListing()
+	listing.PartialListing = append(listing.PartialListing,
+		makeFileStatus("a"), makeFileStatus("b"))
+
+	summary := writables.NewContentSummary()
+	summary.Length = 200
+	summary.FileCount = 2
+
+	located := writables.NewLocatedBlocks()
+	located.Length = 100
+
+	address := startNameNode(t, func(c call, buf *bytes.Buffer) {
+		switch c.method {
+		case "getFileInfo":
+			if c.path == "/missing" {
+				writeWritable(c, "org.apache.hadoop.hdfs.protocol.HdfsFileStatus",
+					nil, buf)
+				return
+			}
+			writeWritable(c, "org.apache.hadoop.hdfs.protocol.HdfsFileStatus",
+				makeFileStatus(""), buf)
+		case "getListing":
+			writeWritable(c, "org.apache.hadoop.hdfs.protocol.DirectoryListing",
+				listing, buf)
+		case "getContentSummary":
+			writeWritable(c, "org.apache.hadoop.fs.ContentSummary", summary, buf)
+		case "getBlockLocations":
+			writeWritable(c, "org.apache.hadoop.hdfs.protocol.LocatedBlocks",
+				located, buf)
+		case "mkdirs":
+			writeSuccess(c, buf)
+			writeUTF8("boolean", buf)
+			writables.WriteBoolean(c.path == "/new", buf)
+		}
+	})
+
+	client, err := NewClient(address, "hduser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	status, err := client.GetFileInfo("/file")
+	if err != nil || !reflect.DeepEqual(status, makeFileStatus("")) {
+		t.Fatal("Wrong file info: ", status, err)
+	}
+
+	status, err = client.GetFileInfo("/missing")
+	if err != nil || status != nil {
+		t.Fatal("Missing file has info: ", status, err)
+	}
+
+	dirListing, err := client.GetListing("/dir", []byte{})
+	if err != nil || !reflect.DeepEqual(dirListing, listing) {
+		t.Fatal("Wrong listing: ", dirListing, err)
+	}
+
+	contentSummary, err := client.GetContentSummary("/dir")
+	if err != nil || !reflect.DeepEqual(contentSummary, summary) {
+		t.Fatal("Wrong summary: ", contentSummary, err)
+	}
+
+	blocks, err := client.GetBlockLocations("/file", 0, 100)
+	if err != nil || blocks.Length != 100 || blocks.NumberOfBlocks != 0 {
+		t.Fatal("Wrong block locations: ", blocks, err)
+	}
+
+	created, err := client.Mkdirs("/new", 0755)
+	if err != nil || !created {
+		t.Fatal("Mkdirs failed: ", err)
+	}
+}
+
+func TestClientOutOfOrder(t *testing.T) {
+	//the response to /first is held back until /second has
+	//been answered
+	var held *bytes.Buffer
+	address := startNameNode(t, func(c call, buf *bytes.Buffer) {
+		summary := writables.NewContentSummary()
+		summary.Length = uint64(len(c.path))
+		if c.path == "/first" {
+			held = new(bytes.Buffer)
+			writeWritable(c, "org.apache.hadoop.fs.ContentSummary", summary,
+				held)
+			return
+		}
+
+		writeWritable(c, "org.apache.hadoop.fs.ContentSummary", summary, buf)
+		buf.Write(held.Bytes())
+	})
+
+	client, err := NewClient(address, "hduser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	first := make(chan uint64)
+	go func() {
+		summary, err := client.GetContentSummary("/first")
+		if err != nil {
+			first <- 0
+			return
+		}
+		first <- summary.Length
+	}()
+
+	//wait for /first to be sent
+	time.Sleep(100 * time.Millisecond)
+
+	summary, err := client.GetContentSummary("/second-call")
+	if err != nil || summary.Length != uint64(len("/second-call")) {
+		t.Fatal("Wrong summary: ", summary, err)
+	}
+
+	if <-first != uint64(len("/first")) {
+		t.Fail()
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	address := startNameNode(t, func(c call, buf *bytes.Buffer) {
+		if c.path == "/slow" {
+			return
+		}
+		writeWritable(c, "org.apache.hadoop.hdfs.protocol.HdfsFileStatus",
+			makeFileStatus(""), buf)
+	})
+
+	client, err := NewClient(address, "hduser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Timeout = 100 * time.Millisecond
+	_, err = client.GetFileInfo("/slow")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatal("No timeout: ", err)
+	}
+
+	//the connection is still usable
+	status, err := client.GetFileInfo("/fast")
+	if err != nil || status == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRemoteError(t *testing.T) {
+	address := startNameNode(t, func(c call, buf *bytes.Buffer) {
+		writeRemoteError(c, "org.apache.hadoop.security.AccessControlException",
+			"Permission denied", buf)
+	})
+
+	client, err := NewClient(address, "hduser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Mkdirs("/root", 0755)
+	remoteErr, ok := err.(*RemoteError)
+	if !ok || remoteErr.Message != "Permission denied" {
+		t.Fatal("Wrong error: ", err)
+	}
+
+	client.Close()
+	_, err = client.GetFileInfo("/")
+	if err != ErrClientClosed {
+		t.Fatal("Call on closed client: ", err)
+	}
+}
@@ -50,6 +50,13 @@ func (header_packet *HeaderPacket) Bytes() []byte {
 	return buffer.Bytes()
 }
 
+//what a Hadoop 1 client actually sends when it connects: the
+//header, version and auth method (the serialization type is
+//only sent by later versions of the protocol)
+func (header_packet *HeaderPacket) Preamble() []byte {
+	return header_packet.Bytes()[0:6]
+}
+
 //basic packet structure used by the namenode
 //for RPC.
 type Packet interface {
@@ -0,0 +1,248 @@
+package main
+
+import (
+	//go packages
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	//local packages
+	"cache_protocol"
+	"scheduler/cache_comm"
+)
+
+type command struct {
+	//arguments and what the command does (for usage())
+	usage string
+
+	//checks the arguments before any cache is contacted
+	//(nil => anything goes)
+	check func(args []string) error
+
+	//whether the client has to log in as an admin first
+	admin bool
+
+	//carries out the command on one cache; the result is
+	//printed as JSON or handed to table()
+	run func(client *cache_comm.Client, args []string) (interface{}, error)
+	table func(w io.Writer, result interface{})
+}
+
+//order the commands are listed in by usage()
+var COMMAND_NAMES = []string{"describe", "blocks", "stats", "evict",
+	"pin", "unpin", "prefetch", "clear"}
+
+var COMMANDS = map[string]*command{
+	"describe": &command{usage: "size and fill of the data cache",
+		run: describe,
+		table: describeTable},
+	"blocks": &command{usage: "list the cached blocks",
+		run: blocks,
+		table: blocksTable},
+	"stats": &command{usage: "hits, misses, bytes and latencies",
+		run: stats,
+		table: statsTable},
+	"evict": &command{
+		usage: "<block id|path>... drop blocks (and a path's metadata)",
+		check: checkEvict,
+		admin: true,
+		run: evict,
+		table: countTable},
+	"pin": &command{usage: "<block id>... pin blocks into the cache",
+		check: checkBlockIds,
+		run: blockCommand((*cache_comm.Client).PinBlocks),
+		table: statusTable},
+	"unpin": &command{usage: "<block id>... let pinned blocks go",
+		check: checkBlockIds,
+		run: blockCommand((*cache_comm.Client).UnpinBlocks),
+		table: statusTable},
+	"prefetch": &command{usage: "<block id>... read blocks into the cache",
+		check: checkBlockIds,
+		run: blockCommand((*cache_comm.Client).PrefetchBlocks),
+		table: statusTable},
+	"clear": &command{usage: "<data|getfileinfo|getlisting> empty a cache",
+		check: checkClear,
+		admin: true,
+		run: clearCache,
+		table: countTable},
+}
+
+func parseBlockIds(args []string) ([]uint64, error) {
+	res := make([]uint64, len(args))
+	for i := 0; i < len(args); i++ {
+		blockId, err := strconv.ParseUint(args[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Bad block id %q.", args[i])
+		}
+		res[i] = blockId
+	}
+
+	return res, nil
+}
+
+func checkBlockIds(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Give one or more block ids.")
+	}
+
+	_, err := parseBlockIds(args)
+	return err
+}
+
+func checkEvict(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Give one or more block ids or paths.")
+	}
+
+	return nil
+}
+
+func checkClear(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Give the cache to clear.")
+	}
+
+	_, present := cache_protocol.CACHE_NAMES[args[0]]
+	if !present {
+		return fmt.Errorf("Unknown cache %q.", args[0])
+	}
+
+	return nil
+}
+
+func describe(client *cache_comm.Client, args []string) (interface{}, error) {
+	return client.GetCacheDescription()
+}
+
+func describeTable(w io.Writer, result interface{}) {
+	descr := result.(*cache_protocol.CacheDescription)
+	fmt.Fprintf(w, "capacity\t%d\n", descr.CacheSize)
+	fmt.Fprintf(w, "used\t%d\n", descr.CurrSize)
+	fmt.Fprintf(w, "algorithm\t%d\n", descr.ReplaceAlgorithm)
+}
+
+func blocks(client *cache_comm.Client, args []string) (interface{}, error) {
+	return client.GetCachedBlocks()
+}
+
+func blocksTable(w io.Writer, result interface{}) {
+	cachedBlocks := result.(*cache_protocol.CachedBlocks)
+	fmt.Fprintln(w, "BLOCK\tGENSTAMP\tLENGTH\tCACHED\tDATANODE\tPATH")
+	for _, block := range cachedBlocks.Blocks {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\t%s\n", block.BlockId,
+			block.GenerationStamp, block.Length, block.BytesCached,
+			block.DataNode, block.Path)
+	}
+}
+
+func stats(client *cache_comm.Client, args []string) (interface{}, error) {
+	return client.GetCacheStats()
+}
+
+//number of blocks statsTable lists the reads of
+var TOP_BLOCKS = 10
+
+func latencyRow(w io.Writer, name string, l cache_protocol.Latencies) {
+	fmt.Fprintf(w, "%s\tp50 %v\tp90 %v\tp99 %v\tmax %v\n", name,
+		time.Duration(l.P50), time.Duration(l.P90), time.Duration(l.P99),
+		time.Duration(l.Max))
+}
+
+func statsTable(w io.Writer, result interface{}) {
+	s := result.(*cache_protocol.CacheStats)
+	ratio := 0.0
+	if s.Hits + s.Misses > 0 {
+		ratio = float64(s.Hits) / float64(s.Hits + s.Misses)
+	}
+
+	fmt.Fprintf(w, "hits\t%d\t(%.1f%%)\n", s.Hits, ratio * 100)
+	fmt.Fprintf(w, "misses\t%d\n", s.Misses)
+	fmt.Fprintf(w, "bytes from cache\t%d\n", s.BytesFromCache)
+	fmt.Fprintf(w, "bytes from datanodes\t%d\n", s.BytesFromDataNode)
+	fmt.Fprintf(w, "evictions\t%d\n", s.Evictions)
+	latencyRow(w, "hit latency", s.HitLatency)
+	latencyRow(w, "miss latency", s.MissLatency)
+
+	for i := 0; i < len(s.Accesses) && i < TOP_BLOCKS; i++ {
+		fmt.Fprintf(w, "block %d\t%d reads\n", s.Accesses[i].BlockId,
+			s.Accesses[i].Count)
+	}
+}
+
+//what evict and clear answer with
+type dropped struct {
+	Count int
+}
+
+func countTable(w io.Writer, result interface{}) {
+	fmt.Fprintf(w, "dropped %d entries\n", result.(*dropped).Count)
+}
+
+//arguments that are block ids are evicted as blocks, the rest
+//as paths
+func evict(client *cache_comm.Client, args []string) (interface{}, error) {
+	res := &dropped{}
+	for i := 0; i < len(args); i++ {
+		var count int
+		blockId, err := strconv.ParseUint(args[i], 10, 64)
+		if err == nil {
+			count, err = client.EvictBlock(blockId)
+		} else {
+			count, err = client.EvictPath(args[i])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		res.Count += count
+	}
+
+	return res, nil
+}
+
+func clearCache(client *cache_comm.Client, args []string) (interface{}, error) {
+	count, err := client.ClearCache(cache_protocol.CACHE_NAMES[args[0]])
+	if err != nil {
+		return nil, err
+	}
+
+	return &dropped{Count: count}, nil
+}
+
+//what happened to one of the blocks of pin, unpin or prefetch
+type blockStatus struct {
+	BlockId uint64
+	Status string
+}
+
+//a command that hands its block ids to send
+func blockCommand(send func(*cache_comm.Client, []uint64) (
+	*cache_protocol.BlockStatuses, error)) func(*cache_comm.Client,
+	[]string) (interface{}, error) {
+	return func(client *cache_comm.Client, args []string) (interface{}, error) {
+		blockIds, err := parseBlockIds(args)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses, err := send(client, blockIds)
+		if err != nil {
+			return nil, err
+		}
+
+		res := make([]blockStatus, len(statuses.Statuses))
+		for i, status := range statuses.Statuses {
+			res[i] = blockStatus{BlockId: status.BlockId,
+				Status: cache_protocol.BLOCK_STATUS_NAMES[status.Status]}
+		}
+		return res, nil
+	}
+}
+
+func statusTable(w io.Writer, result interface{}) {
+	fmt.Fprintln(w, "BLOCK\tSTATUS")
+	for _, status := range result.([]blockStatus) {
+		fmt.Fprintf(w, "%d\t%s\n", status.BlockId, status.Status)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+* panthera-ctl talks to one or more running caches through their
+* cache_info_server and prints what they answer, as tables or
+* as JSON.
+*
+*     panthera-ctl [flags] <command> [arguments]
+*
+* See usage() for the commands.
+*/
+
+package main
+
+import (
+	//go packages
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	//local packages
+	"scheduler/cache_comm"
+	"scheduler/configuration"
+)
+
+var cacheAddrs = flag.String("caches", "localhost:1337",
+	"comma separated host:port list of the caches to talk to")
+var confFile = flag.String("conf", "",
+	"scheduler configuration to take the caches and key from")
+var key = flag.String("key", "",
+	"key the caches authenticate clients with")
+var secret = flag.String("secret", "",
+	"admin secret (needed by evict and clear)")
+var jsonOutput = flag.Bool("json", false, "print JSON instead of tables")
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: panthera-ctl [flags] <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, name := range COMMAND_NAMES {
+		fmt.Fprintf(w, "  %s\t%s\n", name, COMMANDS[name].usage)
+	}
+	fmt.Fprintf(w, "  watch\tstream the block events of the caches\n")
+	w.Flush()
+
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+//turns a comma separated host:port list into cache locations
+func parseCacheAddrs(addrs string) ([]configuration.CacheLocation, error) {
+	res := make([]configuration.CacheLocation, 0)
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		hostname, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *configuration.NewCacheLocation(hostname, port))
+	}
+
+	if len(res) == 0 {
+		return nil, errors.New("No caches given.")
+	}
+
+	return res, nil
+}
+
+//the caches to talk to and the key to authenticate with, from
+//the flags (which win) and the scheduler configuration
+func cacheLocations() ([]configuration.CacheLocation, []byte, error) {
+	authKey := *key
+	if *confFile != "" {
+		conf := configuration.NewConfiguration()
+		err := conf.ReadFromFile(*confFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if authKey == "" {
+			authKey = conf.CacheInfoKey
+		}
+
+		//-caches only counts if it was given
+		caches := ""
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "caches" {
+				caches = f.Value.String()
+			}
+		})
+
+		if caches == "" {
+			return conf.CacheLocations, []byte(authKey), nil
+		}
+	}
+
+	locations, err := parseCacheAddrs(*cacheAddrs)
+	return locations, []byte(authKey), err
+}
+
+//what one cache answered to a command
+type cacheResult struct {
+	Cache string
+	Result interface{}
+	Error string
+}
+
+//runs cmd against each of the caches in turn
+func runCommand(cmd *command, locations []configuration.CacheLocation,
+	authKey []byte, args []string) []cacheResult {
+	res := make([]cacheResult, len(locations))
+	for i := 0; i < len(locations); i++ {
+		res[i].Cache = locations[i].GetString()
+		result, err := runOnCache(cmd, locations[i], authKey, args)
+		if err != nil {
+			res[i].Error = err.Error()
+			continue
+		}
+		res[i].Result = result
+	}
+
+	return res
+}
+
+func runOnCache(cmd *command, location configuration.CacheLocation,
+	authKey []byte, args []string) (interface{}, error) {
+	client, err := cache_comm.NewClient(location, authKey)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if cmd.admin {
+		if *secret == "" {
+			return nil, errors.New("This command needs -secret.")
+		}
+
+		err = client.AdminLogin(*secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cmd.run(client, args)
+}
+
+func printResults(cmd *command, results []cacheResult) {
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+		return
+	}
+
+	for i := 0; i < len(results); i++ {
+		if len(results) > 1 {
+			fmt.Printf("== %s ==\n", results[i].Cache)
+		}
+
+		if results[i].Error != "" {
+			fmt.Println("Error: ", results[i].Error)
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		cmd.table(w, results[i].Result)
+		w.Flush()
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	locations, authKey, err := cacheLocations()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not work out the caches: ", err)
+		os.Exit(2)
+	}
+
+	if args[0] == "watch" {
+		os.Exit(watch(locations, authKey))
+	}
+
+	cmd, present := COMMANDS[args[0]]
+	if !present {
+		fmt.Fprintln(os.Stderr, "Unknown command: ", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	if cmd.check != nil {
+		err = cmd.check(args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	results := runCommand(cmd, locations, authKey, args[1:])
+	printResults(cmd, results)
+
+	for i := 0; i < len(results); i++ {
+		if results[i].Error != "" {
+			os.Exit(1)
+		}
+	}
+}
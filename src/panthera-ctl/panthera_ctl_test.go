@@ -0,0 +1,109 @@
+package main
+
+import (
+	//go packages
+	"net"
+	"sync"
+	"testing"
+
+	//local packages
+	"cache_info_server"
+	"caches"
+	"cache_protocol"
+	"scheduler/configuration"
+	"util"
+	"writables"
+)
+
+func TestParseCacheAddrs(t *testing.T) {
+	locations, err := parseCacheAddrs("a:1, b:2,")
+	if err != nil || len(locations) != 2 || locations[1].Hostname != "b" ||
+		locations[1].Port != "2" {
+		t.Fail()
+	}
+
+	_, err = parseCacheAddrs("nope")
+	if err == nil {
+		t.Fail()
+	}
+
+	_, err = parseCacheAddrs("")
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	if checkBlockIds([]string{"1", "x"}) == nil || checkBlockIds(nil) == nil {
+		t.Fail()
+	}
+
+	if checkClear([]string{"nope"}) == nil || 
+		checkClear([]string{"getlisting"}) != nil {
+		t.Fail()
+	}
+}
+
+//the caches log through util
+var initLoggers sync.Once
+
+//serves one client with a cache that holds block 1
+func startCache(t *testing.T, adminSecret string) configuration.CacheLocation {
+	initLoggers.Do(func() { util.Init() })
+	dataCache := caches.NewWritableDataCache(15)
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 1
+	pair, _ := dataCache.Join("", request)
+	pair.Complete()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		p := cache_info_server.NewProcessor(dataCache, conn)
+		p.AdminSecret = []byte(adminSecret)
+		p.HandleClient()
+	}()
+
+	hostname, port, _ := net.SplitHostPort(ln.Addr().String())
+	return *configuration.NewCacheLocation(hostname, port)
+}
+
+func TestRunCommand(t *testing.T) {
+	locations := []configuration.CacheLocation{startCache(t, "")}
+	results := runCommand(COMMANDS["blocks"], locations, nil, nil)
+	if len(results) != 1 || results[0].Error != "" {
+		t.FailNow()
+	}
+
+	if results[0].Result.(*cache_protocol.CachedBlocks).NumBlocks != 1 {
+		t.Fail()
+	}
+}
+
+func TestRunAdminCommand(t *testing.T) {
+	*secret = "s"
+	defer func() { *secret = "" }()
+
+	locations := []configuration.CacheLocation{startCache(t, "s")}
+	results := runCommand(COMMANDS["evict"], locations, nil, []string{"1"})
+	if len(results) != 1 || results[0].Error != "" || 
+		results[0].Result.(*dropped).Count != 1 {
+		t.Fail()
+	}
+
+	//pinning needs block locations, which this cache does not have
+	locations = []configuration.CacheLocation{startCache(t, "")}
+	results = runCommand(COMMANDS["pin"], locations, nil, []string{"1"})
+	if len(results) != 1 || results[0].Error == "" {
+		t.Fail()
+	}
+}
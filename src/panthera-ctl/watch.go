@@ -0,0 +1,97 @@
+package main
+
+import (
+	//go packages
+	"encoding/json"
+	"fmt"
+	"os"
+
+	//local packages
+	"caches"
+	"cache_protocol"
+	"scheduler/cache_comm"
+	"scheduler/configuration"
+)
+
+var EVENT_NAMES = map[uint16]string{
+	caches.CACHE_EVENT_INSERT: "insert",
+	caches.CACHE_EVENT_EVICT: "evict",
+	caches.CACHE_EVENT_PIN: "pin",
+	caches.CACHE_EVENT_UNPIN: "unpin",
+}
+
+//an event as watch prints it
+type watchedEvent struct {
+	Cache string
+	Seq uint64
+	Type string
+	BlockId uint64
+}
+
+func printEvent(event watchedEvent) {
+	if *jsonOutput {
+		//one object per line so that the output can be streamed
+		json.NewEncoder(os.Stdout).Encode(event)
+		return
+	}
+
+	fmt.Printf("%s\t%d\t%s\t%d\n", event.Cache, event.Seq, event.Type,
+		event.BlockId)
+}
+
+//passes the events of the cache at location on to events
+//until the subscription breaks
+func watchCache(location configuration.CacheLocation, authKey []byte,
+	events chan watchedEvent) error {
+	client, err := cache_comm.NewClient(location, authKey)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Subscribe()
+	if err != nil {
+		return err
+	}
+
+	for {
+		var event *cache_protocol.CacheEvent
+		event, err = client.NextEvent()
+		if err != nil {
+			return err
+		}
+
+		events <- watchedEvent{Cache: location.GetString(),
+			Seq: event.Seq,
+			Type: EVENT_NAMES[event.Type],
+			BlockId: event.BlockId}
+	}
+}
+
+//prints the events of all of the caches as they come in; returns
+//(the exit status) once none of the caches is left
+func watch(locations []configuration.CacheLocation, authKey []byte) int {
+	events := make(chan watchedEvent)
+	done := make(chan error)
+	for i := 0; i < len(locations); i++ {
+		go func(location configuration.CacheLocation) {
+			err := watchCache(location, authKey, events)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Stopped watching ", 
+					location.GetString(), ": ", err)
+			}
+			done <- err
+		}(locations[i])
+	}
+
+	for left := len(locations); left > 0; {
+		select {
+		case event := <-events:
+			printEvent(event)
+		case <-done:
+			left--
+		}
+	}
+
+	return 1
+}
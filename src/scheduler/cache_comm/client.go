@@ -2,6 +2,7 @@ package cache_comm
 
 import (
 	//go packages
+	"errors"
 	"net"
 	"fmt"
 
@@ -18,9 +19,16 @@ type Client struct {
 
 	//connection to the cacheLoc
 	Conn *writable_processor.Connection
+
+	//what the cache said about itself when we connected
+	Server *cache_protocol.Hello
 }
 
-func NewClient(loc configuration.CacheLocation) (*Client, error) {
+//connects to the cache at loc. Fails if the cache does not speak
+//our version of cache_protocol, or wants us to authenticate and
+//key (which may be empty) is not the one it has.
+func NewClient(loc configuration.CacheLocation, 
+	key []byte) (*Client, error) {
 	c := Client{}
 	c.CacheLoc = loc
 
@@ -31,21 +39,119 @@ func NewClient(loc configuration.CacheLocation) (*Client, error) {
 	}
 	c.Conn = writable_processor.NewConnection(conn)
 
+	err = c.hello(key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	return &c, nil
 }
 
+func (c *Client) hello(key []byte) error {
+	conn := c.Conn
+	err := cache_protocol.NewHello(0).Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.Server = new(cache_protocol.Hello)
+	err = cache_protocol.ReadResponse(conn, c.Server)
+	if err != nil || !c.Server.HasCapability(cache_protocol.CAP_AUTH) {
+		return err
+	}
+
+	return c.authenticate(key)
+}
+
+//answers the challenge the cache sends after its Hello
+func (c *Client) authenticate(key []byte) error {
+	if len(key) == 0 {
+		return errors.New("The cache at " + c.CacheLoc.GetString() + 
+			" requires authentication but no key was given.")
+	}
+
+	conn := c.Conn
+	challenge := new(cache_protocol.AuthChallenge)
+	err := challenge.Read(conn)
+	if err != nil {
+		return err
+	}
+
+	err = cache_protocol.NewAuthResponse(key, challenge).Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cache_protocol.ReadResponse(conn, nil)
+}
+
+//hangs up on the cache
+func (c *Client) Close() error {
+	return c.Conn.Close()
+}
+
+//fails unless the cache said it can do capability
+func (c *Client) require(capability uint64) error {
+	if c.Server == nil || !c.Server.HasCapability(capability) {
+		return errors.New("The cache at " + c.CacheLoc.GetString() + 
+			" does not support this request.")
+	}
+
+	return nil
+}
+
 func (c *Client) GetCacheDescription() (
 	*cache_protocol.CacheDescription, error) {
 
 	conn := c.Conn
 	req := cache_protocol.NewRequest(cache_protocol.REQ_CACHE_DESCRIPTION)
 	err := req.Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	res := cache_protocol.NewCacheDescription()
-	err = res.Read(conn)
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//asks the cache for its hit/miss counters and read latencies
+func (c *Client) GetCacheStats() (*cache_protocol.CacheStats, error) {
+	err := c.require(cache_protocol.CAP_STATS)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_CACHE_STATS)
+	err = req.Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := cache_protocol.NewCacheStats()
+	err = cache_protocol.ReadResponse(conn, res)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +166,10 @@ func (c *Client) GetCachedBlocks() (
 	fmt.Println("Writing request...")
 	req := cache_protocol.NewRequest(cache_protocol.REQ_CACHED_BLOCKS)
 	err := req.Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +177,7 @@ func (c *Client) GetCachedBlocks() (
 
 	fmt.Println("Reading request...")
 	res := cache_protocol.NewCachedBlocks()
-	err = res.Read(conn)
+	err = cache_protocol.ReadResponse(conn, res)
 	if err != nil {
 		return nil, err
 	}
@@ -75,3 +185,263 @@ func (c *Client) GetCachedBlocks() (
 
 	return res, nil
 }
+
+//sends a request of type requestType for blockIds and
+//reads back the status of each block
+func (c *Client) sendBlockList(requestType uint16, blockIds []uint64) (
+	*cache_protocol.BlockStatuses, error) {
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(requestType)
+	err := req.Write(conn)
+	if err == nil {
+		err = cache_protocol.NewBlockIdList(blockIds).Write(conn)
+	}
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := cache_protocol.NewBlockStatuses()
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//asks the cache to read blockIds from their DataNodes
+func (c *Client) PrefetchBlocks(blockIds []uint64) (
+	*cache_protocol.BlockStatuses, error) {
+	err := c.require(cache_protocol.CAP_BLOCK_REQUESTS)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sendBlockList(cache_protocol.REQ_PREFETCH_BLOCKS, blockIds)
+}
+
+//asks the cache to read blockIds and keep them until they are unpinned
+func (c *Client) PinBlocks(blockIds []uint64) (
+	*cache_protocol.BlockStatuses, error) {
+	err := c.require(cache_protocol.CAP_BLOCK_REQUESTS)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sendBlockList(cache_protocol.REQ_PIN_BLOCKS, blockIds)
+}
+
+func (c *Client) UnpinBlocks(blockIds []uint64) (
+	*cache_protocol.BlockStatuses, error) {
+	return c.sendBlockList(cache_protocol.REQ_UNPIN_BLOCKS, blockIds)
+}
+
+//asks the cache which blocks make up the files matching
+//paths (each of which may be a glob, e.g. "/data/part-*")
+func (c *Client) ResolvePaths(paths []string) (
+	*cache_protocol.ResolvedPaths, error) {
+	err := c.require(cache_protocol.CAP_RESOLVE_PATHS)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_RESOLVE_PATHS)
+	err = req.Write(conn)
+	if err == nil {
+		err = cache_protocol.NewPathList(paths).Write(conn)
+	}
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := cache_protocol.NewResolvedPaths()
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//asks the cache for the events that came after seq
+func (c *Client) GetCachedBlocksSince(seq uint64) (
+	*cache_protocol.CacheEvents, error) {
+	err := c.require(cache_protocol.CAP_EVENTS)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_CACHED_BLOCKS_SINCE)
+	err = req.Write(conn)
+	if err == nil {
+		since := cache_protocol.SinceRequest{Seq: seq}
+		err = since.Write(conn)
+	}
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := cache_protocol.NewCacheEvents()
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//turns this client's connection into a stream of cache events
+//(read them with NextEvent()); no other requests can be made on
+//it afterwards. Returns the sequence number of the last event
+//before the subscription started.
+func (c *Client) Subscribe() (uint64, error) {
+	err := c.require(cache_protocol.CAP_EVENTS)
+	if err != nil {
+		return 0, err
+	}
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_SUBSCRIBE)
+	err = req.Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	res := new(cache_protocol.Subscription)
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastSeq, nil
+}
+
+//waits for the next event after Subscribe(). An error means the
+//subscription is over (the cache drops subscribers that fall too
+//far behind); reconnect and catch up with GetCachedBlocksSince()
+func (c *Client) NextEvent() (*cache_protocol.CacheEvent, error) {
+	res := cache_protocol.NewCacheEvent()
+	err := res.Read(c.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+//logs in as an admin so that the admin requests below are
+//answered (they fail with ERR_UNAUTHORIZED otherwise). The
+//secret never goes over the wire; the cache challenges us to
+//HMAC a nonce with it instead.
+func (c *Client) AdminLogin(secret string) error {
+	err := c.require(cache_protocol.CAP_ADMIN)
+	if err != nil {
+		return err
+	}
+
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_ADMIN_LOGIN)
+	err = req.Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	challenge := new(cache_protocol.AuthChallenge)
+	err = cache_protocol.ReadResponse(conn, challenge)
+	if err != nil {
+		return err
+	}
+
+	err = cache_protocol.NewAuthResponse([]byte(secret), challenge).Write(conn)
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cache_protocol.ReadResponse(conn, nil)
+}
+
+//sends command; returns the number of cache entries it dropped
+func (c *Client) admin(command *cache_protocol.AdminCommand) (int, error) {
+	conn := c.Conn
+	req := cache_protocol.NewRequest(cache_protocol.REQ_ADMIN)
+	err := req.Write(conn)
+	if err == nil {
+		err = command.Write(conn)
+	}
+	if err == nil {
+		err = conn.Flush()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	res := new(cache_protocol.AdminResult)
+	err = cache_protocol.ReadResponse(conn, res)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.Count), nil
+}
+
+//drops every cached read of blockId
+func (c *Client) EvictBlock(blockId uint64) (int, error) {
+	command := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_BLOCK)
+	command.BlockId = blockId
+	return c.admin(command)
+}
+
+//drops the cached blocks of path and the metadata cached about it
+func (c *Client) EvictPath(path string) (int, error) {
+	command := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_EVICT_PATH)
+	command.Path = path
+	return c.admin(command)
+}
+
+//drops everything in target (one of cache_protocol.CACHE_*)
+func (c *Client) ClearCache(target uint16) (int, error) {
+	command := cache_protocol.NewAdminCommand(cache_protocol.ADMIN_CLEAR)
+	command.Target = target
+	return c.admin(command)
+}
+
+//turns target (one of cache_protocol.CACHE_*) on or off
+func (c *Client) SetCacheEnabled(target uint16, enabled bool) error {
+	op := cache_protocol.ADMIN_DISABLE
+	if enabled {
+		op = cache_protocol.ADMIN_ENABLE
+	}
+
+	command := cache_protocol.NewAdminCommand(op)
+	command.Target = target
+	_, err := c.admin(command)
+	return err
+}
@@ -17,6 +17,23 @@ type Configuration struct {
 	//Folder which has all of the job_info.JobInfo 
 	//description files
 	JobInfoDir string
+
+	//key shared with the caches (see CacheInfoKey in the cache's
+	//configuration); empty if they do not authenticate clients
+	CacheInfoKey string
+
+	//host:port of the NameNode that the files of jobs are
+	//resolved to blocks with (empty => ask the caches instead)
+	NameNodeAddress string
+
+	//HDFS user the scheduler talks to the NameNode as; the
+	//relative paths of jobs are relative to its home directory
+	HdfsUser string
+}
+
+//what the relative paths of jobs are relative to
+func (c *Configuration) HdfsWorkingDir() string {
+	return "/user/" + c.HdfsUser
 }
 
 func NewConfiguration() *Configuration {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	//go packages
+	"fmt"
+	"path"
+
+	//local packages
+	"scheduler/cache_comm"
+)
+
+//resolves the files of jobs to blocks through the caches, which
+//know the files they have seen a getBlockLocations for (used if
+//the scheduler is not given a NameNode to ask)
+type cacheResolver struct {
+	clients []*cache_comm.Client
+
+	//what relative paths are relative to
+	workingDir string
+}
+
+func newCacheResolver(clients []*cache_comm.Client,
+	workingDir string) *cacheResolver {
+	c := cacheResolver{clients: clients, workingDir: workingDir}
+	return &c
+}
+
+//the blocks any of the caches resolves globs to; caches that
+//cannot resolve paths are skipped
+func (c *cacheResolver) ResolveBlocks(globs []string) ([]uint64, error) {
+	absolute := make([]string, len(globs))
+	for i := 0; i < len(globs); i++ {
+		absolute[i] = globs[i]
+		if !path.IsAbs(globs[i]) {
+			absolute[i] = path.Join(c.workingDir, globs[i])
+		}
+	}
+
+	res := make([]uint64, 0)
+	seen := make(map[uint64]bool)
+	var lastErr error
+	for i := 0; i < len(c.clients); i++ {
+		resolved, err := c.clients[i].ResolvePaths(absolute)
+		if err != nil {
+			fmt.Println("Cache could not resolve paths, err: ", err)
+			lastErr = err
+			continue
+		}
+
+		for _, blockId := range resolved.BlockIds() {
+			if !seen[blockId] {
+				seen[blockId] = true
+				res = append(res, blockId)
+			}
+		}
+	}
+
+	//only give up if none of the caches could answer
+	if len(res) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return res, nil
+}
@@ -14,6 +14,7 @@ import (
 	//local packages
 	"cache_protocol"
 	"job_info"
+	"namenode_rpc"
 	"scheduler/configuration"
 	"scheduler/cache_comm"
 )
@@ -100,6 +101,41 @@ func runJobs(jobs []*job_info.JobInfo) {
 	}
 }
 
+//the BlockResolver for a scheduling round: the NameNode if the
+//configuration names one, else the caches. The returned function
+//hangs up on the NameNode once the resolver is no longer needed.
+func newResolver(conf *configuration.Configuration,
+	clients []*cache_comm.Client) (*job_info.RoundResolver, func() error,
+	error) {
+	if conf.NameNodeAddress == "" {
+		resolver := job_info.NewRoundResolver(
+			newCacheResolver(clients, conf.HdfsWorkingDir()))
+		return resolver, func() error { return nil }, nil
+	}
+
+	nameNode, err := namenode_rpc.NewClient(conf.NameNodeAddress,
+		conf.HdfsUser)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolver := job_info.NewRoundResolver(job_info.NewNameNodeResolver(
+		nameNode, conf.HdfsWorkingDir()))
+	return resolver, nameNode.Close, nil
+}
+
+//fills in the BlocksAccessed of jobs from their FilesAccessed;
+//a job whose files cannot be resolved keeps the blocks it lists
+func resolveJobs(resolver job_info.BlockResolver, jobs []*job_info.JobInfo) {
+	for i := 0; i < len(jobs); i++ {
+		err := jobs[i].GetBlocksAccessed(resolver)
+		if err != nil {
+			fmt.Println("Could not resolve files of job ", jobs[i].Name,
+				", err: ", err)
+		}
+	}
+}
+
 func main() {
 	scheduler := NewScheduler()
 
@@ -129,13 +165,17 @@ func main() {
 	}
 
 	//get a cache description
+	clients := make([]*cache_comm.Client, 0)
 	for i := 0; i < len(conf.CacheLocations); i++ { 
 		cacheLocation := conf.CacheLocations[i]
-		client, err := cache_comm.NewClient(cacheLocation)
+		client, err := cache_comm.NewClient(cacheLocation, 
+			[]byte(conf.CacheInfoKey))
 		if err != nil {
 			fmt.Println("Could not initialize client (quitting), err: ", err)
 			return
 		}
+		defer client.Close()
+		clients = append(clients, client)
 
 		descr, err := client.GetCacheDescription()
 		if err != nil {
@@ -157,6 +197,15 @@ func main() {
 		scheduler.Caches = append(scheduler.Caches, cacheInfo)
 	}
 
+	//work out the blocks of the files the jobs read
+	resolver, closeResolver, err := newResolver(conf, clients)
+	if err != nil {
+		fmt.Println("Could not connect to the NameNode, err: ", err)
+		return
+	}
+	resolveJobs(resolver, scheduler.Jobs)
+	closeResolver()
+
 	//sort the jobs
 	SortJobs(scheduler.Caches, scheduler.Jobs)
 
@@ -0,0 +1,65 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"net"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+//how long we wait on a DataNode when asking it for a checksum
+var BlockChecksumTimeout = 30 * time.Second
+
+//asks the DataNode at address for the OP_BLOCK_CHECKSUM of the block
+//that request reads (used by caches.DataCacheScrubber)
+func FetchBlockChecksum(address string, 
+	request *writables.ReadBlockHeader) (*writables.BlockChecksumResponse, error) {
+
+	dataNode, err := net.DialTimeout("tcp", address, BlockChecksumTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer dataNode.Close()
+	dataNode.SetDeadline(time.Now().Add(BlockChecksumTimeout))
+
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_BLOCK_CHECKSUM
+
+	checksumHeader := writables.NewBlockChecksumHeader()
+	checksumHeader.BlockId = request.BlockId
+	checksumHeader.GenerationStamp = request.Timestamp
+	checksumHeader.AccessToken = request.AccessToken
+
+	reqBuf := new(bytes.Buffer)
+	err = requestHeader.Write(reqBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checksumHeader.Write(reqBuf)
+	if err != nil {
+		return nil, err
+	}
+
+	dataNodeObj := NewConnection(dataNode)
+	_, err = dataNodeObj.Write(reqBuf.Bytes())
+	if err == nil {
+		err = dataNodeObj.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := writables.NewBlockChecksumResponse()
+	err = res.Read(dataNodeObj)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
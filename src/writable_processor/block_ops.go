@@ -0,0 +1,197 @@
+package writable_processor
+
+/*
+* Relaying of the small block operations: OP_READ_METADATA,
+* OP_BLOCK_CHECKSUM, OP_COPY_BLOCK and OP_REPLACE_BLOCK. The
+* request and the response of each are decoded and forwarded
+* one message at a time rather than byte by byte.
+*/
+
+import (
+	//go packages
+	"bytes"
+
+	//local packages
+	"util"
+	"writables"
+)
+
+//writes the request header followed by the op's own header
+//to the DataNode in one go
+func (w *WritableProcessor) forwardOpRequest(
+	requestHeader *writables.DataRequestHeader, opHeader writables.Writable,
+	dataNode writables.ReaderWriter) error {
+
+	reqBuf := new(bytes.Buffer)
+	err := requestHeader.Write(reqBuf)
+	if err != nil {
+		return err
+	}
+
+	err = opHeader.Write(reqBuf)
+	if err != nil {
+		return err
+	}
+
+	_, err = dataNode.Write(reqBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return flush(dataNode)
+}
+
+//reads a message from src and writes it to dst
+func relayWritable(msg writables.Writable, src writables.ReaderWriter,
+	dst writables.ReaderWriter) error {
+
+	err := msg.Read(src)
+	if err != nil {
+		return err
+	}
+
+	err = msg.Write(dst)
+	if err != nil {
+		return err
+	}
+
+	return flush(dst)
+}
+
+//logs err and returns false (i.e. the connection is not reusable)
+func (w *WritableProcessor) logOpError(op string, err error) bool {
+	util.DebugLogger.Println(w.id, "Error occurred in relaying ", op, ": ", err)
+	util.DebugLogger.Println(w.id, "Assuming socket is closed.")
+	return false
+}
+
+func (w *WritableProcessor) processReadMetadata(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
+
+	header := writables.NewReadMetadataHeader()
+	err := header.Read(conn)
+	if err == nil {
+		err = w.forwardOpRequest(requestHeader, header, dataNode)
+	}
+
+	if err == nil {
+		err = relayWritable(writables.NewReadMetadataResponse(), dataNode, conn)
+	}
+
+	if err != nil {
+		return w.logOpError("OP_READ_METADATA", err)
+	}
+
+	return true
+}
+
+//answers out of ChecksumCache if we can; otherwise asks the
+//DataNode and remembers what it said
+func (w *WritableProcessor) processBlockChecksum(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
+
+	header := writables.NewBlockChecksumHeader()
+	err := header.Read(conn)
+	if err != nil {
+		return w.logOpError("OP_BLOCK_CHECKSUM", err)
+	}
+
+	useCache := w.ChecksumCache != nil && 
+		w.mayUseCache(header.AccessToken, header.BlockId)
+
+	if useCache {
+		res := w.ChecksumCache.Query(header)
+		if res != nil {
+			util.TempLogger.Println(w.id, "Serving block checksum from cache: ",
+				header.BlockId)
+
+			err = res.Write(conn)
+			if err == nil {
+				err = flush(conn)
+			}
+
+			if err != nil {
+				return w.logOpError("OP_BLOCK_CHECKSUM", err)
+			}
+			return true
+		}
+	}
+
+	err = w.forwardOpRequest(requestHeader, header, dataNode)
+	if err != nil {
+		return w.logOpError("OP_BLOCK_CHECKSUM", err)
+	}
+
+	res := writables.NewBlockChecksumResponse()
+	err = relayWritable(res, dataNode, conn)
+	if err != nil {
+		return w.logOpError("OP_BLOCK_CHECKSUM", err)
+	}
+
+	if useCache {
+		w.ChecksumCache.Add(header, res)
+	}
+
+	return true
+}
+
+//the block that follows a successful response is relayed
+//packet by packet until the last one
+func (w *WritableProcessor) processCopyBlock(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
+
+	header := writables.NewCopyBlockHeader()
+	err := header.Read(conn)
+	if err == nil {
+		err = w.forwardOpRequest(requestHeader, header, dataNode)
+	}
+
+	res := writables.NewCopyBlockResponse()
+	if err == nil {
+		err = relayWritable(res, dataNode, conn)
+	}
+
+	if err != nil {
+		return w.logOpError("OP_COPY_BLOCK", err)
+	}
+
+	if res.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		return true
+	}
+
+	for {
+		packet := writables.NewWritePacket(res.Checksum)
+		err = relayWritable(packet, dataNode, conn)
+		if err != nil {
+			return w.logOpError("OP_COPY_BLOCK", err)
+		}
+
+		if packet.LastPacket != 0 {
+			return true
+		}
+	}
+}
+
+func (w *WritableProcessor) processReplaceBlock(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
+
+	header := writables.NewReplaceBlockHeader()
+	err := header.Read(conn)
+	if err == nil {
+		err = w.forwardOpRequest(requestHeader, header, dataNode)
+	}
+
+	if err == nil {
+		err = relayWritable(writables.NewReplaceBlockResponse(), dataNode, conn)
+	}
+
+	if err != nil {
+		return w.logOpError("OP_REPLACE_BLOCK", err)
+	}
+
+	return true
+}
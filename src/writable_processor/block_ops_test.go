@@ -0,0 +1,115 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"net"
+	"testing"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//answers an OP_BLOCK_CHECKSUM with response and counts it
+func checksumOp(response *writables.BlockChecksumResponse,
+	asked chan bool) fakeOp {
+	return func(connObj *Connection,
+		requestHeader *writables.DataRequestHeader) bool {
+		header := writables.NewBlockChecksumHeader()
+		err := header.Read(connObj)
+		if err != nil {
+			return false
+		}
+		asked <- true
+
+		resBuf := new(bytes.Buffer)
+		response.Write(resBuf)
+		connObj.Write(resBuf.Bytes())
+		connObj.Flush()
+		return false
+	}
+}
+
+func askChecksum(t *testing.T, w *WritableProcessor, 
+	dataNode net.Conn) *writables.BlockChecksumResponse {
+	//(net.Pipe() would block on the zero length reads
+	//of the empty token)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	proxySide, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxySide.Close()
+
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_BLOCK_CHECKSUM
+	go w.processBlockChecksum(requestHeader, NewConnection(proxySide), 
+		NewConnection(dataNode))
+
+	header := writables.NewBlockChecksumHeader()
+	header.BlockId = 9
+	header.GenerationStamp = 1001
+	clientObj := NewConnection(client)
+	header.Write(clientObj)
+	clientObj.Flush()
+
+	res := writables.NewBlockChecksumResponse()
+	err = res.Read(clientObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestBlockChecksumCached(t *testing.T) {
+	util.Init()
+
+	response := writables.NewBlockChecksumResponse()
+	response.Status = uint16(writables.OP_STATUS_SUCCESS)
+	response.BytesPerCrc = 512
+	response.CrcPerBlock = 2
+	response.Md5 = bytes.Repeat([]byte{7}, int(writables.MD5_SIZE))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	asked := make(chan bool, 2)
+	go serveFakeDataNode(ln, 1, nil, checksumOp(response, asked))
+
+	dataNode, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataNode.Close()
+
+	w := New(caches.NewWritableDataCache(10))
+	w.ChecksumCache = caches.NewBlockChecksumCache(10)
+
+	res := askChecksum(t, w, dataNode)
+	if !bytes.Equal(res.Md5, response.Md5) || len(asked) != 1 {
+		t.FailNow()
+	}
+
+	//the second time around the DataNode is not asked
+	res = askChecksum(t, w, dataNode)
+	if !bytes.Equal(res.Md5, response.Md5) || len(asked) != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,228 @@
+package writable_processor
+
+/*
+* Reading a block from a DataNode ourselves (rather than relaying
+* a client's OP_READ_BLOCK): used to prefetch blocks, warm caches
+* and check data against what the DataNode has.
+*/
+
+import (
+	//go packages
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+//returned by BlockReader when a packet's data does not match its
+//checksums
+var ErrBadChecksum = errors.New("Checksum mismatch in block packet.")
+
+type BlockReader struct {
+	//what was asked of the DataNode
+	Request *writables.ReadBlockHeader
+
+	//how long any single read or write may wait on the DataNode
+	Timeout time.Duration
+
+	//what the DataNode answered the request with
+	header *writables.BlockResponseHeader
+
+	conn net.Conn
+	dataNode *Connection
+
+	//the last packet has been read
+	done bool
+
+	//data that Read() has yet to hand out
+	buf []byte
+
+	//bytes at the start of the data that come before
+	//Request.StartOffset (the DataNode starts at a checksum
+	//chunk boundary)
+	skip uint64
+
+	//bytes of the requested range that Read() has yet to hand out
+	remaining uint64
+}
+
+//sends an OP_READ_BLOCK for request to the DataNode at address
+//and reads the response header. Returns an error if the DataNode
+//refuses the read.
+func NewBlockReader(address string, request *writables.ReadBlockHeader,
+	timeout time.Duration) (*BlockReader, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	b := BlockReader{Request: request, Timeout: timeout, conn: conn,
+		dataNode: NewConnection(conn), remaining: request.Length}
+	err = b.start()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (b *BlockReader) start() error {
+	b.conn.SetDeadline(time.Now().Add(b.Timeout))
+
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_READ_BLOCK
+
+	err := requestHeader.Write(b.dataNode)
+	if err != nil {
+		return err
+	}
+
+	err = b.Request.Write(b.dataNode)
+	if err != nil {
+		return err
+	}
+
+	err = b.dataNode.Flush()
+	if err != nil {
+		return err
+	}
+
+	//a DataNode that refuses the read sends only the status
+	b.header = writables.NewBlockResponseHeader()
+	b.header.Status, err = writables.ReadShortInt(b.dataNode)
+	if err != nil {
+		return err
+	}
+
+	if b.header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		return fmt.Errorf("DataNode refused to read block %d (status %d).",
+			b.Request.BlockId, b.header.Status)
+	}
+
+	err = b.header.Checksum.Read(b.dataNode)
+	if err != nil {
+		return err
+	}
+
+	b.header.ChunkOffset, err = writables.ReadLongInt(b.dataNode)
+	if err != nil {
+		return err
+	}
+
+	if b.header.ChunkOffset > b.Request.StartOffset {
+		return fmt.Errorf("DataNode started block %d at %d, after %d.",
+			b.Request.BlockId, b.header.ChunkOffset, b.Request.StartOffset)
+	}
+	b.skip = b.Request.StartOffset - b.header.ChunkOffset
+
+	return nil
+}
+
+func (b *BlockReader) ResponseHeader() *writables.BlockResponseHeader {
+	return b.header
+}
+
+//reads the next packet of the block and checks its checksums.
+//After the last packet the client's status goes back to the
+//DataNode and io.EOF is returned. The packets hold the whole
+//checksum chunks the DataNode sent, which may start before and
+//end after the requested range; Read() trims them. Do not mix
+//calls to NextPacket() and Read().
+func (b *BlockReader) NextPacket() (*writables.BlockPacket, error) {
+	if b.done {
+		return nil, io.EOF
+	}
+
+	b.conn.SetDeadline(time.Now().Add(b.Timeout))
+	packet := writables.NewBlockPacket(b.header)
+	err := packet.Read(b.dataNode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = packet.VerifyChecksums()
+	if err != nil {
+		writables.WriteShortInt(uint16(writables.OP_STATUS_ERROR_CHECKSUM),
+			b.dataNode)
+		b.dataNode.Flush()
+		return nil, ErrBadChecksum
+	}
+
+	if packet.LastPacket != 0 {
+		b.done = true
+
+		//like a well behaved client, tell the DataNode
+		//that the checksums were fine
+		err = writables.WriteShortInt(uint16(writables.OP_STATUS_CHECKSUM_OK),
+			b.dataNode)
+		if err == nil {
+			err = b.dataNode.Flush()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return packet, nil
+}
+
+//reads the bytes of the block from Request.StartOffset to
+//Request.StartOffset + Request.Length
+func (b *BlockReader) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		if b.remaining == 0 {
+			return 0, b.drain()
+		}
+
+		packet, err := b.NextPacket()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		data := packet.Data
+		if b.skip > 0 {
+			skip := b.skip
+			if skip > uint64(len(data)) {
+				skip = uint64(len(data))
+			}
+			data = data[skip:]
+			b.skip -= skip
+		}
+
+		if uint64(len(data)) > b.remaining {
+			data = data[0:b.remaining]
+		}
+		b.remaining -= uint64(len(data))
+		b.buf = data
+	}
+
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+//reads the packets past the end of the requested range so
+//that the DataNode gets our status
+func (b *BlockReader) drain() error {
+	for {
+		_, err := b.NextPacket()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *BlockReader) Close() error {
+	return b.conn.Close()
+}
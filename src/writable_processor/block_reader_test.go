@@ -0,0 +1,181 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	//local packages
+	"writables"
+)
+
+//answers an OP_READ_BLOCK for a range of data (see
+//writeRangeResponse()) and sends the status the client replied
+//with on statuses
+func rangeOp(data []byte, corrupt bool, statuses chan uint16) fakeOp {
+	return func(connObj *Connection,
+		requestHeader *writables.DataRequestHeader) bool {
+		request := writables.NewReadBlockHeader()
+		err := request.Read(connObj)
+		if err != nil {
+			return false
+		}
+
+		writeRangeResponse(connObj, request, data, corrupt)
+
+		status, err := writables.ReadShortInt(connObj)
+		if err == nil {
+			statuses <- status
+		}
+		return false
+	}
+}
+
+//writes the response to request for a range of data the way a
+//DataNode does: from the start of the checksum chunk the range
+//starts in, one chunk per packet, up to the end of the chunk it
+//ends in. If corrupt is true, the checksums of the second packet
+//are wrong.
+func writeRangeResponse(connObj *Connection,
+	request *writables.ReadBlockHeader, data []byte, corrupt bool) {
+	bytesPerChecksum := uint64(4)
+	start := request.StartOffset - request.StartOffset % bytesPerChecksum
+	end := request.StartOffset + request.Length
+	if end % bytesPerChecksum != 0 {
+		end += bytesPerChecksum - end % bytesPerChecksum
+	}
+	if end > uint64(len(data)) {
+		end = uint64(len(data))
+	}
+
+	header := writables.NewBlockResponseHeader()
+	header.Checksum.Type = writables.CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = uint32(bytesPerChecksum)
+	header.ChunkOffset = start
+
+	resBuf := new(bytes.Buffer)
+	header.Write(resBuf)
+
+	seqNo := uint64(0)
+	for offset := start; offset < end; offset += bytesPerChecksum {
+		packet := writables.NewBlockPacket(header)
+		packet.Offset = offset
+		packet.SeqNo = seqNo
+		packet.Data = data[offset:offset + bytesPerChecksum]
+		packet.Length = uint32(len(packet.Data))
+		packet.FillChecksums()
+		if corrupt && seqNo == 1 {
+			packet.ChecksumData[0]++
+		}
+		packet.PacketLength = uint32(4 + len(packet.ChecksumData) +
+			len(packet.Data))
+		packet.Write(resBuf)
+		seqNo++
+	}
+
+	last := writables.NewBlockPacket(header)
+	last.SeqNo = seqNo
+	last.LastPacket = 1
+	last.PacketLength = 4
+	last.Write(resBuf)
+	connObj.Write(resBuf.Bytes())
+	connObj.Flush()
+}
+
+func rangeRequest(offset uint64, length uint64) *writables.ReadBlockHeader {
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 12
+	request.StartOffset = offset
+	request.Length = length
+	return request
+}
+
+func TestBlockReaderRange(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	data := []byte("0123456789abcdef")
+	statuses := make(chan uint16, 1)
+	go serveFakeDataNode(ln, 1, nil, rangeOp(data, false, statuses))
+
+	reader, err := NewBlockReader(ln.Addr().String(), rangeRequest(6, 5),
+		time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	res, err := ioutil.ReadAll(reader)
+	if err != nil || string(res) != "6789a" {
+		t.Fatal("Wrong range: ", string(res), err)
+	}
+
+	select {
+	case status := <-statuses:
+		if status != uint16(writables.OP_STATUS_CHECKSUM_OK) {
+			t.Fatal("Wrong status: ", status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No status sent to the DataNode.")
+	}
+}
+
+func TestBlockReaderBadChecksum(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	statuses := make(chan uint16, 1)
+	go serveFakeDataNode(ln, 1, nil,
+		rangeOp([]byte("0123456789abcdef"), true, statuses))
+
+	reader, err := NewBlockReader(ln.Addr().String(), rangeRequest(0, 16),
+		time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	_, err = ioutil.ReadAll(reader)
+	if err != ErrBadChecksum {
+		t.Fatal("Corrupt packet not noticed: ", err)
+	}
+
+	if <-statuses != uint16(writables.OP_STATUS_ERROR_CHECKSUM) {
+		t.Fail()
+	}
+}
+
+func TestBlockReaderRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	refuse := func(connObj *Connection,
+		requestHeader *writables.DataRequestHeader) bool {
+		err := writables.NewReadBlockHeader().Read(connObj)
+		if err == nil {
+			writables.WriteShortInt(
+				uint16(writables.OP_STATUS_ERROR_ACCESS_TOKEN), connObj)
+			connObj.Flush()
+		}
+		return false
+	}
+	go serveFakeDataNode(ln, 1, nil, refuse)
+
+	_, err = NewBlockReader(ln.Addr().String(), rangeRequest(0, 16),
+		time.Second)
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,118 @@
+package writable_processor
+
+/*
+* Per-DataNode circuit breaker. After Threshold failures in a row
+* a DataNode is left alone for Cooldown; once that is up, a single
+* request is let through to see whether it has come back.
+*/
+
+import (
+	//go packages
+	"sync"
+	"time"
+)
+
+var DEFAULT_BREAKER_THRESHOLD = 3
+var DEFAULT_BREAKER_COOLDOWN = 30 * time.Second
+
+/* nodeHealth.State */
+const (
+	//requests go through
+	BREAKER_CLOSED = iota
+
+	//requests are not sent until the cooldown is over
+	BREAKER_OPEN
+
+	//one request has been let through to test the DataNode
+	BREAKER_HALF_OPEN
+)
+
+type nodeHealth struct {
+	State int
+
+	//failures in a row
+	Failures int
+
+	//when an open breaker lets a test request through
+	RetryAt time.Time
+}
+
+type DataNodeHealth struct {
+	sync.Mutex
+
+	Threshold int
+	Cooldown time.Duration
+
+	//address -> health of the DataNode at that address
+	nodes map[string]*nodeHealth
+}
+
+func NewDataNodeHealth(threshold int, cooldown time.Duration) *DataNodeHealth {
+	h := DataNodeHealth{Threshold: threshold, Cooldown: cooldown}
+	h.nodes = make(map[string]*nodeHealth)
+	return &h
+}
+
+//assumes the lock is held
+func (h *DataNodeHealth) node(address string) *nodeHealth {
+	n, present := h.nodes[address]
+	if !present {
+		n = &nodeHealth{State: BREAKER_CLOSED}
+		h.nodes[address] = n
+	}
+
+	return n
+}
+
+//true if a request may be sent to address. Every true returned
+//must be followed by a call to Success() or Failure().
+func (h *DataNodeHealth) Allow(address string) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	n := h.node(address)
+	switch n.State {
+	case BREAKER_OPEN:
+		if time.Now().Before(n.RetryAt) {
+			return false
+		}
+		n.State = BREAKER_HALF_OPEN
+		return true
+
+	case BREAKER_HALF_OPEN:
+		//someone is already testing it
+		return false
+	}
+
+	return true
+}
+
+func (h *DataNodeHealth) Success(address string) {
+	h.Lock()
+	defer h.Unlock()
+
+	n := h.node(address)
+	n.State = BREAKER_CLOSED
+	n.Failures = 0
+}
+
+func (h *DataNodeHealth) Failure(address string) {
+	h.Lock()
+	defer h.Unlock()
+
+	n := h.node(address)
+	n.Failures += 1
+	if n.State == BREAKER_HALF_OPEN || n.Failures >= h.Threshold {
+		n.State = BREAKER_OPEN
+		n.RetryAt = time.Now().Add(h.Cooldown)
+	}
+}
+
+//the state of the breaker for address (one of BREAKER_CLOSED,
+//BREAKER_OPEN or BREAKER_HALF_OPEN)
+func (h *DataNodeHealth) State(address string) int {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.node(address).State
+}
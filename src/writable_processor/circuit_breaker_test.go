@@ -0,0 +1,52 @@
+package writable_processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpens(t *testing.T) {
+	h := NewDataNodeHealth(2, time.Hour)
+	address := "10.0.0.1:50010"
+
+	h.Failure(address)
+	if !h.Allow(address) {
+		t.Fail()
+	}
+
+	h.Failure(address)
+	if h.Allow(address) || h.State(address) != BREAKER_OPEN {
+		t.Fail()
+	}
+
+	//other DataNodes are not affected
+	if !h.Allow("10.0.0.2:50010") {
+		t.Fail()
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	h := NewDataNodeHealth(1, time.Millisecond)
+	address := "10.0.0.1:50010"
+
+	h.Failure(address)
+	time.Sleep(5 * time.Millisecond)
+
+	//one test request goes through
+	if !h.Allow(address) || h.Allow(address) {
+		t.FailNow()
+	}
+
+	//and if it fails, the breaker opens again
+	h.Failure(address)
+	if h.State(address) != BREAKER_OPEN {
+		t.FailNow()
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	h.Allow(address)
+	h.Success(address)
+	if h.State(address) != BREAKER_CLOSED || !h.Allow(address) {
+		t.Fail()
+	}
+}
@@ -1,39 +1,258 @@
 package writable_processor
 
 import (
+	//go packages
+	"bufio"
+	"errors"
+	"io"
 	"net"
+	"time"
+
+	//local packages
+	"writables"
 )
 
+//size of the read and write buffers of a Connection;
+//big enough to hold a whole 64KB data packet
+var CONNECTION_BUFFER_SIZE = 128 * 1024
+
 //This structure is meant to add
 //ReadByte() and WriteByte() methods to net.Conn so that
-//it satisfies the writables.Reader and writables.Writer interfaces
+//it satisfies the writables.Reader and writables.Writer interfaces.
+//
+//Reads and writes are buffered, so whoever writes a message to
+//a Connection has to call Flush() once the message is complete
+//(nothing is sent until then). Only one Connection should be
+//made per net.Conn, since the read buffer may hold bytes that
+//belong to the next message.
 type Connection struct {
 	Conn net.Conn
+
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	//if set, Conn is only dialed once the Connection is first
+	//read from or written to (see NewLazyConnection())
+	dial Dialer
+	dialErr error
+
+	//an earlier operation went over Conn (see EndOp())
+	reused bool
 }
 
+//opens the connection behind a lazy Connection
+type Dialer func() (net.Conn, error)
+
 func NewConnection(conn net.Conn) *Connection {
-	c := Connection{Conn: conn}
+	c := Connection{}
+	c.setConn(conn)
+	return &c
+}
+
+//returns a Connection that calls dial the first time it is used,
+//so that nothing is dialed for a client that never needs the
+//other end (e.g. all of whose reads are cache hits)
+func NewLazyConnection(dial Dialer) *Connection {
+	c := Connection{dial: dial}
 	return &c
 }
 
+func (c *Connection) setConn(conn net.Conn) {
+	c.Conn = conn
+	c.reader = bufio.NewReaderSize(conn, CONNECTION_BUFFER_SIZE)
+	c.writer = bufio.NewWriterSize(conn, CONNECTION_BUFFER_SIZE)
+}
+
+//dials the connection if that has not been done yet. A failed
+//dial is not retried; every later call returns the same error.
+func (c *Connection) connect() error {
+	if c.Conn != nil {
+		return nil
+	}
+
+	if c.dialErr != nil {
+		return c.dialErr
+	}
+
+	if c.dial == nil {
+		c.dialErr = errors.New("Connection has nothing to dial.")
+		return c.dialErr
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		c.dialErr = err
+		return err
+	}
+
+	c.setConn(conn)
+	return nil
+}
+
+//true if the connection has been dialed
+func (c *Connection) Connected() bool {
+	return c.Conn != nil
+}
+
+//marks the end of an operation; the next one reuses Conn (if
+//it has been dialed), which the other end may have hung up on
+//in the meantime
+func (c *Connection) EndOp() {
+	c.reused = c.Conn != nil
+}
+
+//true if Conn was dialed for an earlier operation
+func (c *Connection) Reused() bool {
+	return c.reused
+}
+
+//hangs up and dials a new connection (only for lazy Connections)
+func (c *Connection) Redial() error {
+	if c.dial == nil {
+		return errors.New("Connection has nothing to dial.")
+	}
+
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+
+	c.Conn = nil
+	c.dialErr = nil
+	c.reused = false
+	return c.connect()
+}
+
 func (c *Connection) Read(p []byte) (n int, err error) {
-	return c.Conn.Read(p)
+	err = c.connect()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.reader.Read(p)
 }
 
 func (c *Connection) ReadByte() (byte, error) {
-	buf := make([]byte, 1)
+	err := c.connect()
+	if err != nil {
+		return 0, err
+	}
 
-	_, err := c.Read(buf)
-	return buf[0], err
+	return c.reader.ReadByte()
 }
 
 func (c *Connection) Write(p []byte) (n int, err error) {
-	return c.Conn.Write(p)
+	err = c.connect()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.writer.Write(p)
 }
 
 func (c *Connection) WriteByte(p byte) (err error) {
-	buf := []byte{p}
+	err = c.connect()
+	if err != nil {
+		return err
+	}
+
+	return c.writer.WriteByte(p)
+}
+
+//sends whatever has been written so far
+func (c *Connection) Flush() error {
+	if c.Conn == nil {
+		return c.dialErr
+	}
+
+	return c.writer.Flush()
+}
+
+func (c *Connection) Close() error {
+	if c.Conn == nil {
+		return nil
+	}
+
+	return c.Conn.Close()
+}
+
+//copies everything from src to dst until src is closed or
+//either side fails. When both are Connections, the bytes
+//already sitting in the buffers are passed on first and the
+//rest is copied straight between the sockets, which lets the
+//kernel splice TCP to TCP without going through userspace.
+func relay(dst writables.ReaderWriter, src writables.ReaderWriter) (int64, error) {
+	dstConn, dstOk := dst.(*Connection)
+	srcConn, srcOk := src.(*Connection)
+	if !dstOk || !srcOk {
+		n, err := io.Copy(dst, src)
+		if err == nil {
+			err = flush(dst)
+		}
+		return n, err
+	}
+
+	err := srcConn.connect()
+	if err == nil {
+		err = dstConn.connect()
+	}
+
+	if err != nil {
+		return 0, err
+	}
 
-	_, err = c.Conn.Write(buf)
-	return err
-}
\ No newline at end of file
+	buffered, _ := srcConn.reader.Peek(srcConn.reader.Buffered())
+	n, err := dstConn.writer.Write(buffered)
+	srcConn.reader.Discard(n)
+	if err != nil {
+		return int64(n), err
+	}
+
+	err = dstConn.Flush()
+	if err != nil {
+		return int64(n), err
+	}
+
+	copied, err := io.Copy(dstConn.Conn, srcConn.Conn)
+	return int64(n) + copied, err
+}
+
+//flushes writer if it is buffered (i.e. it is a Connection
+//rather than, say, a bytes.Buffer)
+func flush(writer writables.Writer) error {
+	flusher, ok := writer.(interface{ Flush() error })
+	if ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+//sets a read deadline timeout from now on conn if it is a Connection;
+//a zero timeout clears the deadline
+func setReadTimeout(conn writables.ReaderWriter, timeout time.Duration) {
+	c, ok := conn.(*Connection)
+	if !ok {
+		return
+	}
+
+	//nothing to time out on yet
+	if c.Conn == nil {
+		return
+	}
+
+	if timeout == 0 {
+		c.Conn.SetReadDeadline(time.Time{})
+		return
+	}
+
+	c.Conn.SetReadDeadline(time.Now().Add(timeout))
+}
+
+//closes conn if it can be closed (i.e. it is a
+//Connection rather than, say, a bytes.Buffer)
+func closeConn(conn writables.ReaderWriter) {
+	closer, ok := conn.(io.Closer)
+	if ok {
+		closer.Close()
+	}
+}
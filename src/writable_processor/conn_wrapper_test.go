@@ -0,0 +1,203 @@
+package writable_processor
+
+/*
+* Throughput of the data path over loopback. Each iteration
+* streams BENCH_CHUNK_SIZE bytes, so e.g.
+*
+*   go test -run XXX -bench . -benchtime 4096x writable_processor
+*
+* pushes 4GB through each relay (except the byte at a time one,
+* which is only there as a baseline; give it -benchtime 64x).
+*/
+
+import (
+	//go packages
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	//local packages
+	"writables"
+)
+
+var BENCH_CHUNK_SIZE = 1024 * 1024
+
+//returns both ends of a loopback TCP connection
+func loopback(t testing.TB) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, server
+}
+
+//writes total bytes to conn in BENCH_CHUNK_SIZE writes and closes it
+func source(conn net.Conn, total int) {
+	chunk := bytes.Repeat([]byte{0xab}, BENCH_CHUNK_SIZE)
+	for total > 0 {
+		n := len(chunk)
+		if n > total {
+			n = total
+		}
+
+		_, err := conn.Write(chunk[:n])
+		if err != nil {
+			break
+		}
+		total -= n
+	}
+	conn.Close()
+}
+
+//sets up source -> (in, out) -> sink and returns the two
+//ends that the relay under test should copy between along
+//with a channel that the sink sends its byte count on
+func relayPipeline(t testing.TB, total int) (net.Conn, net.Conn, chan int64) {
+	srcSide, in := loopback(t)
+	out, sinkSide := loopback(t)
+
+	go source(srcSide, total)
+
+	received := make(chan int64, 1)
+	go func() {
+		n, _ := io.Copy(ioutil.Discard, sinkSide)
+		sinkSide.Close()
+		received <- n
+	}()
+
+	return in, out, received
+}
+
+func TestRelay(t *testing.T) {
+	total := 3*BENCH_CHUNK_SIZE + 17
+	in, out, received := relayPipeline(t, total)
+
+	inObj := NewConnection(in)
+	outObj := NewConnection(out)
+
+	//leave some bytes in the read buffer; they 
+	//must not be lost by the relay
+	_, err := inObj.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := relay(outObj, inObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	if n != int64(total-1) || <-received != int64(total-1) {
+		t.Fail()
+	}
+}
+
+func TestConnectionFlush(t *testing.T) {
+	client, server := loopback(t)
+	defer client.Close()
+	defer server.Close()
+
+	clientObj := NewConnection(client)
+	writables.WriteLongInt(42, clientObj)
+
+	//nothing has been sent until the flush
+	serverObj := NewConnection(server)
+	clientObj.Flush()
+
+	val, err := writables.ReadLongInt(serverObj)
+	if err != nil || val != 42 {
+		t.Fail()
+	}
+}
+
+func BenchmarkRelay(b *testing.B) {
+	b.SetBytes(int64(BENCH_CHUNK_SIZE))
+	in, out, received := relayPipeline(b, b.N*BENCH_CHUNK_SIZE)
+	b.ResetTimer()
+
+	relay(NewConnection(out), NewConnection(in))
+	out.Close()
+	<-received
+}
+
+//the relay as generalProcessing() used to do it
+func BenchmarkRelayByteAtATime(b *testing.B) {
+	b.SetBytes(int64(BENCH_CHUNK_SIZE))
+	in, out, received := relayPipeline(b, b.N*BENCH_CHUNK_SIZE)
+	b.ResetTimer()
+
+	for {
+		buf := make([]byte, 1)
+		_, err := in.Read(buf)
+		if err != nil {
+			break
+		}
+
+		_, err = out.Write(buf)
+		if err != nil {
+			break
+		}
+	}
+	out.Close()
+	<-received
+}
+
+//decodes and re-encodes 64KB data packets, as 
+//handleReadBlockResponse() does
+func BenchmarkBlockPacketRelay(b *testing.B) {
+	header := writables.NewBlockResponseHeader()
+	header.Checksum.Type = writables.CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = 512
+
+	packet := writables.NewBlockPacket(header)
+	packet.Data = bytes.Repeat([]byte{0xab}, 64*1024)
+	packet.Length = uint32(len(packet.Data))
+	packet.FillChecksums()
+	packet.PacketLength = uint32(4 + len(packet.ChecksumData) + len(packet.Data))
+
+	encoded := new(bytes.Buffer)
+	packet.Write(encoded)
+	packetsPerChunk := BENCH_CHUNK_SIZE/len(packet.Data)
+
+	srcSide, in := loopback(b)
+	out, sinkSide := loopback(b)
+	go func() {
+		for i := 0; i < b.N*packetsPerChunk; i++ {
+			srcSide.Write(encoded.Bytes())
+		}
+		srcSide.Close()
+	}()
+	go io.Copy(ioutil.Discard, sinkSide)
+
+	b.SetBytes(int64(BENCH_CHUNK_SIZE))
+	b.ResetTimer()
+
+	inObj := NewConnection(in)
+	outObj := NewConnection(out)
+	for i := 0; i < b.N*packetsPerChunk; i++ {
+		p := writables.NewBlockPacket(header)
+		err := p.Read(inObj)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		p.Write(outObj)
+		outObj.Flush()
+	}
+	out.Close()
+}
@@ -0,0 +1,106 @@
+package writable_processor
+
+/*
+* Retrying OP_READ_BLOCK on the other replicas of a block (as
+* found in the getBlockLocations responses that went through the
+* proxy) when our DataNode is down or cannot serve the block.
+*/
+
+import (
+	//go packages
+	"net"
+	"time"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//how long we wait on a replica's DataNode when connecting to it
+var FailoverDialTimeout = 5 * time.Second
+
+type Failover struct {
+	BlockIndex *caches.BlockLocationIndex
+	Health *DataNodeHealth
+
+	//maps a DataNode name as it appears in LocatedBlocks (which may
+	//be one of our own relay ports) to the address to dial
+	Resolve func(name string) string
+}
+
+func NewFailover(blockIndex *caches.BlockLocationIndex, health *DataNodeHealth,
+	resolve func(name string) string) *Failover {
+	f := Failover{BlockIndex: blockIndex, Health: health, Resolve: resolve}
+	return &f
+}
+
+//the addresses of the replicas of blockId other than exclude
+func (f *Failover) Replicas(blockId uint64, exclude string) []string {
+	res := make([]string, 0)
+	if f.BlockIndex == nil {
+		return res
+	}
+
+	block := f.BlockIndex.LookupBlock(blockId)
+	if block == nil {
+		return res
+	}
+
+	for i := 0; i < len(block.InfoArr); i++ {
+		address := block.InfoArr[i].Id.Name
+		if f.Resolve != nil {
+			address = f.Resolve(address)
+		}
+
+		if address != exclude {
+			res = append(res, address)
+		}
+	}
+
+	return res
+}
+
+//tries the replicas of the block (other than exclude) in turn and
+//returns a connection to the first one that answered blockRequest
+//with success, along with its response header. Returns nil if none
+//of them did.
+func (f *Failover) ReadFromReplica(requestHeader *writables.DataRequestHeader,
+	blockRequest *writables.ReadBlockHeader, 
+	exclude string) (*Connection, *writables.BlockResponseHeader) {
+
+	replicas := f.Replicas(blockRequest.BlockId, exclude)
+	for i := 0; i < len(replicas); i++ {
+		if !f.Health.Allow(replicas[i]) {
+			continue
+		}
+
+		dataNode, err := net.DialTimeout("tcp", replicas[i], FailoverDialTimeout)
+		if err != nil {
+			util.DebugLogger.Println("Could not connect to replica ", 
+				replicas[i], ": ", err)
+			f.Health.Failure(replicas[i])
+			continue
+		}
+
+		dataNodeObj := NewConnection(dataNode)
+		header, err := requestBlock(requestHeader, blockRequest, dataNodeObj)
+		if err != nil {
+			f.Health.Failure(replicas[i])
+			dataNodeObj.Close()
+			continue
+		}
+		f.Health.Success(replicas[i])
+
+		if header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+			dataNodeObj.Close()
+			continue
+		}
+
+		util.DebugLogger.Println("Reading block ", blockRequest.BlockId, 
+			" from replica ", replicas[i])
+		return dataNodeObj, header
+	}
+
+	return nil, nil
+}
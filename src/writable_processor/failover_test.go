@@ -0,0 +1,123 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+func TestReadBlockFailover(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	data := []byte{1, 2, 3, 4, 5}
+	go serveFakeDataNode(ln, 1, nil, oneOp(readBlockOp(data, nil)))
+
+	//our DataNode is down; the other replica is up
+	primary := "127.0.0.1:1"
+	blockIndex := caches.NewBlockLocationIndex()
+	blockIndex.Add("/file", makeLocatedBlocks(primary, ln.Addr().String()))
+
+	health := NewDataNodeHealth(1, time.Hour)
+	w := New(caches.NewWritableDataCache(10))
+	w.DataNodeAddress = primary
+	w.Failover = NewFailover(blockIndex, health, nil)
+
+	dialDataNode := func() (net.Conn, error) {
+		return nil, errors.New("DataNode is down.")
+	}
+
+	client, proxySide := loopback(t)
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	//the processor logs until it returns, so it must not
+	//outlive the test
+	defer func() {
+		client.Close()
+		<-done
+	}()
+
+	clientObj := NewConnection(client)
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_READ_BLOCK
+	requestHeader.Write(clientObj)
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 1
+	request.Length = uint64(len(data))
+	request.Write(clientObj)
+	clientObj.Flush()
+
+	header := writables.NewBlockResponseHeader()
+	err = header.Read(clientObj)
+	if err != nil || header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		t.FailNow()
+	}
+
+	packet := writables.NewBlockPacket(header)
+	err = packet.Read(clientObj)
+	if err != nil || !bytes.Equal(packet.Data, data) {
+		t.Fail()
+	}
+
+	if health.State(primary) != BREAKER_OPEN {
+		t.Fail()
+	}
+}
+
+func makeLocatedBlocks(names ...string) *writables.LocatedBlocks {
+	locatedBlocks := writables.NewLocatedBlocks()
+	locatedBlocks.NumberOfBlocks = 1
+
+	block := writables.NewLocatedBlock()
+	block.B.BlockId = 1
+	block.B.NumBytes = 5
+	for i := 0; i < len(names); i++ {
+		info := writables.NewDataNodeInfo()
+		info.Id.Name = names[i]
+		block.InfoArr = append(block.InfoArr, info)
+	}
+	block.InfoLength = uint32(len(names))
+
+	locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr, block)
+	return locatedBlocks
+}
+
+func TestFailoverReplicas(t *testing.T) {
+	blockIndex := caches.NewBlockLocationIndex()
+	blockIndex.Add("/file", makeLocatedBlocks("a:1", "b:2", "c:3"))
+
+	resolve := func(name string) string {
+		if name == "c:3" {
+			return "d:4"
+		}
+		return name
+	}
+
+	f := NewFailover(blockIndex, NewDataNodeHealth(1, time.Hour), resolve)
+	replicas := f.Replicas(1, "a:1")
+	if len(replicas) != 2 || replicas[0] != "b:2" || replicas[1] != "d:4" {
+		t.Fail()
+	}
+
+	if len(f.Replicas(2, "a:1")) != 0 {
+		t.Fail()
+	}
+}
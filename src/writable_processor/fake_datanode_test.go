@@ -0,0 +1,115 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"net"
+
+	//local packages
+	"writables"
+)
+
+//what a fake DataNode does with one operation, whose request
+//header has already been read off connObj. Returning false
+//hangs up on the client.
+type fakeOp func(connObj *Connection,
+	requestHeader *writables.DataRequestHeader) bool
+
+//accepts connections on ln (conns of them, or until ln is closed
+//if conns is 0) and serves each in its own goroutine: request
+//headers are read and handed to handle until the client hangs up
+//or handle returns false. Sends true on accepted (if it is not
+//nil) for every connection.
+func serveFakeDataNode(ln net.Listener, conns int, accepted chan bool,
+	handle fakeOp) {
+	for i := 0; conns == 0 || i < conns; i++ {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		if accepted != nil {
+			accepted <- true
+		}
+
+		go serveFakeConn(conn, handle)
+	}
+}
+
+func serveFakeConn(conn net.Conn, handle fakeOp) {
+	defer conn.Close()
+	connObj := NewConnection(conn)
+
+	for {
+		requestHeader := writables.NewDataRequestHeader()
+		err := requestHeader.Read(connObj)
+		if err != nil {
+			return
+		}
+
+		if !handle(connObj, requestHeader) {
+			return
+		}
+	}
+}
+
+//handles one operation with handle, then hangs up (the way
+//Hadoop 1 DataNodes do)
+func oneOp(handle fakeOp) fakeOp {
+	return func(connObj *Connection,
+		requestHeader *writables.DataRequestHeader) bool {
+		handle(connObj, requestHeader)
+		return false
+	}
+}
+
+//answers an OP_READ_BLOCK with data and sends the status the
+//client replies with on statuses (if it is not nil)
+func readBlockOp(data []byte, statuses chan uint16) fakeOp {
+	return func(connObj *Connection,
+		requestHeader *writables.DataRequestHeader) bool {
+		request := writables.NewReadBlockHeader()
+		err := request.Read(connObj)
+		if err != nil {
+			return false
+		}
+
+		writeReadBlockResponse(connObj, data)
+
+		status, err := writables.ReadShortInt(connObj)
+		if err != nil {
+			return false
+		}
+
+		if statuses != nil {
+			statuses <- status
+		}
+		return true
+	}
+}
+
+//writes the response to an OP_READ_BLOCK for data (in one packet
+//followed by the empty last packet)
+func writeReadBlockResponse(connObj *Connection, data []byte) {
+	header := writables.NewBlockResponseHeader()
+	header.Checksum.Type = writables.CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = 4
+
+	resBuf := new(bytes.Buffer)
+	header.Write(resBuf)
+
+	packet := writables.NewBlockPacket(header)
+	packet.Length = uint32(len(data))
+	packet.Data = data
+	packet.FillChecksums()
+	packet.PacketLength = uint32(4 + len(packet.ChecksumData) + len(data))
+	packet.Write(resBuf)
+
+	last := writables.NewBlockPacket(header)
+	last.SeqNo = 1
+	last.LastPacket = 1
+	last.PacketLength = 4
+	last.Write(resBuf)
+	connObj.Write(resBuf.Bytes())
+	connObj.Flush()
+}
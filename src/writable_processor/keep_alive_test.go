@@ -0,0 +1,256 @@
+package writable_processor
+
+import (
+	//go packages
+	"net"
+	"testing"
+	"time"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//answers an OP_BLOCK_CHECKSUM; the md5 of block i is i repeated
+func md5Op(connObj *Connection,
+	requestHeader *writables.DataRequestHeader) bool {
+	header := writables.NewBlockChecksumHeader()
+	err := header.Read(connObj)
+	if err != nil {
+		return false
+	}
+
+	res := writables.NewBlockChecksumResponse()
+	res.Status = uint16(writables.OP_STATUS_SUCCESS)
+	res.Md5 = make([]byte, writables.MD5_SIZE)
+	for i := range res.Md5 {
+		res.Md5[i] = byte(header.BlockId)
+	}
+	res.Write(connObj)
+	connObj.Flush()
+	return true
+}
+
+func TestHandleClientKeepAlive(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan bool, 10)
+	go serveFakeDataNode(ln, 0, accepted, md5Op)
+
+	dialDataNode := func() (net.Conn, error) {
+		return net.Dial("tcp", ln.Addr().String())
+	}
+
+	client, proxySide := loopback(t)
+	defer client.Close()
+
+	w := New(caches.NewWritableDataCache(10))
+	w.IdleTimeout = 100 * time.Millisecond
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	clientObj := NewConnection(client)
+	for blockId := uint64(1); blockId <= 3; blockId++ {
+		requestHeader := writables.NewDataRequestHeader()
+		requestHeader.Version = writables.DATA_TRANSFER_VERSION
+		requestHeader.Op = writables.OP_BLOCK_CHECKSUM
+		requestHeader.Write(clientObj)
+
+		header := writables.NewBlockChecksumHeader()
+		header.BlockId = blockId
+		header.Write(clientObj)
+		clientObj.Flush()
+
+		res := writables.NewBlockChecksumResponse()
+		err = res.Read(clientObj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Md5[0] != byte(blockId) {
+			t.Fail()
+		}
+	}
+
+	//one DataNode connection for all three
+	if len(accepted) != 1 {
+		t.Fail()
+	}
+
+	//the idle client is hung up on
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Idle client was not disconnected.")
+	}
+
+	_, err = clientObj.ReadByte()
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestHandleClientKeepAliveReadBlock(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	statuses := make(chan uint16, 10)
+	go serveFakeDataNode(ln, 1, nil,
+		readBlockOp([]byte{1, 2, 3, 4, 5}, statuses))
+
+	dialDataNode := func() (net.Conn, error) {
+		return net.Dial("tcp", ln.Addr().String())
+	}
+
+	client, proxySide := loopback(t)
+
+	w := New(caches.NewWritableDataCache(10))
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	//the processor logs until it returns, so it must not
+	//outlive the test
+	defer func() {
+		client.Close()
+		<-done
+	}()
+
+	clientObj := NewConnection(client)
+	for blockId := uint64(1); blockId <= 2; blockId++ {
+		requestHeader := writables.NewDataRequestHeader()
+		requestHeader.Version = writables.DATA_TRANSFER_VERSION
+		requestHeader.Op = writables.OP_READ_BLOCK
+		requestHeader.Write(clientObj)
+
+		request := writables.NewReadBlockHeader()
+		request.BlockId = blockId
+		request.Length = 5
+		request.Write(clientObj)
+		clientObj.Flush()
+
+		header := writables.NewBlockResponseHeader()
+		err = header.Read(clientObj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			packet := writables.NewBlockPacket(header)
+			err = packet.Read(clientObj)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if packet.LastPacket != 0 {
+				break
+			}
+		}
+
+		writables.WriteShortInt(uint16(writables.OP_STATUS_CHECKSUM_OK), clientObj)
+		clientObj.Flush()
+
+		select {
+		case status := <-statuses:
+			if status != uint16(writables.OP_STATUS_CHECKSUM_OK) {
+				t.Fail()
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Status was not relayed to the DataNode.")
+		}
+	}
+}
+
+func TestHandleClientDataNodeHangsUp(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan bool, 10)
+	//one operation per connection, like Hadoop 1 DataNodes
+	go serveFakeDataNode(ln, 0, accepted,
+		oneOp(readBlockOp([]byte{1, 2, 3, 4, 5}, nil)))
+
+	dialDataNode := func() (net.Conn, error) {
+		return net.Dial("tcp", ln.Addr().String())
+	}
+
+	client, proxySide := loopback(t)
+
+	//a single failure would open the breaker
+	health := NewDataNodeHealth(1, time.Hour)
+	w := New(caches.NewWritableDataCache(10))
+	w.DataNodeAddress = ln.Addr().String()
+	w.Failover = NewFailover(nil, health, nil)
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	defer func() {
+		client.Close()
+		<-done
+	}()
+
+	clientObj := NewConnection(client)
+	for blockId := uint64(1); blockId <= 3; blockId++ {
+		requestHeader := writables.NewDataRequestHeader()
+		requestHeader.Version = writables.DATA_TRANSFER_VERSION
+		requestHeader.Op = writables.OP_READ_BLOCK
+		requestHeader.Write(clientObj)
+
+		request := writables.NewReadBlockHeader()
+		request.BlockId = blockId
+		request.Length = 5
+		request.Write(clientObj)
+		clientObj.Flush()
+
+		header := writables.NewBlockResponseHeader()
+		err = header.Read(clientObj)
+		if err != nil || header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+			t.Fatal("Read of block ", blockId, " failed: ", err)
+		}
+
+		for {
+			packet := writables.NewBlockPacket(header)
+			err = packet.Read(clientObj)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if packet.LastPacket != 0 {
+				break
+			}
+		}
+
+		writables.WriteShortInt(uint16(writables.OP_STATUS_CHECKSUM_OK), clientObj)
+		clientObj.Flush()
+	}
+
+	if len(accepted) != 3 {
+		t.Fatal("DataNode dialed ", len(accepted), " times.")
+	}
+
+	if health.State(ln.Addr().String()) != BREAKER_CLOSED {
+		t.Fatal("Hang up counted against the DataNode.")
+	}
+}
@@ -0,0 +1,249 @@
+package writable_processor
+
+/*
+* Sequential read-ahead. When a client reads block N of a file
+* (as described by the getBlockLocations responses recorded in a
+* caches.BlockLocationIndex), the next few blocks of that file are
+* read from the DataNode in the background and put in the data cache
+* so that the client's later OP_READ_BLOCK requests for them are hits.
+*/
+
+import (
+	//go packages
+	"io"
+	"sync"
+	"time"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//client name that prefetch reads are made under
+var PrefetchClientName = "panthera-prefetch"
+
+//how long a prefetch may wait on the DataNode for
+//any single read or write
+var PrefetchTimeout = 60 * time.Second
+
+type Prefetcher struct {
+	//where prefetched blocks go
+	DataCache *caches.WritableDataCache
+
+	//tells us which block comes next in a file
+	BlockIndex *caches.BlockLocationIndex
+
+	//address of the DataNode that the blocks are read from;
+	//only blocks with a replica there are prefetched
+	Address string
+
+	//maps a DataNode name as it appears in LocatedBlocks (which may
+	//be one of our own relay ports) to the address to dial
+	Resolve func(name string) string
+
+	//number of blocks to read ahead of the client
+	Depth int
+
+	//combined bytes/second that all prefetches of this
+	//Prefetcher may read from the DataNode (0 => no limit)
+	BandwidthCap int
+
+	throttle *throttle
+}
+
+func NewPrefetcher(dataCache *caches.WritableDataCache, 
+	blockIndex *caches.BlockLocationIndex, address string,
+	depth int, bandwidthCap int,
+	resolve func(name string) string) *Prefetcher {
+
+	p := Prefetcher{DataCache: dataCache,
+		BlockIndex: blockIndex,
+		Address: address,
+		Resolve: resolve,
+		Depth: depth,
+		BandwidthCap: bandwidthCap}
+	p.throttle = newThrottle(bandwidthCap)
+	return &p
+}
+
+//called when a client reads the block described by request;
+//starts fetching the blocks that follow it
+func (p *Prefetcher) BlockRead(request *writables.ReadBlockHeader) {
+	if p.Depth <= 0 || !p.DataCache.IsEnabled() {
+		return
+	}
+
+	next := p.BlockIndex.NextBlocks(request.BlockId, p.Depth)
+	for i := 0; i < len(next); i++ {
+		p.Fetch(next[i])
+	}
+}
+
+//starts reading block into the cache in the background (unless
+//someone is already reading it or has read it). Returns false if 
+//block has no replica on our DataNode.
+func (p *Prefetcher) Fetch(block *writables.LocatedBlock) bool {
+	if !p.hasReplica(block) {
+		return false
+	}
+
+	pair, filler := p.DataCache.Join(p.Address, p.makeRequest(block))
+	if filler {
+		go p.fetch(pair)
+	}
+
+	return true
+}
+
+//true if one of the replicas of block is on our DataNode
+func (p *Prefetcher) hasReplica(block *writables.LocatedBlock) bool {
+	for i := 0; i < len(block.InfoArr); i++ {
+		address := block.InfoArr[i].Id.Name
+		if p.Resolve != nil {
+			address = p.Resolve(address)
+		}
+
+		if address == p.Address {
+			return true
+		}
+	}
+
+	return false
+}
+
+//builds the request that a client reading the whole of block
+//would send (so that the cache key matches theirs)
+func (p *Prefetcher) makeRequest(
+	block *writables.LocatedBlock) *writables.ReadBlockHeader {
+
+	r := writables.NewReadBlockHeader()
+	r.BlockId = block.B.BlockId
+	r.Timestamp = block.B.GenerationStamp
+	r.StartOffset = 0
+	r.Length = block.B.NumBytes
+	r.ClientName.Bytes = []byte(PrefetchClientName)
+	r.ClientName.Length = int64(len(r.ClientName.Bytes))
+	r.AccessToken = block.BlockToken
+	return r
+}
+
+//reads the block requested by pair.Request from the DataNode
+//into pair
+func (p *Prefetcher) fetch(pair *writables.ReadPair) {
+	err := p.fill(pair)
+	if err != nil {
+		util.DebugLogger.Println("Prefetch of block ", pair.Request.BlockId, 
+			" failed: ", err)
+	}
+
+	if pair.Fail() {
+		p.DataCache.Remove(pair)
+	}
+}
+
+func (p *Prefetcher) fill(pair *writables.ReadPair) error {
+	reader, err := NewBlockReader(p.Address, pair.Request, PrefetchTimeout)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	pair.SetResponseHeader(reader.ResponseHeader())
+
+	for {
+		blockPacket, err := reader.NextPacket()
+		if err == io.EOF {
+			pair.Complete()
+			return nil
+		}
+
+		if err == ErrBadChecksum {
+			p.DataCache.RecordChecksumMismatch(pair)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		pair.AddBlockPacket(blockPacket)
+		p.throttle.wait(int(blockPacket.PacketLength))
+	}
+}
+
+/**
+* PrefetcherSet
+* The Prefetchers of all of the DataNodes we relay to; used to
+* fetch blocks that the scheduler asks for (see cache_info_server)
+*/
+type PrefetcherSet struct {
+	sync.RWMutex
+	prefetchers []*Prefetcher
+}
+
+func NewPrefetcherSet() *PrefetcherSet {
+	s := PrefetcherSet{}
+	s.prefetchers = make([]*Prefetcher, 0)
+	return &s
+}
+
+func (s *PrefetcherSet) Add(p *Prefetcher) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.prefetchers = append(s.prefetchers, p)
+}
+
+//fetches block through the first Prefetcher whose DataNode has
+//a replica of it. Returns false if there is no such Prefetcher.
+func (s *PrefetcherSet) Fetch(block *writables.LocatedBlock) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	for i := 0; i < len(s.prefetchers); i++ {
+		if s.prefetchers[i].Fetch(block) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+* throttle
+* Keeps the combined rate of a set of readers
+* under a given number of bytes/second
+*/
+type throttle struct {
+	sync.Mutex
+
+	//bytes/second (0 => no limit)
+	rate int
+
+	//the time at which everything read so far
+	//would have been read at exactly rate
+	next time.Time
+}
+
+func newThrottle(rate int) *throttle {
+	t := throttle{rate: rate}
+	return &t
+}
+
+//accounts for n bytes having been read and sleeps for
+//as long as it takes to get back under the rate
+func (t *throttle) wait(n int) {
+	if t.rate <= 0 {
+		return
+	}
+
+	t.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(n) * time.Second / time.Duration(t.rate))
+	delay := t.next.Sub(now)
+	t.Unlock()
+
+	time.Sleep(delay)
+}
@@ -0,0 +1,182 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//a file of count blocks of 5 bytes, each with a replica on the
+//DataNode called name
+func makeFileBlocks(count int, name string) *writables.LocatedBlocks {
+	locatedBlocks := writables.NewLocatedBlocks()
+	locatedBlocks.NumberOfBlocks = uint32(count)
+	for i := 0; i < count; i++ {
+		block := writables.NewLocatedBlock()
+		block.B.BlockId = uint64(i+1)
+		block.B.NumBytes = 5
+		info := writables.NewDataNodeInfo()
+		info.Id.Name = name
+		block.InfoLength = 1
+		block.InfoArr = []*writables.DataNodeInfo{info}
+		locatedBlocks.LocatedBlockArr = append(locatedBlocks.LocatedBlockArr, block)
+	}
+
+	return locatedBlocks
+}
+
+//reads block 1 of a two block file through p and checks that
+//block 2 ends up in the cache
+func checkReadAhead(t *testing.T, p *Prefetcher,
+	locatedBlocks *writables.LocatedBlocks) {
+	request := p.makeRequest(locatedBlocks.LocatedBlockArr[0])
+	p.BlockRead(request)
+
+	//block 2 should be on its way into the cache
+	next := p.makeRequest(locatedBlocks.LocatedBlockArr[1])
+	pair, filler := p.DataCache.Join(p.Address, next)
+	if filler {
+		t.FailNow()
+	}
+
+	packet, err := pair.WaitBlockPacket(0)
+	if err != nil || !bytes.Equal(packet.Data, []byte{1, 2, 3, 4, 5}) {
+		t.Fail()
+	}
+
+	for i := 0; i < 100 && !pair.IsComplete(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if p.DataCache.Query(p.Address, next) != pair {
+		t.Fail()
+	}
+}
+
+func TestPrefetcherBlockRead(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveFakeDataNode(ln, 1, nil,
+		oneOp(readBlockOp([]byte{1, 2, 3, 4, 5}, nil)))
+
+	locatedBlocks := makeFileBlocks(2, ln.Addr().String())
+	index := caches.NewBlockLocationIndex()
+	index.Add("/data/file", locatedBlocks)
+	dataCache := caches.NewWritableDataCache(10)
+
+	p := NewPrefetcher(dataCache, index, ln.Addr().String(), 1, 0, nil)
+	checkReadAhead(t, p, locatedBlocks)
+}
+
+//the NameNode knows the DataNode by the relay port it
+//registered through
+func TestPrefetcherRelayName(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveFakeDataNode(ln, 1, nil,
+		oneOp(readBlockOp([]byte{1, 2, 3, 4, 5}, nil)))
+
+	resolve := func(name string) string {
+		if name == "127.0.0.1:1389" {
+			return ln.Addr().String()
+		}
+		return name
+	}
+
+	locatedBlocks := makeFileBlocks(2, "127.0.0.1:1389")
+	index := caches.NewBlockLocationIndex()
+	index.Add("/data/file", locatedBlocks)
+	dataCache := caches.NewWritableDataCache(10)
+
+	//without resolving, the relay name is not our DataNode
+	p := NewPrefetcher(dataCache, index, ln.Addr().String(), 1, 0, nil)
+	if p.Fetch(locatedBlocks.LocatedBlockArr[1]) {
+		t.Fatal("Fetched a block without a replica.")
+	}
+
+	p = NewPrefetcher(dataCache, index, ln.Addr().String(), 1, 0, resolve)
+	checkReadAhead(t, p, locatedBlocks)
+}
+
+func TestThrottle(t *testing.T) {
+	th := newThrottle(1000)
+
+	start := time.Now()
+	th.wait(50)
+	th.wait(50)
+
+	//100 bytes at 1000 bytes/second
+	if time.Now().Sub(start) < 90 * time.Millisecond {
+		t.Fail()
+	}
+
+	//no limit
+	th = newThrottle(0)
+	start = time.Now()
+	th.wait(1000000)
+	if time.Now().Sub(start) > 10 * time.Millisecond {
+		t.Fail()
+	}
+}
+
+func TestPrefetcherSetRelayName(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveFakeDataNode(ln, 1, nil,
+		oneOp(readBlockOp([]byte{1, 2, 3, 4, 5}, nil)))
+
+	resolve := func(name string) string {
+		if name == "127.0.0.1:1390" {
+			return ln.Addr().String()
+		}
+		return name
+	}
+
+	locatedBlocks := makeFileBlocks(1, "127.0.0.1:1390")
+	index := caches.NewBlockLocationIndex()
+	index.Add("/data/file", locatedBlocks)
+	dataCache := caches.NewWritableDataCache(10)
+
+	s := NewPrefetcherSet()
+	s.Add(NewPrefetcher(dataCache, index, "127.0.0.1:1", 1, 0, resolve))
+	p := NewPrefetcher(dataCache, index, ln.Addr().String(), 1, 0, resolve)
+	s.Add(p)
+
+	block := locatedBlocks.LocatedBlockArr[0]
+	if !s.Fetch(block) {
+		t.Fatal("No Prefetcher found for the relay name.")
+	}
+
+	//the fetch went to the DataNode behind the relay port
+	pair, filler := dataCache.Join(p.Address, p.makeRequest(block))
+	if filler {
+		t.FailNow()
+	}
+
+	packet, err := pair.WaitBlockPacket(0)
+	if err != nil || !bytes.Equal(packet.Data, []byte{1, 2, 3, 4, 5}) {
+		t.Fail()
+	}
+}
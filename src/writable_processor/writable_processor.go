@@ -2,10 +2,12 @@ package writable_processor
 
 import (
 	//go packages
+	"io"
 	"net"
 	"math/rand"
 	"bytes"
 	"encoding/hex"
+	"time"
 
 	//local packages
 	"writables"
@@ -18,6 +20,10 @@ import (
 * uses the writables package instead of trying
 * to read in entire packets at a time */
 
+//how long an idle client connection is kept open by default
+//(the same as the DataNode's socket keepalive in Hadoop 2)
+var DEFAULT_IDLE_TIMEOUT = 4 * time.Second
+
 type WritableProcessor struct {
 	//id of this processor; mostly only used
 	//in the logs
@@ -30,15 +36,44 @@ type WritableProcessor struct {
 	//used to cache and query OP_READ_BLOCK requests
 	//and responses
 	dataCache *caches.WritableDataCache
+
+	//reads ahead of clients reading a file sequentially
+	//(nil => no read-ahead)
+	Prefetcher *Prefetcher
+
+	//if set, blocks written through this processor are put
+	//in dataCache once the pipeline has acknowledged them
+	WriteThrough bool
+
+	//caches OP_BLOCK_CHECKSUM responses (nil => no caching)
+	ChecksumCache *caches.BlockChecksumCache
+
+	//how long a client may sit on a connection between two 
+	//operations (or before sending the status after a read)
+	//before we hang up on it; 0 => forever
+	IdleTimeout time.Duration
+
+	//address of the DataNode this processor relays to (used
+	//to track its health and as part of the data cache key)
+	DataNodeAddress string
+
+	//if set, reads that our DataNode cannot serve are 
+	//retried on the block's other replicas
+	Failover *Failover
+
+	//if set, a client is only given cached data after its block
+	//token has been checked against these keys; otherwise it is
+	//sent to the DataNode (nil => cached data goes to anyone)
+	BlockKeys *caches.BlockKeyStore
 }
 
 func New(dataCache *caches.WritableDataCache) *WritableProcessor {
 	w := WritableProcessor{dataCache: dataCache}
-	w.dataCache.Enabled = false 
-	
+
 	//generate a random id number for this processor
 	w.id = rand.Int63n(999999999)
 	w.commChan = make(chan *CommMessage)
+	w.IdleTimeout = DEFAULT_IDLE_TIMEOUT
 	return &w
 }
 
@@ -77,14 +112,9 @@ func (w *WritableProcessor) readReadBlockRequest(reader writables.Reader) (*writ
 	//read in the block request
 	err := r.Read(reader)
 
-	//cache the request
-	pair := writables.NewReadPair(r)
-	w.dataCache.AddReadPair(pair)
-
 	return r, err
 }
 
-
 //debugging function; reads and logs "length" number of bytes
 //from "conn" 
 func (w *WritableProcessor) TempLogExtra(length int64, conn writables.ReaderWriter) {
@@ -98,150 +128,407 @@ func (w *WritableProcessor) TempLogExtra(length int64, conn writables.ReaderWrit
 	util.TempLogger.Println(hex.Dump(buf))
 }
 
-//this method is called to handle responses to an OP_READ_BLOCK request.
-//the response contains the contents of the actual block.
+//called by the goroutine filling pair once it is done with it. If the
+//pair never completed, it is failed (waking up anyone streaming from it)
+//and dropped from the cache so that it is never served.
+func (w *WritableProcessor) endFill(pair *writables.ReadPair) {
+	if pair.Fail() {
+		util.DebugLogger.Println(w.id, "Fill of block ", pair.Request.BlockId,
+			" failed; dropping it from the cache.")
+		w.dataCache.Remove(pair)
+	}
+}
+
+//this method is called to handle responses to an OP_READ_BLOCK request
+//(once header has been read from dataNode).
+//the response contains the contents of the actual block. Everything
+//read from the DataNode is also added to pair so that concurrent
+//readers of the same block can stream it (see serveFromPair()).
+//Returns the number of bytes of block data sent to the client and
+//true if the whole block was relayed.
 func (w *WritableProcessor) handleReadBlockResponse(
 	conn writables.ReaderWriter, 
 	dataNode writables.ReaderWriter,
-	requestHeader *writables.ReadBlockHeader) {
-	
-	var err error
-	//check the channel to make sure that the socket isn't closed
-	msg := w.readComm()
-	if msg != nil {
-		if msg.SocketClose {
-			return
-		}
-	}
-
-	header := writables.NewBlockResponseHeader()
-	err = header.Read(dataNode)
-	if err != nil {
-		defer w.sendSocketClose()
-		return
-	}
+	pair *writables.ReadPair, 
+	header *writables.BlockResponseHeader) (int64, bool) {
 
+	//unless we see the last packet, the pair is failed
+	defer w.endFill(pair)
+	
 	//write the header to the client
-	err = header.Write(conn)
+	err := header.Write(conn)
 	if err != nil {
-		defer w.sendSocketClose()
-		return
+		return 0, false
 	}
 
-	//check the cache to see if we can immediately
-	//write out the response
-	resPair := w.dataCache.Query(requestHeader)
-	util.TempLogger.Println("Request block id: ", requestHeader.BlockId)
-	util.TempLogger.Println("resPair (from cache): ", resPair)
-	//if it is available in the cache...
-	if resPair != nil && resPair.ResponseSet.Size() != 0 {
-		util.TempLogger.Println("Responded from cache.")
-		//...we write the BlockPackets to the client
-		blockPackets := resPair.ResponseSet.Chunks
-		for i := 0; i<len(blockPackets); i++ {
-			err := blockPackets[i].Write(conn)
-			if err != nil {
-				defer w.sendSocketClose()
-				return
-			}
-		}
-
-		//if we've sent out the cached responses,
-		//there is no point waiting for the server
-		//responses.
-		defer w.sendSocketClose()
-		return
+	//an error status is followed by no packets (and the DataNode
+	//hanging up); the client gets the status but there is nothing
+	//worth caching
+	if header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		util.DebugLogger.Println(w.id, "DataNode returned status ", 
+			header.Status, " for block ", pair.Request.BlockId)
+		flush(conn)
+		return 0, false
 	}
+	pair.SetResponseHeader(header)
+
+	//set to false once a packet fails its checksums; we keep 
+	//relaying to the client (which checks the data itself) but
+	//stop caching
+	caching := true
+	sent := int64(0)
 
 	for {
 		//read in the BlockPacket (contains part of the block)
 		blockPacket := writables.NewBlockPacket(header)
 		err = blockPacket.Read(dataNode)
 		if err != nil {
-			defer w.sendSocketClose()
-			return
+			return sent, false
+		}
+
+		if caching {
+			err = blockPacket.VerifyChecksums()
+			if err != nil {
+				util.DebugLogger.Println(w.id, "Not caching block ", 
+					pair.Request.BlockId, ": ", err)
+				pair.Fail()
+				w.dataCache.RecordChecksumMismatch(pair)
+				caching = false
+			}
 		}
 
 		//cache the blockpacket with the corresponding 
 		//pair
-		w.dataCache.AddBlockPacket(requestHeader, blockPacket)
+		if caching {
+			pair.AddBlockPacket(blockPacket)
+		}
+
+		//write the packet to the client (the header goes
+		//out with the first one)
+		err = blockPacket.Write(conn)
+		if err == nil {
+			err = flush(conn)
+		}
+
+		if err != nil {
+			return sent, false
+		}
+		sent += int64(len(blockPacket.Data))
+
+		if blockPacket.LastPacket != 0 {
+			if caching {
+				pair.Complete()
+			}
+			return sent, true
+		}
+	}
+}
+
+//serves an OP_READ_BLOCK request out of a pair that some other
+//processor is filling (or has filled) from the DataNode. The packets
+//are streamed to the client as they arrive so that concurrent readers
+//of one block only cost a single DataNode fetch. If the fill fails
+//part way through, the client connection is closed so that it never
+//mistakes the truncated stream for the whole block. Returns the number
+//of bytes of block data sent and true if the whole block was sent.
+func (w *WritableProcessor) serveFromPair(conn writables.ReaderWriter,
+	pair *writables.ReadPair) (int64, bool) {
+	util.TempLogger.Println(w.id, "Serving block from cache: ", 
+		pair.Request.BlockId)
+
+	header := pair.WaitResponseHeader()
+	if header == nil {
+		util.DebugLogger.Println(w.id, "Fill of block ", pair.Request.BlockId, 
+			" failed before a response header was read.")
+		closeConn(conn)
+		return 0, false
+	}
+
+	err := header.Write(conn)
+	if err != nil {
+		return 0, false
+	}
+
+	if header.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		flush(conn)
+		return 0, false
+	}
 
-		//write the packet to a buffer
-		resBuf := new(bytes.Buffer)
-		err = blockPacket.Write(resBuf)
+	sent := int64(0)
+	for i := 0; ; i++ {
+		blockPacket, err := pair.WaitBlockPacket(i)
 		if err != nil {
-			util.TempLogger.Println("Could not write to resBuf: ", err)
+			util.DebugLogger.Println(w.id, "Fill of block ", 
+				pair.Request.BlockId, " failed: ", err)
+			closeConn(conn)
+			return sent, false
+		}
+
+		if blockPacket == nil {
+			return sent, true
+		}
+
+		err = blockPacket.Write(conn)
+		if err == nil {
+			err = flush(conn)
 		}
 
-		//write the buffer to the connection
-		_, err = conn.Write(resBuf.Bytes())
 		if err != nil {
-			defer w.sendSocketClose()
+			return sent, false
 		}
+		sent += int64(len(blockPacket.Data))
+	}
+}
+
+//once a client has read a whole block, it replies with a status
+//(OP_STATUS_CHECKSUM_OK if the data checked out). The status is
+//passed on to dataNode if it is the one that sent the block (i.e. 
+//dataNode is not nil). Returns false if the client went away 
+//instead of replying.
+func (w *WritableProcessor) relayClientStatus(conn writables.ReaderWriter,
+	dataNode writables.ReaderWriter) bool {
+
+	setReadTimeout(conn, w.IdleTimeout)
+	defer setReadTimeout(conn, 0)
+
+	status, err := writables.ReadShortInt(conn)
+	if err != nil {
+		util.TempLogger.Println(w.id, "No status from client after block: ", err)
+		return false
+	}
+
+	if dataNode == nil {
+		return true
+	}
+
+	err = writables.WriteShortInt(status, dataNode)
+	if err == nil {
+		err = flush(dataNode)
 	}
+
+	return err == nil
 }
 
 func (w *WritableProcessor) processReadBlock(
 requestHeader *writables.DataRequestHeader, 
-	conn writables.ReaderWriter, dataNode writables.ReaderWriter) {
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
 
 	blockRequest, err := w.readReadBlockRequest(conn)
 	if err != nil {
 		util.DebugLogger.Println(w.id, "Error occurred in reading block request from client: ", err)
 		util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-		go w.sendSocketClose()
-		return
+		return false
+	}
+	start := time.Now()
+
+	//if another reader already has this block (or is in the
+	//middle of fetching it), we stream from them instead of
+	//going to the DataNode
+	var pair *writables.ReadPair
+	filler := true
+	if w.mayUseCache(blockRequest.AccessToken, blockRequest.BlockId) {
+		pair, filler = w.dataCache.Join(w.DataNodeAddress, blockRequest)
+	} else {
+		//the DataNode gets to decide; whatever it sends
+		//this client stays out of the cache
+		pair = writables.NewReadPair(blockRequest)
+		pair.DataNode = w.DataNodeAddress
+	}
+
+	//start fetching the blocks the client will want next
+	if w.Prefetcher != nil {
+		w.Prefetcher.BlockRead(blockRequest)
+	}
+
+	if !filler {
+		sent, ok := w.serveFromPair(conn, pair)
+		if ok {
+			w.dataCache.Stats.RecordHit(blockRequest.BlockId, sent, 
+				time.Since(start))
+		}
+		return ok && w.relayClientStatus(conn, nil)
+	}
+
+	header, source := w.openBlock(requestHeader, blockRequest, dataNode)
+	if header == nil {
+		util.DebugLogger.Println(w.id, "No DataNode could serve block ", 
+			blockRequest.BlockId)
+		w.endFill(pair)
+		return false
+	}
+
+	if source != dataNode {
+		defer closeConn(source)
+	}
+
+	util.TempLogger.Println("Processed readBlock.")
+	sent, ok := w.handleReadBlockResponse(conn, source, pair, header)
+	if ok {
+		w.dataCache.Stats.RecordMiss(blockRequest.BlockId, sent, 
+			time.Since(start))
+	}
+	ok = ok && w.relayClientStatus(conn, source)
+
+	//after a failover, our own DataNode connection is in
+	//no state to take another request
+	return ok && source == dataNode
+}
+
+//true if the holder of token may be given cached data of 
+//blockId (always the case if BlockKeys is not set)
+func (w *WritableProcessor) mayUseCache(token *writables.Token,
+	blockId uint64) bool {
+	if w.BlockKeys == nil {
+		return true
+	}
+
+	err := w.BlockKeys.Validate(token, blockId, writables.ACCESS_MODE_READ)
+	if err != nil {
+		util.DebugLogger.Println(w.id, "Not using the cache for block ", 
+			blockId, ": ", err)
+		return false
 	}
 
-	//start handling the response from the server
-	go w.handleReadBlockResponse(conn, dataNode, blockRequest)
+	return true
+}
+
+//sends an OP_READ_BLOCK for blockRequest to dataNode and
+//reads back the BlockResponseHeader
+func requestBlock(requestHeader *writables.DataRequestHeader,
+	blockRequest *writables.ReadBlockHeader, 
+	dataNode writables.ReaderWriter) (*writables.BlockResponseHeader, error) {
 
 	resBuf := new(bytes.Buffer)
 	requestHeader.Write(resBuf)
 	blockRequest.Write(resBuf)
-	util.TempLogger.Println("Res buf (in processReadBlock()): ")
+	util.TempLogger.Println("Res buf (in requestBlock()): ")
 	util.TempLogger.Println("\n", hex.Dump(resBuf.Bytes()))
 
-	_, err = dataNode.Write(resBuf.Bytes())
+	_, err := dataNode.Write(resBuf.Bytes())
+	if err == nil {
+		err = flush(dataNode)
+	}
+
 	if err != nil {
-		util.DebugLogger.Println(w.id, "Error occurred in writing block to dataNode: ", err)
-		util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-		go w.sendSocketClose()
-		return
+		return nil, err
 	}
 
-	util.TempLogger.Println("Processed readBlock.")
+	header := writables.NewBlockResponseHeader()
+	err = header.Read(dataNode)
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
 }
 
-//this method is called from generalProcessing()
-func (w *WritableProcessor) handleGeneralResponse(
-conn writables.ReaderWriter, dataNode writables.ReaderWriter) {
-	for {
-		//check the channel to make sure that the socket isn't closed
-		msg := w.readComm()
-		if msg != nil {
-			if msg.SocketClose {
-				return
-			}
+//asks our DataNode for the block and, if it is down or answers with
+//an error, the other replicas. Returns the response header along with
+//the connection that the rest of the block is to be read from. If no
+//replica had the block, that is our DataNode's error response; if no
+//DataNode could be reached at all, the header is nil.
+func (w *WritableProcessor) openBlock(requestHeader *writables.DataRequestHeader,
+	blockRequest *writables.ReadBlockHeader, 
+	dataNode writables.ReaderWriter) (*writables.BlockResponseHeader, 
+	writables.ReaderWriter) {
+
+	var header *writables.BlockResponseHeader
+	if w.allowDataNode() {
+		var err error
+		header, err = requestBlock(requestHeader, blockRequest, dataNode)
+		if err != nil && w.redialStale(dataNode, err) {
+			header, err = requestBlock(requestHeader, blockRequest, dataNode)
 		}
 
-		buf := make([]byte, 1)
-		_, err := dataNode.Read(buf)
 		if err != nil {
-			util.DebugLogger.Println(w.id, "Error occurred while reading from dataNode in handleGeneralResponse()")
-			util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-			go w.sendSocketClose()
-			return
+			util.DebugLogger.Println(w.id, "Error occurred in requesting block from dataNode: ", err)
+			w.recordDataNodeHealth(false)
+		} else {
+			w.recordDataNodeHealth(true)
+			if header.Status == uint16(writables.OP_STATUS_SUCCESS) {
+				return header, dataNode
+			}
 		}
+	}
 
-		_, err = conn.Write(buf)
-		if err != nil {
-			util.DebugLogger.Println(w.id, "Error occurred while writing to client in handleGeneralResponse()s")
-			util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-			go w.sendSocketClose()
+	if w.Failover != nil {
+		replica, replicaHeader := w.Failover.ReadFromReplica(requestHeader, 
+			blockRequest, w.DataNodeAddress)
+		if replica != nil {
+			return replicaHeader, replica
+		}
+	}
+
+	return header, dataNode
+}
+
+//DataNodes that serve one operation per connection hang up once
+//it is done, which we only notice when the next operation of
+//the client fails on the connection. If err is what that looks
+//like on a connection an earlier operation used, dials a new
+//one and returns true, so that the operation can be retried
+//without counting against the DataNode.
+func (w *WritableProcessor) redialStale(dataNode writables.ReaderWriter,
+	err error) bool {
+	conn, ok := dataNode.(*Connection)
+	if !ok || !conn.Reused() || !isHangUp(err) {
+		return false
+	}
+
+	util.DebugLogger.Println(w.id, "DataNode hung up on reused connection (",
+		err, "), dialing it again.")
+	return conn.Redial() == nil
+}
+
+//true if err is what reading from or writing to a connection the
+//other end has closed returns (rather than, say, a timeout)
+func isHangUp(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	netErr, ok := err.(net.Error)
+	return ok && !netErr.Timeout()
+}
+
+func (w *WritableProcessor) allowDataNode() bool {
+	if w.Failover == nil || w.Failover.Health == nil {
+		return true
+	}
+
+	return w.Failover.Health.Allow(w.DataNodeAddress)
+}
+
+func (w *WritableProcessor) recordDataNodeHealth(success bool) {
+	if w.Failover == nil || w.Failover.Health == nil {
+		return
+	}
+
+	if success {
+		w.Failover.Health.Success(w.DataNodeAddress)
+	} else {
+		w.Failover.Health.Failure(w.DataNodeAddress)
+	}
+}
+
+//this method is called from generalProcessing(); it relays
+//everything the DataNode sends to the client
+func (w *WritableProcessor) handleGeneralResponse(
+conn writables.ReaderWriter, dataNode writables.ReaderWriter) {
+	//check the channel to make sure that the socket isn't closed
+	msg := w.readComm()
+	if msg != nil {
+		if msg.SocketClose {
 			return
 		}
 	}
+
+	_, err := relay(conn, dataNode)
+	util.DebugLogger.Println(w.id, "Relay from dataNode in handleGeneralResponse() ended: ", err)
+	util.DebugLogger.Println(w.id, "Assuming socket is closed.")
+
+	//unblocks generalProcessing()
+	closeConn(conn)
 }
 
 func (w *WritableProcessor) forwardRequestHeader(
@@ -254,8 +541,11 @@ error {
 	}
 
 	_, err = dataNode.Write(resBuf.Bytes())
+	if err != nil {
+		return err
+	}
 
-	return err
+	return flush(dataNode)
 }
 
 func (w *WritableProcessor) GeneralProcessing(conn net.Conn, 
@@ -280,67 +570,86 @@ replaceResponse bool) {
 		go w.handleGeneralResponse(conn, dataNode)
 	}
 
-	for {
-		//check the channel to make sure that the socket isn't closed
-		msg := w.readComm()
-		if msg != nil {
-			if msg.SocketClose {
-				return
-			}
-		}	
-
-		buf := make([]byte, 1)
-		_, err := conn.Read(buf)
-		if err != nil {
-			util.DebugLogger.Println(w.id, "Error occurred while reading 
-			from client in generalProcessing(): ", err)
-			util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-			go w.sendSocketClose()
-			return
-		}
-
-		_, err = dataNode.Write(buf)
-		if err != nil {
-			util.DebugLogger.Println(w.id, "Error occurred while writing 
-			to DataNode in generalProcessing(): ", err)
-			util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-			go w.sendSocketClose()
+	//check the channel to make sure that the socket isn't closed
+	msg := w.readComm()
+	if msg != nil {
+		if msg.SocketClose {
 			return
 		}
 	}
+
+	_, err := relay(dataNode, conn)
+	util.DebugLogger.Println(w.id, "Relay from client in generalProcessing() ended: ", err)
+	util.DebugLogger.Println(w.id, "Assuming socket is closed.")
+	closeConn(dataNode)
 }
 
+//processes one operation. Returns true if it went through cleanly
+//and both connections can be used for the next one.
 func (w *WritableProcessor) processRequest(
 requestHeader *writables.DataRequestHeader, conn writables.ReaderWriter,
-	dataNode writables.ReaderWriter) {
+	dataNode writables.ReaderWriter) bool {
 	//what kind of processing we do depends on
 	//the type of command given (stored as field Op 
 	//in DataRequestHeader)
 	switch(requestHeader.Op) {
 	case writables.OP_READ_BLOCK:
 		util.TempLogger.Println("Received a OP_READ_BLOCK request.")
-		go w.generalProcessing(conn, dataNode, false)
-		w.processReadBlock(requestHeader, conn, dataNode)
+		return w.processReadBlock(requestHeader, conn, dataNode)
+
+	case writables.OP_WRITE_BLOCK:
+		util.TempLogger.Println("Received a OP_WRITE_BLOCK request.")
+		return w.processWriteBlock(requestHeader, conn, dataNode)
+
+	case writables.OP_READ_METADATA:
+		util.TempLogger.Println("Received a OP_READ_METADATA request.")
+		return w.processReadMetadata(requestHeader, conn, dataNode)
+
+	case writables.OP_BLOCK_CHECKSUM:
+		util.TempLogger.Println("Received a OP_BLOCK_CHECKSUM request.")
+		return w.processBlockChecksum(requestHeader, conn, dataNode)
+
+	case writables.OP_COPY_BLOCK:
+		util.TempLogger.Println("Received a OP_COPY_BLOCK request.")
+		return w.processCopyBlock(requestHeader, conn, dataNode)
+
+	case writables.OP_REPLACE_BLOCK:
+		util.TempLogger.Println("Received a OP_REPLACE_BLOCK request.")
+		return w.processReplaceBlock(requestHeader, conn, dataNode)
 
 	default:
+		//we don't know where this operation ends, so the 
+		//connections are relayed as they are until one of
+		//the sides hangs up
 		util.TempLogger.Println("Received some other kind of request, Op: ", requestHeader.Op)
 		err := w.forwardRequestHeader(requestHeader, dataNode)
 		if err != nil {
 			util.DebugLogger.Println(w.id, "Unable to forward request header to dataNode in generalProcessing(): ", err)
 			util.DebugLogger.Println(w.id, "Assuming socket is closed.")
-			go w.sendSocketClose()
-			return
+			return false
 		}
-		go w.generalProcessing(conn, dataNode, true)
+		w.generalProcessing(conn, dataNode, true)
+		return false
 	}
 }
 
-//talk with the client; cache and forward requests
+//talk with the client; cache and forward requests. Clients (and
+//DataNodes) may send any number of operations over one connection;
+//they are processed one after the other, over the same DataNode
+//connection, until either side hangs up or the client has been
+//idle for IdleTimeout. The DataNode is only dialed once an operation
+//needs it, so a client whose reads are all hits never costs one.
 //run as goroutine from main.go
-func (w *WritableProcessor) HandleClient(conn net.Conn, dataNode net.Conn) {
+func (w *WritableProcessor) HandleClient(conn net.Conn, dialDataNode Dialer) {
 	util.TempLogger.Println("HandleClient() called.")
 
-	for {
+	//we convert the net.Conn's into Connection objects
+	connObj := NewConnection(conn)
+	dataNodeObj := NewLazyConnection(dialDataNode)
+	defer connObj.Close()
+	defer dataNodeObj.Close()
+
+	for ops := 0; ; ops++ {
 		//check the channel to make sure that the socket isn't closed
 		msg := w.readComm()
 		if msg != nil {
@@ -349,20 +658,26 @@ func (w *WritableProcessor) HandleClient(conn net.Conn, dataNode net.Conn) {
 			}
 		}
 
-		//we convert the net.Conn's into Connection objects
-		connObj := NewConnection(conn)
-		dataNodeObj := NewConnection(dataNode)
+		//the first request is waited for as long as it takes
+		//(the client just connected to send it), later ones 
+		//only for IdleTimeout
+		if ops > 0 {
+			setReadTimeout(connObj, w.IdleTimeout)
+		}
 
 		//read in the request header (blocking call)
 		requestHeader := w.ReadRequestHeader(connObj)
 		if requestHeader == nil {
 			return
 		}
+		setReadTimeout(connObj, 0)
 
 		util.TempLogger.Println("Request header: ", requestHeader)
 
 		//now we can process the request
-		w.processRequest(requestHeader, connObj, dataNodeObj)
-		return
+		if !w.processRequest(requestHeader, connObj, dataNodeObj) {
+			return
+		}
+		dataNodeObj.EndOp()
 	}
 }
@@ -3,12 +3,16 @@ package writable_processor
 import (
 	"testing"
 	"bytes"
+	"errors"
 	"fmt"
+	"net"
 	"util"
+	"caches"
+	"writables"
 )
 
 func TestWritableProcessorNew (t *testing.T) {
-	w := New()
+	w := New(caches.NewWritableDataCache(15))
 	if w == nil {
 		t.Fail()
 	}
@@ -25,7 +29,7 @@ func setup() {
 
 func TestReadRequestHeader(t *testing.T) {
 	setup()
-	w := New()
+	w := New(caches.NewWritableDataCache(15))
 	d := w.ReadRequestHeader(dataRequestBuffer)
 
 	if d.Version != 17 {
@@ -38,3 +42,142 @@ func TestReadRequestHeader(t *testing.T) {
 	}
 }
 
+
+//a hit must be answered without ever dialing the DataNode
+func TestHandleClientCacheHit(t *testing.T) {
+	util.Init()
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 5
+	request.Length = 5
+
+	header := writables.NewBlockResponseHeader()
+	header.Checksum.Type = writables.CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = 4
+
+	packet := writables.NewBlockPacket(header)
+	packet.Data = []byte{1, 2, 3, 4, 5}
+	packet.Length = 5
+	packet.FillChecksums()
+	packet.PacketLength = uint32(4 + len(packet.ChecksumData) + 5)
+
+	last := writables.NewBlockPacket(header)
+	last.SeqNo = 1
+	last.LastPacket = 1
+	last.PacketLength = 4
+
+	pair := writables.NewReadPair(request)
+	pair.SetResponseHeader(header)
+	pair.AddBlockPacket(packet)
+	pair.AddBlockPacket(last)
+	pair.Complete()
+
+	dataCache := caches.NewWritableDataCache(10)
+	w := New(dataCache)
+	dataCache.AddReadPair(pair)
+
+	dials := 0
+	dialDataNode := func() (net.Conn, error) {
+		dials += 1
+		return nil, errors.New("DataNode is down.")
+	}
+
+	client, proxySide := loopback(t)
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	clientObj := NewConnection(client)
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_READ_BLOCK
+	requestHeader.Write(clientObj)
+	request.Write(clientObj)
+	clientObj.Flush()
+
+	resHeader := writables.NewBlockResponseHeader()
+	err := resHeader.Read(clientObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resPacket := writables.NewBlockPacket(resHeader)
+	err = resPacket.Read(clientObj)
+	if err != nil || !bytes.Equal(resPacket.Data, packet.Data) {
+		t.FailNow()
+	}
+
+	resLast := writables.NewBlockPacket(resHeader)
+	err = resLast.Read(clientObj)
+	if err != nil || resLast.LastPacket == 0 {
+		t.FailNow()
+	}
+
+	writables.WriteShortInt(uint16(writables.OP_STATUS_CHECKSUM_OK), clientObj)
+	clientObj.Flush()
+	client.Close()
+	<-done
+
+	if dials != 0 {
+		t.Fail()
+	}
+
+	stats := dataCache.Stats.Snapshot()
+	if stats.Hits != 1 || stats.Misses != 0 || stats.BytesFromCache != 5 ||
+		stats.Accesses[5] != 1 {
+		t.Fail()
+	}
+}
+
+//a client whose block token does not check out is sent to
+//the DataNode even if the block is cached
+func TestHandleClientBadTokenSkipsCache(t *testing.T) {
+	util.Init()
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 5
+	request.Length = 5
+
+	pair := writables.NewReadPair(request)
+	pair.SetResponseHeader(writables.NewBlockResponseHeader())
+	pair.Complete()
+
+	dataCache := caches.NewWritableDataCache(10)
+	w := New(dataCache)
+	dataCache.AddReadPair(pair)
+
+	keys := writables.NewExportedBlockKeys()
+	keys.IsBlockTokenEnabled = true
+	keys.CurrentKey.KeyBytes = []byte("key")
+	w.BlockKeys = caches.NewBlockKeyStore()
+	w.BlockKeys.Update(keys)
+
+	dials := 0
+	dialDataNode := func() (net.Conn, error) {
+		dials += 1
+		return nil, errors.New("DataNode is down.")
+	}
+
+	client, proxySide := loopback(t)
+	done := make(chan bool)
+	go func() {
+		w.HandleClient(proxySide, dialDataNode)
+		done <- true
+	}()
+
+	clientObj := NewConnection(client)
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_READ_BLOCK
+	requestHeader.Write(clientObj)
+	request.Write(clientObj)
+	clientObj.Flush()
+	<-done
+	client.Close()
+
+	if dials != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,225 @@
+package writable_processor
+
+/*
+* Relaying of OP_WRITE_BLOCK. The header and the data packets
+* from the client are decoded and forwarded to the DataNode, the
+* PipelineAcks coming back are forwarded to the client and, if
+* WriteThrough is set, the block is put in the data cache once
+* the whole pipeline has acknowledged the last packet.
+*/
+
+import (
+	//go packages
+	"bytes"
+	"errors"
+	"fmt"
+
+	//local packages
+	"util"
+	"writables"
+)
+
+//the largest checksum chunk we accept in an OP_WRITE_BLOCK
+//(HDFS uses 512 bytes)
+var MAX_BYTES_PER_CHECKSUM = uint32(1 << 20)
+
+func (w *WritableProcessor) processWriteBlock(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) bool {
+
+	err := w.relayWriteBlock(requestHeader, conn, dataNode)
+	if err != nil {
+		return w.logOpError("OP_WRITE_BLOCK", err)
+	}
+
+	return true
+}
+
+func (w *WritableProcessor) relayWriteBlock(
+	requestHeader *writables.DataRequestHeader,
+	conn writables.ReaderWriter, dataNode writables.ReaderWriter) error {
+
+	writeHeader := writables.NewWriteBlockHeader()
+	err := writeHeader.Read(conn)
+	if err != nil {
+		return err
+	}
+
+	//the packets that follow could not be split into chunks
+	bytesPerChecksum := writeHeader.Checksum.BytesPerChecksum
+	if bytesPerChecksum == 0 || bytesPerChecksum > MAX_BYTES_PER_CHECKSUM {
+		if writeHeader.FromClient() {
+			res := writables.NewWriteBlockResponse()
+			res.Status = uint16(writables.OP_STATUS_ERROR)
+			res.Write(conn)
+			flush(conn)
+		}
+
+		return fmt.Errorf("Bad BytesPerChecksum %d for block %d.",
+			bytesPerChecksum, writeHeader.BlockId)
+	}
+
+	reqBuf := new(bytes.Buffer)
+	requestHeader.Write(reqBuf)
+	writeHeader.Write(reqBuf)
+	_, err = dataNode.Write(reqBuf.Bytes())
+	if err == nil {
+		err = flush(dataNode)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	//only clients get told whether the pipeline was set up
+	if writeHeader.FromClient() {
+		connectAck := writables.NewWriteBlockResponse()
+		err = connectAck.Read(dataNode)
+		if err != nil {
+			return err
+		}
+
+		err = connectAck.Write(conn)
+		if err == nil {
+			err = flush(conn)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if connectAck.Status != uint16(writables.OP_STATUS_SUCCESS) {
+			return nil
+		}
+	}
+
+	//the seqno of the last packet is sent to the ack relay once
+	//we know it; the relay answers with whether the whole
+	//pipeline acknowledged everything up to it
+	lastSeqNo := make(chan uint64, 1)
+	acked := make(chan bool, 1)
+	go w.relayPipelineAcks(conn, dataNode, lastSeqNo, acked)
+
+	packets := make([]*writables.BlockPacket, 0)
+	for {
+		packet := writables.NewWritePacket(writeHeader.Checksum)
+		err = packet.Read(conn)
+		if err != nil {
+			return err
+		}
+
+		if packet.LastPacket != 0 {
+			lastSeqNo <- packet.SeqNo
+		}
+
+		err = packet.Write(dataNode)
+		if err == nil {
+			err = flush(dataNode)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if w.WriteThrough && packet.SeqNo != writables.HEARTBEAT_SEQNO {
+			packets = append(packets, packet)
+		}
+
+		if packet.LastPacket != 0 {
+			break
+		}
+	}
+
+	if !<-acked {
+		return errors.New("Pipeline did not acknowledge the last packet.")
+	}
+
+	if w.WriteThrough {
+		w.cacheWrittenBlock(writeHeader, packets)
+	}
+
+	return nil
+}
+
+//forwards PipelineAcks from the DataNode to the client until the ack
+//for the last packet (whose seqno arrives on lastSeqNo) has been relayed.
+//Sends true on acked if every ack was successful.
+func (w *WritableProcessor) relayPipelineAcks(conn writables.ReaderWriter,
+	dataNode writables.ReaderWriter, lastSeqNo chan uint64, acked chan bool) {
+
+	success := true
+	last := uint64(0)
+	haveLast := false
+
+	for {
+		ack := writables.NewPipelineAck()
+		err := ack.Read(dataNode)
+		if err != nil {
+			acked <- false
+			return
+		}
+
+		err = ack.Write(conn)
+		if err == nil {
+			err = flush(conn)
+		}
+
+		if err != nil {
+			acked <- false
+			return
+		}
+
+		if ack.SeqNo != writables.HEARTBEAT_SEQNO && !ack.IsSuccess() {
+			success = false
+		}
+
+		//the last packet is always sent before
+		//its ack can come back
+		if !haveLast {
+			select {
+			case last = <-lastSeqNo:
+				haveLast = true
+			default:
+			}
+		}
+
+		if haveLast && ack.SeqNo == last {
+			acked <- success
+			return
+		}
+	}
+}
+
+//puts a block that has just been written into the data cache, as if it
+//had been read in full, so that reading it right after writing it is a hit
+func (w *WritableProcessor) cacheWrittenBlock(
+	writeHeader *writables.WriteBlockHeader, packets []*writables.BlockPacket) {
+
+	length := uint64(0)
+	for i := 0; i < len(packets); i++ {
+		length += uint64(packets[i].Length)
+	}
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = writeHeader.BlockId
+	request.Timestamp = writeHeader.GenerationStamp
+	request.StartOffset = 0
+	request.Length = length
+	request.AccessToken = writeHeader.AccessToken
+
+	responseHeader := writables.NewBlockResponseHeader()
+	responseHeader.Status = uint16(writables.OP_STATUS_SUCCESS)
+	responseHeader.Checksum = writeHeader.Checksum
+	responseHeader.ChunkOffset = 0
+
+	pair := writables.NewReadPair(request)
+	pair.DataNode = w.DataNodeAddress
+	pair.SetResponseHeader(responseHeader)
+	for i := 0; i < len(packets); i++ {
+		pair.AddBlockPacket(packets[i])
+	}
+	pair.Complete()
+
+	w.dataCache.AddReadPair(pair)
+	util.TempLogger.Println(w.id, "Cached written block: ", writeHeader.BlockId)
+}
@@ -0,0 +1,182 @@
+package writable_processor
+
+import (
+	//go packages
+	"bytes"
+	"net"
+	"testing"
+
+	//local packages
+	"caches"
+	"util"
+	"writables"
+)
+
+//accepts an OP_WRITE_BLOCK and acknowledges every packet
+func writeBlockOp(connObj *Connection,
+	requestHeader *writables.DataRequestHeader) bool {
+	writeHeader := writables.NewWriteBlockHeader()
+	err := writeHeader.Read(connObj)
+	if err != nil {
+		return false
+	}
+
+	connectAck := writables.NewWriteBlockResponse()
+	connectAck.Write(connObj)
+	connObj.Flush()
+
+	for {
+		packet := writables.NewWritePacket(writeHeader.Checksum)
+		err = packet.Read(connObj)
+		if err != nil {
+			return false
+		}
+
+		ack := writables.NewPipelineAck()
+		ack.SeqNo = packet.SeqNo
+		ack.NumOfReplies = 1
+		ack.Replies = []uint16{uint16(writables.OP_STATUS_SUCCESS)}
+		ack.Write(connObj)
+		connObj.Flush()
+
+		if packet.LastPacket != 0 {
+			return false
+		}
+	}
+}
+
+func TestWriteBlockWriteThrough(t *testing.T) {
+	util.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveFakeDataNode(ln, 1, nil, writeBlockOp)
+
+	dataNode, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataNode.Close()
+
+	client, proxySide := net.Pipe()
+	defer client.Close()
+
+	dataCache := caches.NewWritableDataCache(10)
+	w := New(dataCache)
+	w.WriteThrough = true
+
+	requestHeader := writables.NewDataRequestHeader()
+	requestHeader.Version = writables.DATA_TRANSFER_VERSION
+	requestHeader.Op = writables.OP_WRITE_BLOCK
+	done := make(chan bool)
+	go func() {
+		w.processWriteBlock(requestHeader, NewConnection(proxySide), 
+			NewConnection(dataNode))
+		done <- true
+	}()
+
+	clientObj := NewConnection(client)
+	writeHeader := writables.NewWriteBlockHeader()
+	writeHeader.BlockId = 7
+	writeHeader.GenerationStamp = 1001
+	writeHeader.ClientName.Bytes = []byte("DFSClient_1")
+	writeHeader.ClientName.Length = int64(len(writeHeader.ClientName.Bytes))
+	writeHeader.Checksum.Type = writables.CHECKSUM_CRC32
+	writeHeader.Checksum.BytesPerChecksum = 4
+	writeHeader.Write(clientObj)
+	clientObj.Flush()
+
+	connectAck := writables.NewWriteBlockResponse()
+	err = connectAck.Read(clientObj)
+	if err != nil || connectAck.Status != uint16(writables.OP_STATUS_SUCCESS) {
+		t.FailNow()
+	}
+
+	data := []byte{1, 2, 3, 4, 5, 6}
+	packet := writables.NewWritePacket(writeHeader.Checksum)
+	packet.Length = uint32(len(data))
+	packet.Data = data
+	packet.FillChecksums()
+	packet.PacketLength = uint32(4 + len(packet.ChecksumData) + len(data))
+
+	last := writables.NewWritePacket(writeHeader.Checksum)
+	last.SeqNo = 1
+	last.Offset = uint64(len(data))
+	last.LastPacket = 1
+	last.PacketLength = 4
+
+	for _, p := range []*writables.BlockPacket{packet, last} {
+		buf := new(bytes.Buffer)
+		p.Write(buf)
+		clientObj.Write(buf.Bytes())
+		clientObj.Flush()
+
+		ack := writables.NewPipelineAck()
+		err = ack.Read(clientObj)
+		if err != nil || ack.SeqNo != p.SeqNo {
+			t.FailNow()
+		}
+	}
+	<-done
+
+	request := writables.NewReadBlockHeader()
+	request.BlockId = 7
+	request.Timestamp = 1001
+	request.Length = uint64(len(data))
+
+	pair := dataCache.Query("", request)
+	if pair == nil {
+		t.FailNow()
+	}
+
+	cached, err := pair.WaitBlockPacket(0)
+	if err != nil || !bytes.Equal(cached.Data, data) {
+		t.Fail()
+	}
+}
+
+func TestWriteBlockBadBytesPerChecksum(t *testing.T) {
+	util.Init()
+
+	for _, bytesPerChecksum := range []uint32{0, MAX_BYTES_PER_CHECKSUM + 1} {
+		client, proxySide := net.Pipe()
+		dataNode, dataNodeSide := net.Pipe()
+
+		w := New(caches.NewWritableDataCache(10))
+		requestHeader := writables.NewDataRequestHeader()
+		requestHeader.Version = writables.DATA_TRANSFER_VERSION
+		requestHeader.Op = writables.OP_WRITE_BLOCK
+		done := make(chan bool, 1)
+		go func() {
+			done <- w.processWriteBlock(requestHeader,
+				NewConnection(proxySide), NewConnection(dataNode))
+		}()
+
+		clientObj := NewConnection(client)
+		writeHeader := writables.NewWriteBlockHeader()
+		writeHeader.BlockId = 7
+		writeHeader.ClientName.Bytes = []byte("DFSClient_1")
+		writeHeader.ClientName.Length = int64(len(writeHeader.ClientName.Bytes))
+		writeHeader.Checksum.Type = writables.CHECKSUM_CRC32
+		writeHeader.Checksum.BytesPerChecksum = bytesPerChecksum
+		writeHeader.Write(clientObj)
+		clientObj.Flush()
+
+		//nothing may reach the DataNode (the write would block)
+		res := writables.NewWriteBlockResponse()
+		err := res.Read(clientObj)
+		if err != nil || res.Status != uint16(writables.OP_STATUS_ERROR) {
+			t.Fatal("Bad BytesPerChecksum accepted: ", bytesPerChecksum, err)
+		}
+
+		if <-done {
+			t.Fail()
+		}
+
+		client.Close()
+		dataNodeSide.Close()
+	}
+}
@@ -5,12 +5,16 @@ package writables
 ** an OP_READ_BLOCK request is received
 **/
 type BlockResponseSet struct {
+	//the BlockResponseHeader sent by the DataNode
+	//before the chunks (nil until it has been read)
+	Header *BlockResponseHeader
+
 	//multiple chunks per block
 	Chunks []*BlockPacket
 }
 
-func NewBlockResponseSet() *BlockResponseSet {
-	b := BlockResponseSet{}
+func NewBlockResponseSet(header *BlockResponseHeader) *BlockResponseSet {
+	b := BlockResponseSet{Header: header}
 	b.Chunks = make([]*BlockPacket, 0)
 	return &b
 }
@@ -27,3 +31,13 @@ func (b *BlockResponseSet) Size() int {
 	return len(b.Chunks)
 }
 
+//number of bytes of block data in the chunks
+func (b *BlockResponseSet) DataSize() int64 {
+	size := int64(0)
+	for i := 0; i < len(b.Chunks); i++ {
+		size += int64(len(b.Chunks[i].Data))
+	}
+
+	return size
+}
+
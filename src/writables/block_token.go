@@ -0,0 +1,162 @@
+package writables
+
+import (
+	//go packages
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+
+	//local packages
+
+)
+
+/**
+** What is inside Token.Identifier for block tokens
+** (org.apache.hadoop.hdfs.security.token.block.BlockTokenIdentifier)
+*/
+
+//access modes that a block token can grant
+var ACCESS_MODE_READ = "READ"
+var ACCESS_MODE_WRITE = "WRITE"
+var ACCESS_MODE_COPY = "COPY"
+var ACCESS_MODE_REPLACE = "REPLACE"
+
+type BlockTokenIdentifier struct {
+	//milliseconds since the epoch (vlong)
+	ExpiryDate int64
+
+	//id of the BlockKey the token was signed with (vint)
+	KeyId int64
+
+	//written as an int length (-1 => no user) followed by
+	//the bytes, as per WritableUtils.writeString
+	UserId string
+
+	//vint count followed by a vlong per block
+	BlockIds []int64
+
+	//vint count followed by a Text per mode
+	Modes []string
+}
+
+func NewBlockTokenIdentifier() *BlockTokenIdentifier {
+	b := BlockTokenIdentifier{}
+	return &b
+}
+
+//reads the identifier of token
+func ParseBlockTokenIdentifier(token *Token) (*BlockTokenIdentifier, error) {
+	b := NewBlockTokenIdentifier()
+	err := b.Read(bytes.NewBuffer(token.Identifier))
+	return b, err
+}
+
+func (b *BlockTokenIdentifier) Read(reader Reader) error {
+	b.ExpiryDate = ReadVInt(reader)
+	b.KeyId = ReadVInt(reader)
+
+	userLength, err := ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.UserId = ""
+	if int32(userLength) > 0 {
+		user, err := ReadBytesIO(int64(userLength), reader)
+		if err != nil {
+			return err
+		}
+		b.UserId = string(user)
+	}
+
+	blockCount := ReadVInt(reader)
+	if blockCount < 0 {
+		return errors.New("Negative block count in block token.")
+	}
+
+	b.BlockIds = make([]int64, 0, blockCount)
+	for i := int64(0); i < blockCount; i++ {
+		b.BlockIds = append(b.BlockIds, ReadVInt(reader))
+	}
+
+	modeCount := ReadVInt(reader)
+	if modeCount < 0 {
+		return errors.New("Negative access mode count in block token.")
+	}
+
+	b.Modes = make([]string, 0, modeCount)
+	for i := int64(0); i < modeCount; i++ {
+		mode := NewText()
+		err = mode.Read(reader)
+		if err != nil {
+			return err
+		}
+		b.Modes = append(b.Modes, string(mode.Bytes))
+	}
+
+	return nil
+}
+
+func (b *BlockTokenIdentifier) Write(writer Writer) error {
+	WriteVInt(b.ExpiryDate, writer)
+	WriteVInt(b.KeyId, writer)
+
+	err := WriteInt(uint32(len(b.UserId)), writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBytes([]byte(b.UserId), int64(len(b.UserId)), writer)
+	if err != nil {
+		return err
+	}
+
+	WriteVInt(int64(len(b.BlockIds)), writer)
+	for i := 0; i < len(b.BlockIds); i++ {
+		WriteVInt(b.BlockIds[i], writer)
+	}
+
+	WriteVInt(int64(len(b.Modes)), writer)
+	for i := 0; i < len(b.Modes); i++ {
+		mode := NewText()
+		mode.Bytes = []byte(b.Modes[i])
+		mode.Length = int64(len(mode.Bytes))
+		err = mode.Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//true if the token was issued for blockId
+func (b *BlockTokenIdentifier) HasBlock(blockId uint64) bool {
+	for i := 0; i < len(b.BlockIds); i++ {
+		if uint64(b.BlockIds[i]) == blockId {
+			return true
+		}
+	}
+
+	return false
+}
+
+//true if the token grants mode (e.g. ACCESS_MODE_READ)
+func (b *BlockTokenIdentifier) HasMode(mode string) bool {
+	for i := 0; i < len(b.Modes); i++ {
+		if b.Modes[i] == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+//the password the NameNode gives out with a token: the
+//HMAC-SHA1 of the identifier under the block key
+func BlockTokenPassword(identifier []byte, key *BlockKey) []byte {
+	mac := hmac.New(sha1.New, key.KeyBytes)
+	mac.Write(identifier)
+	return mac.Sum(nil)
+}
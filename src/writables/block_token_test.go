@@ -0,0 +1,114 @@
+package writables
+
+import (
+	"testing"
+	"bytes"
+	"reflect"
+)
+
+func makeBlockTokenIdentifier() *BlockTokenIdentifier {
+	b := NewBlockTokenIdentifier()
+	b.ExpiryDate = 1400000000000
+	b.KeyId = 7
+	b.UserId = "hduser"
+	b.BlockIds = []int64{-4925431233334556789, 12}
+	b.Modes = []string{ACCESS_MODE_READ, ACCESS_MODE_COPY}
+	return b
+}
+
+func TestBlockTokenIdentifierReadWrite(t *testing.T) {
+	b := makeBlockTokenIdentifier()
+
+	buf := new(bytes.Buffer)
+	err := b.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewBlockTokenIdentifier()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(b, res) {
+		t.Fail()
+	}
+
+	if !res.HasBlock(12) || res.HasBlock(13) {
+		t.Fail()
+	}
+
+	if !res.HasMode(ACCESS_MODE_READ) || res.HasMode(ACCESS_MODE_WRITE) {
+		t.Fail()
+	}
+}
+
+func TestTokenReadWrite(t *testing.T) {
+	identifier := new(bytes.Buffer)
+	makeBlockTokenIdentifier().Write(identifier)
+
+	token := NewToken()
+	token.Identifier = identifier.Bytes()
+	token.IdentifierLength = int64(len(token.Identifier))
+	token.Password = []byte{1, 2, 3, 4}
+	token.PasswordLength = 4
+	token.Kind.Bytes = []byte("HDFS_BLOCK_TOKEN")
+	token.Kind.Length = int64(len(token.Kind.Bytes))
+
+	buf := new(bytes.Buffer)
+	err := token.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a field after the token has to come out intact
+	WriteShortInt(99, buf)
+
+	res := NewToken()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Equals(token) || res.IsEmpty() {
+		t.Fail()
+	}
+
+	after, _ := ReadShortInt(buf)
+	if after != 99 {
+		t.Fail()
+	}
+
+	parsed, err := ParseBlockTokenIdentifier(res)
+	if err != nil || parsed.KeyId != 7 {
+		t.Fail()
+	}
+}
+
+func TestReadBlockHeaderCacheKey(t *testing.T) {
+	r1 := NewReadBlockHeader()
+	r1.BlockId = 1
+	r1.Timestamp = 2
+	r1.Length = 4
+	r1.AccessToken.Password = []byte{1}
+	r1.AccessToken.PasswordLength = 1
+
+	r2 := NewReadBlockHeader()
+	r2.BlockId = 1
+	r2.Timestamp = 2
+	r2.Length = 4
+	r2.AccessToken.Password = []byte{2}
+	r2.AccessToken.PasswordLength = 1
+
+	//different tokens, same data
+	if r1.CacheKey() != r2.CacheKey() {
+		t.Fail()
+	}
+
+	//a new generation stamp means different data
+	r2.Timestamp = 3
+	if r1.CacheKey() == r2.CacheKey() {
+		t.Fail()
+	}
+}
@@ -2,12 +2,20 @@ package writables
 
 import (
 	//go packages
+	"bytes"
 	"reflect"
+	"errors"
+	"hash/crc32"
+	"encoding/binary"
 
 	//local packages
 
 )
 
+//version of the data transfer protocol that we speak
+//when we talk to DataNodes ourselves
+var DATA_TRANSFER_VERSION uint16 = 17
+
 /***
 ** Status codes
 **/
@@ -230,16 +238,16 @@ type Token struct {
 
 	//read as series of bytes,
 	//has a length of Token.IdentifierLength
-	Identifier []int8
+	//(see BlockTokenIdentifier for what is inside)
+	Identifier []byte
 
 	//VInt
 	PasswordLength int64
 
 	//read as series of bytes
 	//has a length of Token.PasswordLength
-	Password []int8
+	Password []byte
 
-	//GenericRead will take care of reading these
 	Kind *Text
 	Service *Text
 	
@@ -251,7 +259,7 @@ func (t *Token) Equals(e *Token) bool {
 		return false
 	}
 
-	if !reflect.DeepEqual(t.Identifier, e.Identifier) {
+	if !bytes.Equal(t.Identifier, e.Identifier) {
 		return false
 	}
 
@@ -259,7 +267,7 @@ func (t *Token) Equals(e *Token) bool {
 		return false
 	}
 
-	if !reflect.DeepEqual(t.Password, e.Password) {
+	if !bytes.Equal(t.Password, e.Password) {
 		return false
 	}
 
@@ -274,12 +282,65 @@ func (t *Token) Equals(e *Token) bool {
 	return true
 }
 
+//true if the token carries nothing (which is what clients
+//send when block tokens are turned off)
+func (t *Token) IsEmpty() bool {
+	return t.IdentifierLength == 0 && t.PasswordLength == 0
+}
+
+//GenericRead can't do the byte arrays here (they are
+//preceded by VInts rather than the field before them
+//being the length), so this is done by hand
 func (t *Token) Read(reader Reader) error {
-	return GenericRead(t, reader)
+	var err error
+
+	t.IdentifierLength = ReadVInt(reader)
+	if t.IdentifierLength < 0 {
+		return errors.New("Negative token identifier length.")
+	}
+
+	t.Identifier, err = ReadBytesIO(t.IdentifierLength, reader)
+	if err != nil {
+		return err
+	}
+
+	t.PasswordLength = ReadVInt(reader)
+	if t.PasswordLength < 0 {
+		return errors.New("Negative token password length.")
+	}
+
+	t.Password, err = ReadBytesIO(t.PasswordLength, reader)
+	if err != nil {
+		return err
+	}
+
+	err = t.Kind.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	return t.Service.Read(reader)
 }
 
 func (t *Token) Write(writer Writer) error {
-	return GenericWrite(t, writer)
+	WriteVInt(int64(len(t.Identifier)), writer)
+	err := WriteBytes(t.Identifier, int64(len(t.Identifier)), writer)
+	if err != nil {
+		return err
+	}
+
+	WriteVInt(int64(len(t.Password)), writer)
+	err = WriteBytes(t.Password, int64(len(t.Password)), writer)
+	if err != nil {
+		return err
+	}
+
+	err = t.Kind.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	return t.Service.Write(writer)
 }
 
 func NewToken() *Token {
@@ -316,7 +377,27 @@ func NewReadBlockHeader() *ReadBlockHeader {
 	return &r
 }
 
-//comparing two ReadBlockHeaders (used for caching)
+//what the data cache stores a block read under. The AccessToken is
+//left out on purpose: with block tokens turned on every client
+//presents a different one, so keying on it would mean the cache
+//never hits across jobs. Whether a client may read the block is 
+//a separate question (see caches.BlockKeyStore).
+type ReadBlockKey struct {
+	BlockId uint64
+	GenerationStamp uint64
+	StartOffset uint64
+	Length uint64
+}
+
+func (r *ReadBlockHeader) CacheKey() ReadBlockKey {
+	return ReadBlockKey{BlockId: r.BlockId, 
+		GenerationStamp: r.Timestamp,
+		StartOffset: r.StartOffset,
+		Length: r.Length}
+}
+
+//comparing two ReadBlockHeaders (including their tokens); the 
+//caches compare CacheKey()s instead
 func (r *ReadBlockHeader) Equals(e *ReadBlockHeader) bool {
 	if r.BlockId != e.BlockId {
 		return false
@@ -460,6 +541,20 @@ func NewBlockPacket(header *BlockResponseHeader) *BlockPacket {
 	return &b
 }
 
+//a BlockPacket sent by a client as part of an OP_WRITE_BLOCK. It has
+//the same layout as the ones sent in response to OP_READ_BLOCK,
+//but the checksum is described by the WriteBlockHeader
+func NewWritePacket(checksum *ChecksumHeader) *BlockPacket {
+	header := NewBlockResponseHeader()
+	header.Checksum = checksum
+	return NewBlockPacket(header)
+}
+
+//the header the packet was read with
+func (r *BlockPacket) Header() *BlockResponseHeader {
+	return r.header
+}
+
 func (r *BlockPacket) checksumLen() int {
 	return int(r.numChunks() * int(r.header.Checksum.Size()))
 }
@@ -468,82 +563,117 @@ func (r *BlockPacket) numChunks() int {
 	return int((r.Length + r.header.Checksum.BytesPerChecksum - 1)/r.header.Checksum.BytesPerChecksum)
 }
 
-func (r *BlockPacket) Read(reader Reader) error {
-	var err error
+//size of the fixed fields at the start of a BlockPacket (PacketLength,
+//Offset, SeqNo, LastPacket and Length); these are read and written 
+//in one go rather than field by field
+var BLOCK_PACKET_HEADER_SIZE int64 = 25
 
-	r.PacketLength, err = ReadInt(reader)
+func (r *BlockPacket) Read(reader Reader) error {
+	buf, err := ReadBytesIO(BLOCK_PACKET_HEADER_SIZE, reader)
 	if err != nil {
 		return err
 	}
 
-	r.Offset, err = ReadLongInt(reader)
+	r.PacketLength = binary.BigEndian.Uint32(buf[0:4])
+	r.Offset = binary.BigEndian.Uint64(buf[4:12])
+	r.SeqNo = binary.BigEndian.Uint64(buf[12:20])
+	r.LastPacket = int8(buf[20])
+	r.Length = binary.BigEndian.Uint32(buf[21:25])
+
+	//read in the checksum bytes
+	checksumLen := r.checksumLen()
+	r.ChecksumData, _, err = ReadBytesIOInfo(int64(checksumLen), reader)
 	if err != nil {
 		return err
 	}
 
-	r.SeqNo, err = ReadLongInt(reader)
+	//read some more the actual data
+	chunkLen := int64(r.Length)
+	r.Data, _, err = ReadBytesIOInfo(int64(chunkLen), reader)
 	if err != nil {
 		return err
 	}
 
-	r.LastPacket, err = ReadByte(reader)
-	if err != nil {
-		return err
+	return nil
+}
+
+//checks ChecksumData against Data, chunk by chunk, as described
+//by the ChecksumHeader. Only CRC32 is checked; packets with
+//CHECKSUM_NULL always pass.
+func (r *BlockPacket) VerifyChecksums() error {
+	if r.header.Checksum.Type != CHECKSUM_CRC32 {
+		return nil
 	}
 
-	r.Length, err = ReadInt(reader)
-	if err != nil {
-		return err
+	bytesPerChecksum := int(r.header.Checksum.BytesPerChecksum)
+	if bytesPerChecksum == 0 {
+		return errors.New("BytesPerChecksum is zero.")
 	}
 
-	//read in the checksum bytes
-	checksumLen := r.checksumLen()
-	r.Data, _, err = ReadBytesIOInfo(int64(checksumLen), reader)
-	if err != nil {
-		return err
+	if len(r.ChecksumData) != r.checksumLen() || 
+	len(r.Data) != int(r.Length) {
+		return errors.New("Packet is truncated.")
 	}
 
-	//read some more the actual data
-	var interm []byte
-	chunkLen := int64(r.Length)
-	interm, _, err = ReadBytesIOInfo(int64(chunkLen), reader)
-	r.Data = append(r.Data, interm...)
+	checksumSize := int(CHECKSUM_CRC32_SIZE)
+	for i := 0; i < r.numChunks(); i++ {
+		start := i*bytesPerChecksum
+		end := start + bytesPerChecksum
+		if end > len(r.Data) {
+			end = len(r.Data)
+		}
 
-	if err != nil {
-		return err
+		expected := binary.BigEndian.Uint32(
+			r.ChecksumData[i*checksumSize:(i+1)*checksumSize])
+		if crc32.ChecksumIEEE(r.Data[start:end]) != expected {
+			return errors.New("Checksum mismatch in chunk of packet.")
+		}
 	}
 
 	return nil
 }
 
-func (r *BlockPacket) Write(writer Writer) error {
-	var err error
-	err = WriteInt(r.PacketLength, writer)
-	if err != nil {
-		return err
+//computes ChecksumData from Data (the inverse of VerifyChecksums())
+func (r *BlockPacket) FillChecksums() {
+	r.ChecksumData = make([]byte, r.checksumLen())
+	if r.header.Checksum.Type != CHECKSUM_CRC32 {
+		return
 	}
 
-	err = WriteLongInt(r.Offset, writer)
-	if err != nil {
-		return err
-	}
+	bytesPerChecksum := int(r.header.Checksum.BytesPerChecksum)
+	checksumSize := int(CHECKSUM_CRC32_SIZE)
+	for i := 0; i < r.numChunks(); i++ {
+		start := i*bytesPerChecksum
+		end := start + bytesPerChecksum
+		if end > len(r.Data) {
+			end = len(r.Data)
+		}
 
-	err = WriteLongInt(r.SeqNo, writer)
-	if err != nil {
-		return err
+		binary.BigEndian.PutUint32(
+			r.ChecksumData[i*checksumSize:(i+1)*checksumSize], 
+			crc32.ChecksumIEEE(r.Data[start:end]))
 	}
+}
 
-	err = WriteByte(r.LastPacket, writer)
+func (r *BlockPacket) Write(writer Writer) error {
+	buf := make([]byte, BLOCK_PACKET_HEADER_SIZE)
+	binary.BigEndian.PutUint32(buf[0:4], r.PacketLength)
+	binary.BigEndian.PutUint64(buf[4:12], r.Offset)
+	binary.BigEndian.PutUint64(buf[12:20], r.SeqNo)
+	buf[20] = byte(r.LastPacket)
+	binary.BigEndian.PutUint32(buf[21:25], r.Length)
+
+	_, err := writer.Write(buf)
 	if err != nil {
 		return err
 	}
 
-	err = WriteInt(r.Length, writer)
+	_, err = WriteBytesInfo(r.ChecksumData, int64(len(r.ChecksumData)), writer)
 	if err != nil {
 		return err
 	}
 
-	_, err = WriteBytesInfo(r.Data, int64(r.PacketLength), writer)
+	_, err = WriteBytesInfo(r.Data, int64(r.Length), writer)
 	if err != nil {
 		return err
 	}
@@ -714,3 +844,667 @@ func (r *ReadBlockResponse) Write(writer Writer) error {
 	return GenericWrite(r, writer)
 }
 
+/**
+** BlockChecksumHeader
+** Header when the DataRequest has Op of OP_BLOCK_CHECKSUM
+*/
+type BlockChecksumHeader struct {
+	//long
+	BlockId uint64
+
+	//long
+	GenerationStamp uint64
+
+	AccessToken *Token
+}
+
+func NewBlockChecksumHeader() *BlockChecksumHeader {
+	b := BlockChecksumHeader{}
+	b.AccessToken = NewToken()
+	return &b
+}
+
+func (b *BlockChecksumHeader) Read(reader Reader) error {
+	var err error
+	b.BlockId, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.GenerationStamp, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	return b.AccessToken.Read(reader)
+}
+
+func (b *BlockChecksumHeader) Write(writer Writer) error {
+	var err error
+	err = WriteLongInt(b.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(b.GenerationStamp, writer)
+	if err != nil {
+		return err
+	}
+
+	return b.AccessToken.Write(writer)
+}
+
+//size of the MD5 digest in a BlockChecksumResponse
+var MD5_SIZE int64 = 16
+
+/**
+** BlockChecksumResponse
+** The DataNode's answer to OP_BLOCK_CHECKSUM: an MD5 
+** over all of the CRCs in the block's metadata file
+*/
+type BlockChecksumResponse struct {
+	//short
+	Status uint16
+
+	//int
+	BytesPerCrc uint32
+
+	//long
+	CrcPerBlock uint64
+
+	//MD5_SIZE bytes
+	Md5 []byte
+}
+
+func NewBlockChecksumResponse() *BlockChecksumResponse {
+	b := BlockChecksumResponse{}
+	return &b
+}
+
+func (b *BlockChecksumResponse) Read(reader Reader) error {
+	var err error
+	b.Status, err = ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	//nothing else follows an error status
+	if b.Status != uint16(OP_STATUS_SUCCESS) {
+		return nil
+	}
+
+	b.BytesPerCrc, err = ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.CrcPerBlock, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	b.Md5, err = ReadBytesIO(MD5_SIZE, reader)
+	return err
+}
+
+func (b *BlockChecksumResponse) Write(writer Writer) error {
+	var err error
+	err = WriteShortInt(b.Status, writer)
+	if err != nil {
+		return err
+	}
+
+	if b.Status != uint16(OP_STATUS_SUCCESS) {
+		return nil
+	}
+
+	err = WriteInt(b.BytesPerCrc, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(b.CrcPerBlock, writer)
+	if err != nil {
+		return err
+	}
+
+	return WriteBytes(b.Md5, MD5_SIZE, writer)
+}
+
+/**
+** WriteBlockHeader
+** Header when the DataRequest has Op of OP_WRITE_BLOCK
+*/
+type WriteBlockHeader struct {
+	//long
+	BlockId uint64
+
+	//long
+	GenerationStamp uint64
+
+	//int; number of DataNodes in the entire pipeline
+	PipelineSize uint32
+
+	//boolean; set if this write is part of a pipeline recovery
+	IsRecovery bool
+
+	//empty if the write comes from another DataNode
+	//rather than from a client
+	ClientName *Text
+
+	//boolean; if set, SrcDataNode follows
+	HasSrcDataNode bool
+	SrcDataNode *DataNodeInfo
+
+	//int; the rest of the pipeline (the DataNodes
+	//the receiving DataNode will forward to)
+	NumTargets uint32
+	Targets []*DataNodeInfo
+
+	AccessToken *Token
+
+	//describes the checksums in the data packets that follow
+	Checksum *ChecksumHeader
+}
+
+func NewWriteBlockHeader() *WriteBlockHeader {
+	w := WriteBlockHeader{}
+	w.ClientName = NewText()
+	w.SrcDataNode = NewDataNodeInfo()
+	w.Targets = make([]*DataNodeInfo, 0)
+	w.AccessToken = NewToken()
+	w.Checksum = NewChecksumHeader()
+	return &w
+}
+
+//true if the write comes from a client (only 
+//clients get a WriteBlockResponse)
+func (w *WriteBlockHeader) FromClient() bool {
+	return w.ClientName.Length != 0
+}
+
+func (w *WriteBlockHeader) Read(reader Reader) error {
+	var err error
+	w.BlockId, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	w.GenerationStamp, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	w.PipelineSize, err = ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	w.IsRecovery, err = ReadBoolean(reader)
+	if err != nil {
+		return err
+	}
+
+	err = w.ClientName.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	w.HasSrcDataNode, err = ReadBoolean(reader)
+	if err != nil {
+		return err
+	}
+
+	if w.HasSrcDataNode {
+		err = w.SrcDataNode.Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.NumTargets, err = ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	w.Targets = make([]*DataNodeInfo, w.NumTargets)
+	for i := 0; i < int(w.NumTargets); i++ {
+		w.Targets[i] = NewDataNodeInfo()
+		err = w.Targets[i].Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.AccessToken.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	return w.Checksum.Read(reader)
+}
+
+func (w *WriteBlockHeader) Write(writer Writer) error {
+	var err error
+	err = WriteLongInt(w.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(w.GenerationStamp, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteInt(w.PipelineSize, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBoolean(w.IsRecovery, writer)
+	if err != nil {
+		return err
+	}
+
+	err = w.ClientName.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBoolean(w.HasSrcDataNode, writer)
+	if err != nil {
+		return err
+	}
+
+	if w.HasSrcDataNode {
+		err = w.SrcDataNode.Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteInt(w.NumTargets, writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(w.NumTargets); i++ {
+		err = w.Targets[i].Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.AccessToken.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	return w.Checksum.Write(writer)
+}
+
+/**
+** WriteBlockResponse
+** The "connect ack" a DataNode sends to a client once
+** the pipeline behind it has been set up
+*/
+type WriteBlockResponse struct {
+	//short
+	Status uint16
+
+	//name of the first DataNode in the pipeline that could
+	//not be connected to (empty if there was none)
+	FirstBadLink *Text
+}
+
+func NewWriteBlockResponse() *WriteBlockResponse {
+	w := WriteBlockResponse{}
+	w.FirstBadLink = NewText()
+	return &w
+}
+
+func (w *WriteBlockResponse) Read(reader Reader) error {
+	var err error
+	w.Status, err = ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	return w.FirstBadLink.Read(reader)
+}
+
+func (w *WriteBlockResponse) Write(writer Writer) error {
+	err := WriteShortInt(w.Status, writer)
+	if err != nil {
+		return err
+	}
+
+	return w.FirstBadLink.Write(writer)
+}
+
+//seqno of the heartbeat packets a client sends
+//on an idle write pipeline
+var HEARTBEAT_SEQNO uint64 = ^uint64(0)
+
+//true if every DataNode in the pipeline acknowledged
+//the packet successfully
+func (p *PipelineAck) IsSuccess() bool {
+	for i := 0; i < int(p.NumOfReplies); i++ {
+		if p.Replies[i] != uint16(OP_STATUS_SUCCESS) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/**
+** ReadMetadataHeader
+** Header when the DataRequest has Op of OP_READ_METADATA
+*/
+type ReadMetadataHeader struct {
+	//long
+	BlockId uint64
+
+	//long
+	GenerationStamp uint64
+}
+
+func NewReadMetadataHeader() *ReadMetadataHeader {
+	r := ReadMetadataHeader{}
+	return &r
+}
+
+func (r *ReadMetadataHeader) Read(reader Reader) error {
+	var err error
+	r.BlockId, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	r.GenerationStamp, err = ReadLongInt(reader)
+	return err
+}
+
+func (r *ReadMetadataHeader) Write(writer Writer) error {
+	err := WriteLongInt(r.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	return WriteLongInt(r.GenerationStamp, writer)
+}
+
+/**
+** ReadMetadataResponse
+** The contents of the block's metadata (.meta) file. Note
+** that, unlike the other responses, the status is a byte.
+*/
+type ReadMetadataResponse struct {
+	//byte
+	Status int8
+
+	//int
+	Length uint32
+
+	//byte array of length ReadMetadataResponse.Length
+	Data []byte
+
+	//int; always 0 (marks the end of the data)
+	Trailer uint32
+}
+
+func NewReadMetadataResponse() *ReadMetadataResponse {
+	r := ReadMetadataResponse{}
+	r.Data = []byte{}
+	return &r
+}
+
+func (r *ReadMetadataResponse) Read(reader Reader) error {
+	var err error
+	r.Status, err = ReadByte(reader)
+	if err != nil {
+		return err
+	}
+
+	//nothing else follows an error status
+	if r.Status != OP_STATUS_SUCCESS {
+		return nil
+	}
+
+	r.Length, err = ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	r.Data, err = ReadBytesIO(int64(r.Length), reader)
+	if err != nil {
+		return err
+	}
+
+	r.Trailer, err = ReadInt(reader)
+	return err
+}
+
+func (r *ReadMetadataResponse) Write(writer Writer) error {
+	err := WriteByte(r.Status, writer)
+	if err != nil {
+		return err
+	}
+
+	if r.Status != OP_STATUS_SUCCESS {
+		return nil
+	}
+
+	err = WriteInt(r.Length, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBytes(r.Data, int64(r.Length), writer)
+	if err != nil {
+		return err
+	}
+
+	return WriteInt(r.Trailer, writer)
+}
+
+/**
+** CopyBlockHeader
+** Header when the DataRequest has Op of OP_COPY_BLOCK
+** (sent by a DataNode that is replacing a block to the
+** DataNode it is copying the block from)
+*/
+type CopyBlockHeader struct {
+	//long
+	BlockId uint64
+
+	//long
+	GenerationStamp uint64
+
+	AccessToken *Token
+}
+
+func NewCopyBlockHeader() *CopyBlockHeader {
+	c := CopyBlockHeader{}
+	c.AccessToken = NewToken()
+	return &c
+}
+
+func (c *CopyBlockHeader) Read(reader Reader) error {
+	var err error
+	c.BlockId, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	c.GenerationStamp, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	return c.AccessToken.Read(reader)
+}
+
+func (c *CopyBlockHeader) Write(writer Writer) error {
+	err := WriteLongInt(c.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(c.GenerationStamp, writer)
+	if err != nil {
+		return err
+	}
+
+	return c.AccessToken.Write(writer)
+}
+
+/**
+** CopyBlockResponse
+** Sent before the block itself in response to OP_COPY_BLOCK. 
+** On success, BlockPackets (laid out as in an OP_WRITE_BLOCK, i.e.
+** without a chunk offset before them) follow until the last packet.
+*/
+type CopyBlockResponse struct {
+	//short
+	Status uint16
+
+	//only present if Status is OP_STATUS_SUCCESS
+	Checksum *ChecksumHeader
+}
+
+func NewCopyBlockResponse() *CopyBlockResponse {
+	c := CopyBlockResponse{}
+	c.Checksum = NewChecksumHeader()
+	return &c
+}
+
+func (c *CopyBlockResponse) Read(reader Reader) error {
+	var err error
+	c.Status, err = ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	if c.Status != uint16(OP_STATUS_SUCCESS) {
+		return nil
+	}
+
+	return c.Checksum.Read(reader)
+}
+
+func (c *CopyBlockResponse) Write(writer Writer) error {
+	err := WriteShortInt(c.Status, writer)
+	if err != nil {
+		return err
+	}
+
+	if c.Status != uint16(OP_STATUS_SUCCESS) {
+		return nil
+	}
+
+	return c.Checksum.Write(writer)
+}
+
+/**
+** ReplaceBlockHeader
+** Header when the DataRequest has Op of OP_REPLACE_BLOCK
+** (sent by the balancer)
+*/
+type ReplaceBlockHeader struct {
+	//long
+	BlockId uint64
+
+	//long
+	GenerationStamp uint64
+
+	//storage ID of the DataNode the block is being moved from
+	SourceId *Text
+
+	//the DataNode to copy the block from
+	ProxySource *DataNodeInfo
+
+	AccessToken *Token
+}
+
+func NewReplaceBlockHeader() *ReplaceBlockHeader {
+	r := ReplaceBlockHeader{}
+	r.SourceId = NewText()
+	r.ProxySource = NewDataNodeInfo()
+	r.AccessToken = NewToken()
+	return &r
+}
+
+func (r *ReplaceBlockHeader) Read(reader Reader) error {
+	var err error
+	r.BlockId, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	r.GenerationStamp, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	err = r.SourceId.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	err = r.ProxySource.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	return r.AccessToken.Read(reader)
+}
+
+func (r *ReplaceBlockHeader) Write(writer Writer) error {
+	err := WriteLongInt(r.BlockId, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(r.GenerationStamp, writer)
+	if err != nil {
+		return err
+	}
+
+	err = r.SourceId.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	err = r.ProxySource.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	return r.AccessToken.Write(writer)
+}
+
+/**
+** ReplaceBlockResponse
+** The status a DataNode answers OP_REPLACE_BLOCK with
+*/
+type ReplaceBlockResponse struct {
+	//short
+	Status uint16
+}
+
+func NewReplaceBlockResponse() *ReplaceBlockResponse {
+	r := ReplaceBlockResponse{}
+	return &r
+}
+
+func (r *ReplaceBlockResponse) Read(reader Reader) error {
+	var err error
+	r.Status, err = ReadShortInt(reader)
+	return err
+}
+
+func (r *ReplaceBlockResponse) Write(writer Writer) error {
+	return WriteShortInt(r.Status, writer)
+}
@@ -108,3 +108,252 @@ func TestReadRequestHeaderEquals(t *testing.T) {
 		t.Fail()
 	}
 }
+
+/**
+* BlockPacket checksum tests
+*/
+func makeCRCPacket(data []byte) *BlockPacket {
+	header := NewBlockResponseHeader()
+	header.Checksum.Type = CHECKSUM_CRC32
+	header.Checksum.BytesPerChecksum = 4
+
+	b := NewBlockPacket(header)
+	b.Length = uint32(len(data))
+	b.Data = data
+	b.FillChecksums()
+	b.PacketLength = uint32(4 + len(b.ChecksumData) + len(data))
+	return b
+}
+
+func TestBlockPacketVerifyChecksums(t *testing.T) {
+	//two full chunks and a partial one
+	b := makeCRCPacket([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	if len(b.ChecksumData) != 12 {
+		t.Fail()
+	}
+
+	if b.VerifyChecksums() != nil {
+		t.Fail()
+	}
+
+	b.Data[9] ^= 0x01
+	if b.VerifyChecksums() == nil {
+		t.Fail()
+	}
+}
+
+func TestBlockPacketReadWrite(t *testing.T) {
+	b := makeCRCPacket([]byte{1, 2, 3, 4, 5, 6})
+	b.Offset = 512
+	b.SeqNo = 3
+	b.LastPacket = 1
+
+	buf := new(bytes.Buffer)
+	err := b.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewBlockPacket(b.header)
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, b) {
+		t.Fail()
+	}
+
+	if res.VerifyChecksums() != nil {
+		t.Fail()
+	}
+}
+
+func TestBlockChecksumResponseReadWrite(t *testing.T) {
+	b := NewBlockChecksumResponse()
+	b.BytesPerCrc = 512
+	b.CrcPerBlock = 2
+	b.Md5 = make([]byte, MD5_SIZE)
+	b.Md5[3] = 7
+
+	buf := new(bytes.Buffer)
+	b.Write(buf)
+
+	res := NewBlockChecksumResponse()
+	err := res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, b) {
+		t.Fail()
+	}
+
+	//an error status is just the status
+	b = NewBlockChecksumResponse()
+	b.Status = uint16(OP_STATUS_ERROR)
+	buf = new(bytes.Buffer)
+	b.Write(buf)
+	if buf.Len() != 2 {
+		t.Fail()
+	}
+}
+
+/**
+* OP_WRITE_BLOCK tests
+*/
+func TestWriteBlockHeaderReadWrite(t *testing.T) {
+	w := NewWriteBlockHeader()
+	w.BlockId = 12
+	w.GenerationStamp = 1001
+	w.PipelineSize = 2
+	w.ClientName.Bytes = []byte("DFSClient_1")
+	w.ClientName.Length = int64(len(w.ClientName.Bytes))
+	w.NumTargets = 1
+	w.Targets = []*DataNodeInfo{NewDataNodeInfo()}
+	w.Targets[0].Id.Name = "10.0.0.2:50010"
+	w.Checksum.Type = CHECKSUM_CRC32
+	w.Checksum.BytesPerChecksum = 512
+
+	buf := new(bytes.Buffer)
+	err := w.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewWriteBlockHeader()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.BlockId != 12 || res.GenerationStamp != 1001 || res.PipelineSize != 2 {
+		t.Fail()
+	}
+
+	if !res.FromClient() || !res.ClientName.Equals(w.ClientName) {
+		t.Fail()
+	}
+
+	if res.NumTargets != 1 || res.Targets[0].Id.Name != "10.0.0.2:50010" {
+		t.Fail()
+	}
+
+	if !reflect.DeepEqual(res.Checksum, w.Checksum) {
+		t.Fail()
+	}
+
+	if buf.Len() != 0 {
+		t.Fail()
+	}
+}
+
+func TestWriteBlockResponseReadWrite(t *testing.T) {
+	w := NewWriteBlockResponse()
+	w.Status = uint16(OP_STATUS_ERROR)
+	w.FirstBadLink.Bytes = []byte("10.0.0.2:50010")
+	w.FirstBadLink.Length = int64(len(w.FirstBadLink.Bytes))
+
+	buf := new(bytes.Buffer)
+	w.Write(buf)
+
+	res := NewWriteBlockResponse()
+	err := res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Status != w.Status || !res.FirstBadLink.Equals(w.FirstBadLink) {
+		t.Fail()
+	}
+}
+
+func TestPipelineAckIsSuccess(t *testing.T) {
+	pa := NewPipelineAck()
+	pa.NumOfReplies = 2
+	pa.Replies = []uint16{uint16(OP_STATUS_SUCCESS), uint16(OP_STATUS_SUCCESS)}
+	if !pa.IsSuccess() {
+		t.Fail()
+	}
+
+	pa.Replies[1] = uint16(OP_STATUS_ERROR)
+	if pa.IsSuccess() {
+		t.Fail()
+	}
+}
+
+func TestReadMetadataResponseReadWrite(t *testing.T) {
+	r := NewReadMetadataResponse()
+	r.Status = OP_STATUS_SUCCESS
+	r.Data = []byte{0, 1, 1, 0, 0, 2, 0}
+	r.Length = uint32(len(r.Data))
+
+	buf := new(bytes.Buffer)
+	r.Write(buf)
+
+	res := NewReadMetadataResponse()
+	err := res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r, res) || buf.Len() != 0 {
+		t.Fail()
+	}
+
+	//an error status is all there is
+	r.Status = OP_STATUS_ERROR
+	buf.Reset()
+	r.Write(buf)
+	if buf.Len() != 1 {
+		t.Fail()
+	}
+}
+
+func TestReplaceBlockHeaderReadWrite(t *testing.T) {
+	r := NewReplaceBlockHeader()
+	r.BlockId = 4
+	r.GenerationStamp = 1001
+	r.SourceId.Bytes = []byte("DS-1234")
+	r.SourceId.Length = int64(len(r.SourceId.Bytes))
+	r.ProxySource.Id.Name = "10.0.0.3:50010"
+
+	buf := new(bytes.Buffer)
+	err := r.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewReplaceBlockHeader()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.BlockId != 4 || res.GenerationStamp != 1001 || 
+		!res.SourceId.Equals(r.SourceId) ||
+		res.ProxySource.Id.Name != "10.0.0.3:50010" || buf.Len() != 0 {
+		t.Fail()
+	}
+}
+
+func TestCopyBlockResponseReadWrite(t *testing.T) {
+	c := NewCopyBlockResponse()
+	c.Status = uint16(OP_STATUS_SUCCESS)
+	c.Checksum.Type = CHECKSUM_CRC32
+	c.Checksum.BytesPerChecksum = 512
+
+	buf := new(bytes.Buffer)
+	c.Write(buf)
+
+	res := NewCopyBlockResponse()
+	err := res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c, res) {
+		t.Fail()
+	}
+}
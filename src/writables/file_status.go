@@ -0,0 +1,260 @@
+/*
+* What the NameNode answers getFileInfo, getListing and
+* getContentSummary with (see namenode_rpc.Client).
+*/
+
+package writables
+
+import (
+	//go packages
+	"errors"
+
+	//local packages
+)
+
+/**
+** HdfsFileStatus
+** (org.apache.hadoop.hdfs.protocol.HdfsFileStatus)
+*/
+
+type HdfsFileStatus struct {
+	//local name of the file (empty for the path that was
+	//asked about in getFileInfo)
+	Path []byte
+
+	Length uint64
+	IsDir bool
+	BlockReplication uint16
+	BlockSize uint64
+
+	//milliseconds since the epoch
+	ModificationTime uint64
+	AccessTime uint64
+
+	//unix style permission bits (FsPermission)
+	Permission uint16
+
+	Owner *Text
+	Group *Text
+}
+
+func NewHdfsFileStatus() *HdfsFileStatus {
+	h := HdfsFileStatus{}
+	h.Owner = NewText()
+	h.Group = NewText()
+	return &h
+}
+
+func (h *HdfsFileStatus) Read(reader Reader) error {
+	pathLength, err := ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	h.Path, err = ReadBytesIO(int64(pathLength), reader)
+	if err != nil {
+		return err
+	}
+
+	h.Length, err = ReadLongInt(reader)
+	if err != nil {
+		return err
+	}
+
+	h.IsDir, err = ReadBoolean(reader)
+	if err != nil {
+		return err
+	}
+
+	h.BlockReplication, err = ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	times := []*uint64{&h.BlockSize, &h.ModificationTime, &h.AccessTime}
+	for i := 0; i < len(times); i++ {
+		value, err := ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+		*times[i] = value
+	}
+
+	h.Permission, err = ReadShortInt(reader)
+	if err != nil {
+		return err
+	}
+
+	err = h.Owner.Read(reader)
+	if err != nil {
+		return err
+	}
+
+	return h.Group.Read(reader)
+}
+
+func (h *HdfsFileStatus) Write(writer Writer) error {
+	err := WriteInt(uint32(len(h.Path)), writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBytes(h.Path, int64(len(h.Path)), writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteLongInt(h.Length, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteBoolean(h.IsDir, writer)
+	if err != nil {
+		return err
+	}
+
+	err = WriteShortInt(h.BlockReplication, writer)
+	if err != nil {
+		return err
+	}
+
+	times := []uint64{h.BlockSize, h.ModificationTime, h.AccessTime}
+	for i := 0; i < len(times); i++ {
+		err = WriteLongInt(times[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteShortInt(h.Permission, writer)
+	if err != nil {
+		return err
+	}
+
+	err = h.Owner.Write(writer)
+	if err != nil {
+		return err
+	}
+
+	return h.Group.Write(writer)
+}
+
+/**
+** DirectoryListing
+** (org.apache.hadoop.hdfs.protocol.DirectoryListing)
+*/
+
+type DirectoryListing struct {
+	PartialListing []*HdfsFileStatus
+
+	//number of entries that did not fit in this listing; ask
+	//again starting after the last of PartialListing for them
+	RemainingEntries uint32
+}
+
+func NewDirectoryListing() *DirectoryListing {
+	d := DirectoryListing{}
+	d.PartialListing = make([]*HdfsFileStatus, 0)
+	return &d
+}
+
+//name of the last entry (what the next getListing should
+//start after)
+func (d *DirectoryListing) LastName() []byte {
+	if len(d.PartialListing) == 0 {
+		return []byte{}
+	}
+
+	return d.PartialListing[len(d.PartialListing) - 1].Path
+}
+
+func (d *DirectoryListing) Read(reader Reader) error {
+	numEntries, err := ReadInt(reader)
+	if err != nil {
+		return err
+	}
+
+	//the count comes off the wire, so do not trust it
+	//with the size of the slice up front
+	if numEntries > 1 << 20 {
+		return errors.New("Too many entries in directory listing.")
+	}
+
+	d.PartialListing = make([]*HdfsFileStatus, numEntries)
+	for i := 0; i < int(numEntries); i++ {
+		d.PartialListing[i] = NewHdfsFileStatus()
+		err = d.PartialListing[i].Read(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.RemainingEntries, err = ReadInt(reader)
+	return err
+}
+
+func (d *DirectoryListing) Write(writer Writer) error {
+	err := WriteInt(uint32(len(d.PartialListing)), writer)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(d.PartialListing); i++ {
+		err = d.PartialListing[i].Write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return WriteInt(d.RemainingEntries, writer)
+}
+
+/**
+** ContentSummary
+** (org.apache.hadoop.fs.ContentSummary)
+*/
+
+type ContentSummary struct {
+	Length uint64
+	FileCount uint64
+	DirectoryCount uint64
+
+	//-1 (as a uint64) if there is no quota
+	Quota uint64
+
+	SpaceConsumed uint64
+	SpaceQuota uint64
+}
+
+func NewContentSummary() *ContentSummary {
+	c := ContentSummary{}
+	return &c
+}
+
+func (c *ContentSummary) Read(reader Reader) error {
+	values := []*uint64{&c.Length, &c.FileCount, &c.DirectoryCount, &c.Quota,
+		&c.SpaceConsumed, &c.SpaceQuota}
+	for i := 0; i < len(values); i++ {
+		value, err := ReadLongInt(reader)
+		if err != nil {
+			return err
+		}
+		*values[i] = value
+	}
+
+	return nil
+}
+
+func (c *ContentSummary) Write(writer Writer) error {
+	values := []uint64{c.Length, c.FileCount, c.DirectoryCount, c.Quota,
+		c.SpaceConsumed, c.SpaceQuota}
+	for i := 0; i < len(values); i++ {
+		err := WriteLongInt(values[i], writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
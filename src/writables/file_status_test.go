@@ -0,0 +1,105 @@
+package writables
+
+import (
+	"testing"
+	"bytes"
+	"reflect"
+)
+
+func makeHdfsFileStatus(name string, isDir bool) *HdfsFileStatus {
+	h := NewHdfsFileStatus()
+	h.Path = []byte(name)
+	h.Length = 1234
+	h.IsDir = isDir
+	h.BlockReplication = 3
+	h.BlockSize = 67108864
+	h.ModificationTime = 1387734822426
+	h.AccessTime = 1387734822000
+	h.Permission = 0644
+	h.Owner.Bytes = []byte("hduser")
+	h.Owner.Length = 6
+	h.Group.Bytes = []byte("supergroup")
+	h.Group.Length = 10
+	return h
+}
+
+func TestHdfsFileStatusReadWrite(t *testing.T) {
+	h := makeHdfsFileStatus("part-00000", false)
+
+	buf := new(bytes.Buffer)
+	err := h.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewHdfsFileStatus()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(h, res) || buf.Len() != 0 {
+		t.Fail()
+	}
+}
+
+func TestDirectoryListingReadWrite(t *testing.T) {
+	d := NewDirectoryListing()
+	d.PartialListing = append(d.PartialListing,
+		makeHdfsFileStatus("a", true), makeHdfsFileStatus("b", false))
+	d.RemainingEntries = 5
+
+	buf := new(bytes.Buffer)
+	err := d.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewDirectoryListing()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(d, res) {
+		t.Fail()
+	}
+
+	if string(res.LastName()) != "b" {
+		t.Fail()
+	}
+
+	if len(NewDirectoryListing().LastName()) != 0 {
+		t.Fail()
+	}
+}
+
+func TestContentSummaryReadWrite(t *testing.T) {
+	c := NewContentSummary()
+	c.Length = 1 << 40
+	c.FileCount = 12
+	c.DirectoryCount = 3
+	c.Quota = 0xFFFFFFFFFFFFFFFF
+	c.SpaceConsumed = 3 << 40
+	c.SpaceQuota = 0xFFFFFFFFFFFFFFFF
+
+	buf := new(bytes.Buffer)
+	err := c.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != 48 {
+		t.Fatal("Wrong length: ", buf.Len())
+	}
+
+	res := NewContentSummary()
+	err = res.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c, res) {
+		t.Fail()
+	}
+}
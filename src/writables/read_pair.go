@@ -1,26 +1,192 @@
 package writables
 
+import (
+	//go packages
+	"errors"
+	"sync"
+)
+
+/* ReadPair.State */
+const (
+	//packets are still coming in from the DataNode
+	FILL_FILLING = iota
+
+	//the last packet has been seen; the pair holds
+	//the whole response
+	FILL_COMPLETE
+
+	//the DataNode stream broke before the last packet;
+	//the pair must never be served
+	FILL_FAILED
+)
+
 /**
-* An OP_READ_BLOCK request and its 
+* An OP_READ_BLOCK request and its
 * corresponding set of responses
 */
 type ReadPair struct {
 	Request *ReadBlockHeader
 	ResponseSet *BlockResponseSet
+
+	//address of the DataNode the block was read from
+	DataNode string
+
+	//the pair is filled by one goroutine (the one talking to the
+	//DataNode) while any number of other goroutines may be streaming
+	//the packets out of it, so access to ResponseSet goes through
+	//this lock and waiters sleep on cond
+	lock sync.Mutex
+	cond *sync.Cond
+
+	//one of FILL_FILLING, FILL_COMPLETE or FILL_FAILED
+	state int
+
+	//called (once) when the pair becomes complete
+	onComplete func(*ReadPair)
 }
 
 func NewReadPair(request *ReadBlockHeader) *ReadPair {
-	r := ReadPair{Request: request}
-	r.ResponseSet = NewBlockResponseSet()
+	r := ReadPair{Request: request, state: FILL_FILLING}
+	r.ResponseSet = NewBlockResponseSet(nil)
+	r.cond = sync.NewCond(&r.lock)
 	return &r
 }
 
+//sets the BlockResponseHeader that the DataNode sent
+//and wakes up anyone waiting for it
+func (r *ReadPair) SetResponseHeader(header *BlockResponseHeader) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.ResponseSet.Header = header
+	r.cond.Broadcast()
+}
+
+//blocks until the BlockResponseHeader is available. Returns
+//nil if the fill failed.
+func (r *ReadPair) WaitResponseHeader() *BlockResponseHeader {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for r.ResponseSet.Header == nil && r.state == FILL_FILLING {
+		r.cond.Wait()
+	}
+
+	if r.state == FILL_FAILED {
+		return nil
+	}
+
+	return r.ResponseSet.Header
+}
+
 //conv method
 func (r *ReadPair) AddBlockPacket(q *BlockPacket) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
 	r.ResponseSet.AddBlockPacket(q)
+	r.cond.Broadcast()
 }
 
 //convin. method
 func (r *ReadPair) AddChunk(q *BlockPacket) {
 	r.AddBlockPacket(q)
-}
\ No newline at end of file
+}
+
+//blocks until the i'th BlockPacket has been added to the pair
+//and returns it. Returns nil once the pair is complete and
+//there is no i'th packet, or an error if the fill failed (in
+//which case whatever was already streamed is a truncated block).
+func (r *ReadPair) WaitBlockPacket(i int) (*BlockPacket, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for r.ResponseSet.Size() <= i && r.state == FILL_FILLING {
+		r.cond.Wait()
+	}
+
+	if r.state == FILL_FAILED {
+		return nil, errors.New("Fill of the block failed.")
+	}
+
+	if i < r.ResponseSet.Size() {
+		return r.ResponseSet.Chunks[i], nil
+	}
+
+	return nil, nil
+}
+
+//marks the pair as complete (called once the last packet
+//has been added). Returns false if the pair was not filling.
+func (r *ReadPair) Complete() bool {
+	return r.transition(FILL_COMPLETE)
+}
+
+//marks the pair as failed. Returns false if the pair was 
+//not filling, so it is safe to defer after Complete().
+func (r *ReadPair) Fail() bool {
+	return r.transition(FILL_FAILED)
+}
+
+func (r *ReadPair) transition(state int) bool {
+	r.lock.Lock()
+
+	if r.state != FILL_FILLING {
+		r.lock.Unlock()
+		return false
+	}
+
+	r.state = state
+	r.cond.Broadcast()
+
+	onComplete := r.onComplete
+	r.lock.Unlock()
+
+	//called without the lock so that it may look at the pair
+	if state == FILL_COMPLETE && onComplete != nil {
+		onComplete(r)
+	}
+
+	return true
+}
+
+//has f called once the pair is complete (right away, if
+//it already is). Replaces any earlier f.
+func (r *ReadPair) OnComplete(f func(*ReadPair)) {
+	r.lock.Lock()
+	if r.state != FILL_COMPLETE {
+		r.onComplete = f
+		r.lock.Unlock()
+		return
+	}
+	r.lock.Unlock()
+
+	f(r)
+}
+
+func (r *ReadPair) State() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.state
+}
+
+func (r *ReadPair) IsComplete() bool {
+	return r.State() == FILL_COMPLETE
+}
+
+//number of BlockPackets currently held by the pair
+func (r *ReadPair) Size() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.ResponseSet.Size()
+}
+
+//number of bytes of block data currently held by the pair
+func (r *ReadPair) DataSize() int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.ResponseSet.DataSize()
+}
@@ -0,0 +1,132 @@
+package writables
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewReadPair(t *testing.T) {
+	request := NewReadBlockHeader()
+	r := NewReadPair(request)
+
+	if r.Request != request {
+		t.Fail()
+	}
+
+	if r.ResponseSet == nil || r.ResponseSet.Header != nil {
+		t.Fail()
+	}
+
+	if r.State() != FILL_FILLING {
+		t.Fail()
+	}
+}
+
+//a reader waiting on a packet should be woken
+//up when the filler adds it
+func TestReadPairWaitBlockPacket(t *testing.T) {
+	r := NewReadPair(NewReadBlockHeader())
+	header := NewBlockResponseHeader()
+	packet := NewBlockPacket(header)
+
+	res := make(chan *BlockPacket)
+	go func() {
+		p, _ := r.WaitBlockPacket(0)
+		res <- p
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	r.AddBlockPacket(packet)
+
+	if <-res != packet {
+		t.Fail()
+	}
+
+	//past the end of a complete pair, we get nil
+	go func() {
+		p, _ := r.WaitBlockPacket(1)
+		res <- p
+	}()
+
+	r.Complete()
+	if <-res != nil {
+		t.Fail()
+	}
+}
+
+func TestReadPairStates(t *testing.T) {
+	r := NewReadPair(NewReadBlockHeader())
+	r.AddBlockPacket(NewBlockPacket(NewBlockResponseHeader()))
+
+	if !r.Fail() || r.State() != FILL_FAILED {
+		t.Fail()
+	}
+
+	//a failed pair never hands out its packets
+	p, err := r.WaitBlockPacket(0)
+	if p != nil || err == nil {
+		t.Fail()
+	}
+
+	//can't go from failed to complete
+	if r.Complete() || r.IsComplete() {
+		t.Fail()
+	}
+
+	r = NewReadPair(NewReadBlockHeader())
+	if !r.Complete() || !r.IsComplete() {
+		t.Fail()
+	}
+
+	//a deferred Fail() after Complete() is a no-op
+	if r.Fail() || !r.IsComplete() {
+		t.Fail()
+	}
+}
+
+func TestReadPairWaitResponseHeader(t *testing.T) {
+	r := NewReadPair(NewReadBlockHeader())
+	header := NewBlockResponseHeader()
+
+	res := make(chan *BlockResponseHeader)
+	go func() {
+		res <- r.WaitResponseHeader()
+	}()
+
+	r.SetResponseHeader(header)
+	if <-res != header {
+		t.Fail()
+	}
+
+	//a pair that failed without a header returns nil
+	r = NewReadPair(NewReadBlockHeader())
+	r.Fail()
+	if r.WaitResponseHeader() != nil {
+		t.Fail()
+	}
+}
+
+func TestReadPairOnComplete(t *testing.T) {
+	r := NewReadPair(NewReadBlockHeader())
+
+	called := 0
+	r.OnComplete(func(p *ReadPair) { called++ })
+	r.Fail()
+	if called != 0 {
+		t.Fail()
+	}
+
+	r = NewReadPair(NewReadBlockHeader())
+	r.OnComplete(func(p *ReadPair) { called++ })
+	r.Complete()
+	r.Complete()
+	if called != 1 {
+		t.Fail()
+	}
+
+	//already complete => right away
+	r.OnComplete(func(p *ReadPair) { called++ })
+	if called != 2 {
+		t.Fail()
+	}
+}
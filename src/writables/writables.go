@@ -139,8 +139,6 @@ func GenericWrite(packet interface{}, writer Writer) error {
 			if err != nil {
 				return err
 			}
-
-			element.Set(reflect.ValueOf(val))
 		case Writable:
 			writable := elementVal.(Writable)
 			err := writable.Write(writer)
@@ -685,11 +683,25 @@ func (e *ExportedBlockKeys) Read(reader Reader) error {
 	
 	e.CurrentKey.Read(reader)
 
-	e.KeyLength, _ = ReadInt(reader)
+	var err error
+	e.KeyLength, err = ReadInt(reader)
+	if err != nil {
+		return err
+	}
 
+	//the NameNode only keeps a few keys around; a count beyond
+	//that is garbage we should not size a slice with
+	if e.KeyLength > 1 << 10 {
+		return errors.New("Too many keys in exported block keys.")
+	}
+
+	e.AllKeys = make([]*BlockKey, e.KeyLength)
 	for i := 0; i< int(e.KeyLength); i++ {
 		e.AllKeys[i] = NewBlockKey()
-		e.AllKeys[i].Read(reader)
+		err = e.AllKeys[i].Read(reader)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -795,6 +807,33 @@ func (d *DataNodeRegistration) Read(reader Reader) error {
 	return err
 }
 
+//reads the structure written by WriteWithoutName (this is how
+//the NameNode answers a registration; the name is in the response
+//packet itself)
+func (d *DataNodeRegistration) ReadWithoutName(reader Reader) error {
+	err := d.ReadStorageID(reader)
+	if err == nil {
+		err = d.ReadInfoPort(reader)
+	}
+	if err == nil {
+		err = d.ReadIpcPort(reader)
+	}
+	if err == nil {
+		err = d.ReadLayoutVersion(reader)
+	}
+	if err == nil {
+		err = d.ReadNamespaceID(reader)
+	}
+	if err == nil {
+		err = d.ReadCTime(reader)
+	}
+	if err == nil {
+		err = d.ReadKeys(reader)
+	}
+
+	return err
+}
+
 //reads the name value from a reader (this can be a connection,
 //byte buffer, etc.)
 func (d *DataNodeRegistration) ReadName(reader Reader) error {
@@ -427,3 +427,23 @@ func TestWriteVarint(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestExportedBlockKeysBadLength(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewExportedBlockKeys().Write(buf)
+
+	//the key count comes last; claim 1 << 30 keys
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[len(data) - 4:], 1 << 30)
+
+	res := NewExportedBlockKeys()
+	if res.Read(bytes.NewReader(data)) == nil || res.AllKeys != nil {
+		t.Fail()
+	}
+
+	//cut off before the key count
+	res = NewExportedBlockKeys()
+	if res.Read(bytes.NewReader(data[:len(data) - 4])) == nil {
+		t.Fail()
+	}
+}